@@ -0,0 +1,288 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/obergerkatz/sortTF/vfs"
+)
+
+// Backup errors reported by RestoreBackup.
+var (
+	// ErrModifiedSinceBackup indicates a file no longer holds the sorted
+	// content it was given when it was backed up, so restoring it would
+	// discard later edits.
+	ErrModifiedSinceBackup = errors.New("file was modified after it was backed up")
+
+	// ErrBackupCorrupt indicates a backup file does not hold the content
+	// recorded in the manifest.
+	ErrBackupCorrupt = errors.New("backup does not match the manifest")
+)
+
+// ManifestName is the name of the manifest file in a backup directory.
+const ManifestName = "manifest.json"
+
+// A Backup saves the original content of files before they are overwritten.
+type Backup interface {
+	// Save is called with the original and sorted content of the file at
+	// path in fsys right before the sorted content replaces it. If Save
+	// fails, the file is not written. Save may be called concurrently.
+	Save(fsys fs.FS, path string, original, sorted []byte) error
+}
+
+// SiblingBackup is a Backup that saves the original content of each file next
+// to it, with ".orig" appended to its name, replacing any earlier backup.
+type SiblingBackup struct{}
+
+// Save writes original to path + ".orig".
+func (SiblingBackup) Save(fsys fs.FS, path string, original, _ []byte) error {
+	if err := writeFile(fsys, path+".orig", original); err != nil {
+		return fmt.Errorf("back up file: %w", err)
+	}
+	return nil
+}
+
+// BackupDir is a Backup that saves the original content of files in a new
+// timestamped directory, together with a manifest that RestoreBackup uses to
+// put them back. Close writes the manifest.
+type BackupDir struct {
+	parent string
+	now    time.Time
+
+	mu      sync.Mutex
+	fsys    fs.FS
+	dir     string
+	entries []ManifestEntry
+}
+
+// NewBackupDir returns a BackupDir that saves files in a directory named
+// after the current time, such as "20240102-150405", inside parent. The
+// directory is only created once the first file is saved.
+func NewBackupDir(parent string) *BackupDir {
+	return &BackupDir{parent: parent, now: time.Now()}
+}
+
+// Dir returns the directory the files were saved in, or "" if none was saved.
+func (b *BackupDir) Dir() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dir
+}
+
+// Len returns the number of files saved.
+func (b *BackupDir) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
+
+// Save copies original into the backup directory and records it in the
+// manifest. Paths on the operating system's file system are recorded as
+// absolute paths, so the backup can be restored from any directory.
+func (b *BackupDir) Save(fsys fs.FS, filePath string, original, sorted []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.dir == "" {
+		dir, err := createBackupDir(fsys, b.parent, b.now)
+		if err != nil {
+			return fmt.Errorf("create backup directory: %w", err)
+		}
+		b.fsys, b.dir = fsys, dir
+	}
+
+	if _, ok := fsys.(vfs.OSFS); ok {
+		abs, err := filepath.Abs(filePath)
+		if err != nil {
+			return fmt.Errorf("back up file: %w", err)
+		}
+		filePath = abs
+	}
+
+	// The .orig suffix keeps backups out of later runs over the same tree
+	name := path.Join("files", fmt.Sprintf("%04d-%s.orig", len(b.entries)+1, filepath.Base(filePath)))
+	if err := writeFile(fsys, filepath.Join(b.dir, filepath.FromSlash(name)), original); err != nil {
+		return fmt.Errorf("back up file: %w", err)
+	}
+
+	b.entries = append(b.entries, ManifestEntry{
+		Path:           filePath,
+		Backup:         name,
+		OriginalSHA256: hashHex(original),
+		SortedSHA256:   hashHex(sorted),
+	})
+	return nil
+}
+
+// Close writes the manifest of the saved files. It does nothing if no file
+// was saved.
+func (b *BackupDir) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.dir == "" {
+		return nil
+	}
+
+	manifest := Manifest{Created: b.now.UTC(), Files: append([]ManifestEntry(nil), b.entries...)}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	if err := writeFile(b.fsys, filepath.Join(b.dir, ManifestName), append(data, '\n')); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// createBackupDir creates a new directory for the backup taken at now in
+// parent, adding a counter to the name if a backup with the same timestamp
+// exists.
+func createBackupDir(fsys fs.FS, parent string, now time.Time) (string, error) {
+	wfs, ok := fsys.(vfs.WriteFS)
+	if !ok {
+		return "", vfs.ErrReadOnly
+	}
+
+	name := now.Format("20060102-150405")
+	for i := 1; ; i++ {
+		dir := filepath.Join(parent, name)
+		if i > 1 {
+			dir += "-" + strconv.Itoa(i)
+		}
+
+		_, err := fs.Stat(fsys, dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return dir, wfs.MkdirAll(filepath.Join(dir, "files"), 0750)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// Manifest describes the files saved in a backup directory.
+type Manifest struct {
+	Created time.Time       `json:"created"`
+	Files   []ManifestEntry `json:"files"`
+}
+
+// ManifestEntry describes one file saved in a backup directory.
+type ManifestEntry struct {
+	Path           string `json:"path"`            // File that was overwritten
+	Backup         string `json:"backup"`          // Saved copy, relative to the backup directory
+	OriginalSHA256 string `json:"original_sha256"` // Hash of the saved content
+	SortedSHA256   string `json:"sorted_sha256"`   // Hash of the content written in its place
+}
+
+// hashHex returns the hex-encoded SHA-256 hash of data.
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// RestoreOptions configures RestoreBackup.
+type RestoreOptions struct {
+	// Options selects the file system and the run mode. With DryRun set,
+	// files are checked but not written. Other fields are ignored.
+	Options
+
+	// Force restores files that were modified after they were backed up,
+	// discarding those modifications.
+	Force bool
+}
+
+// RestoreBackup puts back the files saved in a backup directory created by
+// BackupDir, as listed in its manifest.
+//
+// A file is only restored if it still holds the sorted content written when
+// it was backed up, so later edits are never lost; otherwise its Result
+// fails with ErrModifiedSinceBackup, unless opts.Force is set. Each backup is
+// checked against the hash in the manifest before it is used.
+//
+// Returns a Result for every file in the manifest, in path order, with
+// status StatusChanged when restored (StatusWouldChange in DryRun mode),
+// StatusUnchanged when the file already holds its original content, or
+// StatusFailed. Returns an error if the manifest cannot be read.
+func RestoreBackup(dir string, opts RestoreOptions) ([]Result, error) {
+	fsys := opts.fsys()
+
+	data, err := fs.ReadFile(fsys, filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	results := make([]Result, 0, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		start := time.Now()
+		result := Result{Path: entry.Path}
+		status, err := restoreFile(fsys, dir, entry, opts)
+		result.Status = status
+		if err != nil {
+			result.Status = StatusFailed
+			result.Err = err
+		}
+		result.Duration = time.Since(start)
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	return results, nil
+}
+
+// restoreFile puts back one file of a backup. See RestoreBackup.
+func restoreFile(fsys fs.FS, dir string, entry ManifestEntry, opts RestoreOptions) (Status, error) {
+	current, version, err := readVersion(fsys, entry.Path)
+	exists := !errors.Is(err, fs.ErrNotExist)
+	switch {
+	case !exists:
+		// The file was removed since; recreating it is a change too
+		if !opts.Force {
+			return StatusFailed, ErrModifiedSinceBackup
+		}
+	case err != nil:
+		return StatusFailed, err
+	case hashHex(current) == entry.OriginalSHA256:
+		return StatusUnchanged, nil
+	case hashHex(current) != entry.SortedSHA256 && !opts.Force:
+		return StatusFailed, ErrModifiedSinceBackup
+	}
+
+	original, err := fs.ReadFile(fsys, filepath.Join(dir, filepath.FromSlash(entry.Backup)))
+	if err != nil {
+		return StatusFailed, fmt.Errorf("read backup: %w", err)
+	}
+	if hashHex(original) != entry.OriginalSHA256 {
+		return StatusFailed, ErrBackupCorrupt
+	}
+
+	if opts.DryRun {
+		return StatusWouldChange, nil
+	}
+
+	var check func() error
+	if exists {
+		check = func() error { return version.check(fsys, entry.Path) }
+	}
+	if err := writeFileIf(fsys, entry.Path, original, check); err != nil {
+		return StatusFailed, err
+	}
+	return StatusChanged, nil
+}
@@ -0,0 +1,275 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/obergerkatz/sortTF/vfs"
+)
+
+const (
+	backupUnsorted = "variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n"
+	backupSorted   = "variable \"a\" {\n  type = string\n}\n\nvariable \"b\" {\n  type = string\n}\n"
+)
+
+// failingBackup is a Backup that always fails.
+type failingBackup struct{}
+
+func (failingBackup) Save(fs.FS, string, []byte, []byte) error {
+	return errors.New("disk full")
+}
+
+// TestSiblingBackup tests saving originals next to the sorted files.
+func TestSiblingBackup(t *testing.T) {
+	fsys := vfs.NewMemFS(map[string][]byte{
+		"main.tf":   []byte(backupUnsorted),
+		"sorted.tf": []byte(backupSorted),
+	})
+
+	results, err := SortDirectory(".", false, Options{FS: fsys, Backup: SiblingBackup{}})
+	if err != nil {
+		t.Fatalf("SortDirectory() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", results)
+	}
+
+	if got, err := fsys.ReadFile("main.tf.orig"); err != nil || string(got) != backupUnsorted {
+		t.Errorf("Expected main.tf.orig to hold the original, got %q (err %v)", got, err)
+	}
+	if _, err := fsys.Stat("sorted.tf.orig"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected no backup of an unchanged file, got %v", err)
+	}
+}
+
+// TestBackup_Failure tests that a file is not written if its backup fails.
+func TestBackup_Failure(t *testing.T) {
+	fsys := vfs.NewMemFS(map[string][]byte{"main.tf": []byte(backupUnsorted)})
+
+	result, err := ProcessFile("main.tf", Options{FS: fsys, Backup: failingBackup{}})
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Expected the backup error, got %v", err)
+	}
+	if result.Status != StatusFailed {
+		t.Errorf("Expected status %s, got %s", StatusFailed, result.Status)
+	}
+	if got, _ := fsys.ReadFile("main.tf"); string(got) != backupUnsorted {
+		t.Errorf("Expected main.tf to be left alone, got:\n%s", got)
+	}
+}
+
+// TestBackupDir tests backing up files into a directory and restoring them.
+func TestBackupDir(t *testing.T) {
+	fsys := vfs.NewMemFS(map[string][]byte{
+		"main.tf":             []byte(backupUnsorted),
+		"modules/vpc/main.tf": []byte(backupUnsorted),
+		"sorted.tf":           []byte(backupSorted),
+	})
+
+	backup := NewBackupDir("backups")
+	if _, err := SortDirectory(".", true, Options{FS: fsys, Backup: backup}); err != nil {
+		t.Fatalf("SortDirectory() error = %v", err)
+	}
+	if err := backup.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if backup.Len() != 2 {
+		t.Errorf("Expected 2 files backed up, got %d", backup.Len())
+	}
+	dir := backup.Dir()
+	if !strings.HasPrefix(dir, "backups/") {
+		t.Fatalf("Expected a directory in backups, got %q", dir)
+	}
+
+	data, err := fsys.ReadFile(dir + "/" + ManifestName)
+	if err != nil {
+		t.Fatalf("Expected a manifest: %v", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Invalid manifest: %v", err)
+	}
+	if len(manifest.Files) != 2 || manifest.Files[0].Path != "main.tf" || manifest.Files[1].Path != "modules/vpc/main.tf" {
+		t.Errorf("Unexpected manifest entries: %+v", manifest.Files)
+	}
+
+	results, err := RestoreBackup(dir, RestoreOptions{Options: Options{FS: fsys}})
+	if err != nil {
+		t.Fatalf("RestoreBackup() error = %v", err)
+	}
+	for _, result := range results {
+		if result.Status != StatusChanged {
+			t.Errorf("%s: status = %s, want %s (err %v)", result.Path, result.Status, StatusChanged, result.Err)
+		}
+		if got, _ := fsys.ReadFile(result.Path); string(got) != backupUnsorted {
+			t.Errorf("%s was not restored:\n%s", result.Path, got)
+		}
+	}
+
+	// Restoring again finds the originals in place
+	results, err = RestoreBackup(dir, RestoreOptions{Options: Options{FS: fsys}})
+	if err != nil {
+		t.Fatalf("RestoreBackup() error = %v", err)
+	}
+	for _, result := range results {
+		if result.Status != StatusUnchanged {
+			t.Errorf("%s: status = %s on second restore, want %s", result.Path, result.Status, StatusUnchanged)
+		}
+	}
+}
+
+// TestBackupDir_Unused tests that nothing is created when no file is written.
+func TestBackupDir_Unused(t *testing.T) {
+	fsys := vfs.NewMemFS(map[string][]byte{"main.tf": []byte(backupUnsorted)})
+
+	backup := NewBackupDir("backups")
+	if _, err := SortDirectory(".", false, Options{FS: fsys, Backup: backup, DryRun: true}); err != nil {
+		t.Fatalf("SortDirectory() error = %v", err)
+	}
+	if err := backup.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if backup.Dir() != "" {
+		t.Errorf("Expected no backup directory, got %q", backup.Dir())
+	}
+	if _, err := fsys.Stat("backups"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected backups not to be created, got %v", err)
+	}
+}
+
+// TestRestoreBackup_Checks tests that restoring never clobbers later edits or
+// uses a damaged backup.
+func TestRestoreBackup_Checks(t *testing.T) {
+	tests := []struct {
+		name       string
+		change     func(fsys *vfs.MemFS, dir string)
+		opts       RestoreOptions
+		wantStatus Status
+		wantErr    error
+		want       string
+	}{
+		{
+			name:       "dry run",
+			opts:       RestoreOptions{Options: Options{DryRun: true}},
+			wantStatus: StatusWouldChange,
+			want:       backupSorted,
+		},
+		{
+			name: "edited since",
+			change: func(fsys *vfs.MemFS, _ string) {
+				_ = fsys.WriteFile("main.tf", []byte("edited = true\n"), 0644)
+			},
+			wantStatus: StatusFailed,
+			wantErr:    ErrModifiedSinceBackup,
+			want:       "edited = true\n",
+		},
+		{
+			name: "edited since, forced",
+			change: func(fsys *vfs.MemFS, _ string) {
+				_ = fsys.WriteFile("main.tf", []byte("edited = true\n"), 0644)
+			},
+			opts:       RestoreOptions{Force: true},
+			wantStatus: StatusChanged,
+			want:       backupUnsorted,
+		},
+		{
+			name: "removed since",
+			change: func(fsys *vfs.MemFS, _ string) {
+				_ = fsys.Remove("main.tf")
+			},
+			wantStatus: StatusFailed,
+			wantErr:    ErrModifiedSinceBackup,
+		},
+		{
+			name: "damaged backup",
+			change: func(fsys *vfs.MemFS, dir string) {
+				_ = fsys.WriteFile(dir+"/files/0001-main.tf.orig", []byte("damaged\n"), 0644)
+			},
+			opts:       RestoreOptions{Force: true},
+			wantStatus: StatusFailed,
+			wantErr:    ErrBackupCorrupt,
+			want:       backupSorted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := vfs.NewMemFS(map[string][]byte{"main.tf": []byte(backupUnsorted)})
+			backup := NewBackupDir("backups")
+			if err := SortFile("main.tf", Options{FS: fsys, Backup: backup}); err != nil {
+				t.Fatalf("SortFile() error = %v", err)
+			}
+			if err := backup.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if tt.change != nil {
+				tt.change(fsys, backup.Dir())
+			}
+
+			opts := tt.opts
+			opts.FS = fsys
+			results, err := RestoreBackup(backup.Dir(), opts)
+			if err != nil {
+				t.Fatalf("RestoreBackup() error = %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result, got %+v", results)
+			}
+			if results[0].Status != tt.wantStatus || !errors.Is(results[0].Err, tt.wantErr) {
+				t.Errorf("Got status %s and error %v, want %s and %v", results[0].Status, results[0].Err, tt.wantStatus, tt.wantErr)
+			}
+			if got, _ := fsys.ReadFile("main.tf"); string(got) != tt.want {
+				t.Errorf("Expected content:\n%s\ngot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestRestoreBackup_MissingManifest tests restoring from a directory that is not a backup.
+func TestRestoreBackup_MissingManifest(t *testing.T) {
+	if _, err := RestoreBackup(t.TempDir(), RestoreOptions{}); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+// TestBackupDir_OS tests that backups on disk record absolute paths and can
+// be restored from another working directory.
+func TestBackupDir_OS(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+	if err := os.WriteFile(testFile, []byte(backupUnsorted), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(tmpDir)
+
+	backup := NewBackupDir(filepath.Join(tmpDir, "backups"))
+	if err := SortFile("main.tf", Options{Backup: backup}); err != nil {
+		t.Fatalf("SortFile() error = %v", err)
+	}
+	if err := backup.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Chdir(t.TempDir())
+	results, err := RestoreBackup(backup.Dir(), RestoreOptions{})
+	if err != nil {
+		t.Fatalf("RestoreBackup() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Path != testFile || results[0].Status != StatusChanged {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+
+	//nolint:gosec // G304: Test file path is controlled
+	if got, _ := os.ReadFile(testFile); string(got) != backupUnsorted {
+		t.Errorf("Expected the original to be restored, got:\n%s", got)
+	}
+	if info, err := os.Stat(testFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 to be kept, got %v (err %v)", info.Mode().Perm(), err)
+	}
+}
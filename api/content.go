@@ -0,0 +1,159 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/obergerkatz/sortTF/diff"
+	"github.com/obergerkatz/sortTF/hcl"
+
+	hcllib "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// SortBytes sorts and formats Terraform or Terragrunt source held in memory.
+//
+// It applies the same validation, sorting and change detection as
+// GetSortedContentWithOptions, without touching the file system. The filename
+// is only used in diagnostics and passed to opts.OnRewrite; it may be empty.
+// The DryRun and Validate fields of opts are ignored since nothing is written,
+// so the Status is StatusUnchanged or StatusWouldChange. For the same reason
+// the sorted content is not checked with hcl.CheckEquivalent; SortFile and
+// SortReader check it before writing it.
+//
+// Returns a parsing or validation error if src cannot be sorted.
+func SortBytes(src []byte, filename string, opts Options) (Result, error) {
+	return sortBytes(src, filename, opts, false)
+}
+
+// sortBytes is SortBytes. If write is set, the sorted content is about to be
+// written, so it is checked with hcl.CheckEquivalent unless opts is a dry
+// run or validation.
+func sortBytes(src []byte, filename string, opts Options, write bool) (Result, error) {
+	hclFile, err := parseAndValidate(src, filename, opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	sortOpts := opts.sortOptions()
+	sortOpts.SkipEquivalenceCheck = sortOpts.SkipEquivalenceCheck || !write
+	formatted, rewrites, err := hcl.SortAndFormatHCLFileWithOptions(hclFile, sortOpts)
+	if err != nil {
+		return Result{}, fmt.Errorf("sort/format: %w", err)
+	}
+
+	if opts.OnRewrite != nil {
+		for _, rewrite := range rewrites {
+			opts.OnRewrite(filename, rewrite)
+		}
+	}
+
+	if opts.CheckIdempotence {
+		if err := checkIdempotent([]byte(formatted), filename, opts); err != nil {
+			return Result{}, err
+		}
+	}
+
+	return newResult(filename, src, []byte(formatted)), nil
+}
+
+// IdempotenceError reports that sorting a file's sorted content again
+// changed it. It wraps ErrNotIdempotent.
+type IdempotenceError struct {
+	First  []byte      // Content after the first pass
+	Second []byte      // Content after sorting First again
+	Diff   []diff.Line // Differences that turn First into Second
+}
+
+// Error implements the error interface, counting the lines that differ.
+func (e *IdempotenceError) Error() string {
+	changed := 0
+	for _, line := range e.Diff {
+		if line.Op != diff.Equal {
+			changed++
+		}
+	}
+	return fmt.Sprintf("%v: %d lines differ between the first and second pass", ErrNotIdempotent, changed)
+}
+
+// Unwrap returns ErrNotIdempotent.
+func (e *IdempotenceError) Unwrap() error {
+	return ErrNotIdempotent
+}
+
+// checkIdempotent sorts sorted, the result of sorting a file with opts,
+// again and returns an *IdempotenceError if the second pass changes it.
+func checkIdempotent(sorted []byte, filename string, opts Options) error {
+	hclFile, diags := hclwrite.ParseConfig(sorted, filename, hcllib.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return fmt.Errorf("check idempotence: %w", diags)
+	}
+
+	// The first pass was checked already, if it is to be written
+	sortOpts := opts.sortOptions()
+	sortOpts.SkipEquivalenceCheck = true
+	second, _, err := hcl.SortAndFormatHCLFileWithOptions(hclFile, sortOpts)
+	if err != nil {
+		return fmt.Errorf("check idempotence: %w", err)
+	}
+
+	if second != string(sorted) {
+		return &IdempotenceError{
+			First:  sorted,
+			Second: []byte(second),
+			Diff:   diff.Lines(string(sorted), second),
+		}
+	}
+	return nil
+}
+
+// SortReader reads Terraform or Terragrunt source from r, sorts it like
+// SortBytes, and writes the sorted content to w, whether or not it changed.
+// Nothing is written to w if the source cannot be sorted.
+func SortReader(r io.Reader, w io.Writer, filename string, opts Options) (Result, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return Result{}, fmt.Errorf("read: %w", err)
+	}
+
+	result, err := sortBytes(src, filename, opts, true)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if _, err := w.Write(result.Sorted); err != nil {
+		return result, fmt.Errorf("write: %w", err)
+	}
+	return result, nil
+}
+
+// parseAndValidate parses src, validates it, and parses it again with
+// hclwrite for sorting. Schema validation runs unless opts disables it.
+//
+// Both parses use the same bytes. They cannot be merged: validation needs
+// the hclsyntax tree for its source ranges, and hclwrite builds its own tree
+// from a private hclsyntax parse that it does not expose.
+func parseAndValidate(src []byte, filename string, opts Options) (*hclwrite.File, error) {
+	parsed, err := hcl.ParseHCL(src, filename)
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+
+	if err := hcl.ValidateRequiredBlockLabels(parsed); err != nil {
+		return nil, fmt.Errorf("validate: %w", err)
+	}
+
+	if !opts.SkipSchemaValidation {
+		if err := hcl.ValidateSchema(parsed); err != nil {
+			return nil, fmt.Errorf("validate schema: %w", err)
+		}
+	}
+
+	hclFile, diags := hclwrite.ParseConfig(src, filename, hcllib.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("parse for formatting: %w", diags)
+	}
+
+	return hclFile, nil
+}
@@ -0,0 +1,148 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/obergerkatz/sortTF/hcl"
+)
+
+func TestSortBytes(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         string
+		opts        Options
+		wantSorted  string
+		wantChanged bool
+		wantErr     func(error) bool
+	}{
+		{
+			name:        "sorts blocks",
+			src:         "variable \"b\" {\n  type = string\n}\nvariable \"a\" {\n  type = string\n}\n",
+			wantSorted:  "variable \"a\" {\n  type = string\n}\n\nvariable \"b\" {\n  type = string\n}\n",
+			wantChanged: true,
+		},
+		{
+			name:       "already sorted",
+			src:        "variable \"a\" {\n  type = string\n}\n",
+			wantSorted: "variable \"a\" {\n  type = string\n}\n",
+		},
+		{
+			name:    "syntax error",
+			src:     "resource \"broken\" {\n",
+			wantErr: hcl.IsHCLParseError,
+		},
+		{
+			name:    "label error",
+			src:     "resource \"aws_instance\" {}\n",
+			wantErr: hcl.IsValidationError,
+		},
+		{
+			name:    "schema error",
+			src:     "output \"id\" {}\n",
+			wantErr: hcl.IsSchemaError,
+		},
+		{
+			name:       "schema check skipped",
+			src:        "output \"id\" {\n  description = \"ID\"\n}\n",
+			opts:       Options{SkipSchemaValidation: true},
+			wantSorted: "output \"id\" {\n  description = \"ID\"\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SortBytes([]byte(tt.src), "mem.tf", tt.opts)
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("SortBytes() unexpected error %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SortBytes() error = %v", err)
+			}
+			if string(result.Sorted) != tt.wantSorted {
+				t.Errorf("Sorted = %q, want %q", result.Sorted, tt.wantSorted)
+			}
+			if result.Changed != tt.wantChanged {
+				t.Errorf("Changed = %v, want %v", result.Changed, tt.wantChanged)
+			}
+			if result.Path != "mem.tf" || string(result.Original) != tt.src {
+				t.Errorf("Unexpected path or original: %+v", result)
+			}
+		})
+	}
+}
+
+func TestSortBytes_DiagnosticsUseFilename(t *testing.T) {
+	_, err := SortBytes([]byte("resource \"aws_instance\" {}\n"), "generated.tf", Options{})
+	diags := hcl.ValidationDiagnostics(err)
+	if len(diags) != 1 || diags[0].Subject.Filename != "generated.tf" {
+		t.Errorf("Expected diagnostic in generated.tf, got %v", diags)
+	}
+}
+
+func TestSortReader(t *testing.T) {
+	var out bytes.Buffer
+	result, err := SortReader(strings.NewReader("variable \"b\" {}\nvariable \"a\" {}\n"), &out, "stdin.tf", Options{})
+	if err != nil {
+		t.Fatalf("SortReader() error = %v", err)
+	}
+	if !result.Changed || out.String() != string(result.Sorted) {
+		t.Errorf("Expected sorted output written, got %q (result %+v)", out.String(), result)
+	}
+
+	// Nothing is written when the source cannot be sorted
+	out.Reset()
+	if _, err := SortReader(strings.NewReader("resource \"broken\" {\n"), &out, "stdin.tf", Options{}); err == nil {
+		t.Error("Expected error for invalid source")
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no output, got %q", out.String())
+	}
+}
+
+// failingReader always fails to read.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("boom")
+}
+
+func TestSortReader_ReadError(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := SortReader(failingReader{}, &out, "stdin.tf", Options{}); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected read error, got %v", err)
+	}
+}
+
+// TestSortBytes_CheckIdempotence tests that sorted content is sorted again
+// and that a second pass that changes it is reported with the diff
+func TestSortBytes_CheckIdempotence(t *testing.T) {
+	unsorted := "variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n"
+
+	result, err := SortBytes([]byte(unsorted), "main.tf", Options{CheckIdempotence: true, Normalize: true, MaxLineWidth: 40})
+	if err != nil {
+		t.Fatalf("SortBytes() error = %v", err)
+	}
+	if !result.Changed {
+		t.Error("Expected the content to change")
+	}
+
+	// Content that is not sorted stands in for a first pass that is not stable
+	err = checkIdempotent([]byte(unsorted), "main.tf", Options{})
+	var idemErr *IdempotenceError
+	if !errors.As(err, &idemErr) || !errors.Is(err, ErrNotIdempotent) {
+		t.Fatalf("checkIdempotent() error = %v, want *IdempotenceError", err)
+	}
+	if string(idemErr.First) != unsorted || !strings.HasPrefix(string(idemErr.Second), "variable \"a\"") {
+		t.Errorf("Unexpected passes:\nfirst:\n%s\nsecond:\n%s", idemErr.First, idemErr.Second)
+	}
+	if !strings.Contains(err.Error(), "lines differ between the first and second pass") {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
@@ -0,0 +1,68 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"sync"
+
+	"github.com/obergerkatz/sortTF/hcl"
+)
+
+// events delivers the callbacks of Options for one run of SortFilesContext.
+// Calls are serialized, so callbacks never run concurrently with each other
+// even though files are processed by several workers.
+type events struct {
+	mu   sync.Mutex
+	opts Options
+}
+
+// newEvents returns the event dispatcher for a run with opts.
+func newEvents(opts Options) *events {
+	return &events{opts: opts}
+}
+
+// discover reports the files about to be processed.
+func (e *events) discover(paths []string) {
+	if e.opts.OnDiscover == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.opts.OnDiscover(paths)
+}
+
+// start reports that a worker picked up path.
+func (e *events) start(path string) {
+	if e.opts.OnStart == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.opts.OnStart(path)
+}
+
+// rewrite reports a normalization rewrite applied to path.
+func (e *events) rewrite(path string, rewrite hcl.Rewrite) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.opts.OnRewrite(path, rewrite)
+}
+
+// result reports that a worker finished a file.
+func (e *events) result(result Result) {
+	if e.opts.OnResult == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.opts.OnResult(result)
+}
+
+// complete reports the results of the whole run.
+func (e *events) complete(results []Result) {
+	if e.opts.OnComplete == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.opts.OnComplete(results)
+}
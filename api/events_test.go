@@ -0,0 +1,84 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/obergerkatz/sortTF/hcl"
+)
+
+func TestSortFiles_Events(t *testing.T) {
+	tmpDir := t.TempDir()
+	var paths []string
+	for i := range 8 {
+		path := filepath.Join(tmpDir, string(rune('a'+i))+".tf")
+		//nolint:gosec // G306: Test files can use 0644 permissions
+		if err := os.WriteFile(path, []byte("variable \"x\" {\n  type = \"string\"\n}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	paths = append(paths, filepath.Join(tmpDir, "notes.txt"))
+
+	// The callbacks use no locking; the race detector flags concurrent calls
+	var log []string
+	inCallback := false
+	record := func(event string) {
+		if inCallback {
+			t.Errorf("callback %s called concurrently", event)
+		}
+		inCallback = true
+		log = append(log, event)
+		inCallback = false
+	}
+
+	var discovered, started, finished []string
+	var completed []Result
+	opts := Options{
+		Workers:   4,
+		Normalize: true,
+		OnDiscover: func(paths []string) {
+			record("discover")
+			discovered = paths
+		},
+		OnStart: func(path string) {
+			record("start")
+			started = append(started, path)
+		},
+		OnRewrite: func(string, hcl.Rewrite) {
+			record("rewrite")
+		},
+		OnResult: func(result Result) {
+			record("result")
+			finished = append(finished, result.Path)
+		},
+		OnComplete: func(results []Result) {
+			record("complete")
+			completed = results
+		},
+	}
+
+	results := SortFiles(paths, opts)
+
+	if log[0] != "discover" || log[len(log)-1] != "complete" {
+		t.Errorf("Expected discover first and complete last, got %v", log)
+	}
+	if len(discovered) != 8 || !sort.StringsAreSorted(discovered) {
+		t.Errorf("Expected 8 discovered files in path order, got %v", discovered)
+	}
+	sort.Strings(started)
+	sort.Strings(finished)
+	if strings.Join(started, ",") != strings.Join(discovered, ",") || strings.Join(finished, ",") != strings.Join(discovered, ",") {
+		t.Errorf("Expected every discovered file started and finished, got %v and %v", started, finished)
+	}
+	if strings.Count(strings.Join(log, ","), "rewrite") != 8 {
+		t.Errorf("Expected one rewrite per file, got %v", log)
+	}
+	if len(completed) != len(results) || completed[len(completed)-1].Status != StatusSkipped {
+		t.Errorf("Expected OnComplete with all results including the skipped file, got %+v", completed)
+	}
+}
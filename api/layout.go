@@ -0,0 +1,135 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/obergerkatz/sortTF/hcl"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// LayoutOptions configures LayoutDirectory.
+type LayoutOptions struct {
+	// Options configures sorting of the rewritten files and the run mode.
+	// With DryRun or Validate set, the plan is computed but nothing is written.
+	Options
+
+	// Mapping assigns block types to canonical file names.
+	// If nil, hcl.DefaultLayout() is used.
+	Mapping map[string]string
+}
+
+// LayoutResult describes the planned or applied layout of a module directory.
+type LayoutResult struct {
+	Dir     string          // Module directory
+	Moves   []hcl.BlockMove // Blocks moved between files
+	Written []string        // Files written (or that would be written), sorted
+	Removed []string        // Files left empty and removed (or that would be removed)
+}
+
+// LayoutDirectory moves each top-level block in a Terraform module directory to
+// its canonical file, sorts every affected file, and removes files left empty.
+//
+// The directory is treated as a single module: only .tf files directly inside it
+// are considered. All files are parsed and validated before anything is written.
+//
+// Returns the result together with:
+//   - nil: the layout was applied (or would be, in DryRun mode)
+//   - ErrNoChanges: every block is already in its canonical file
+//   - ErrNeedsSorting: blocks need to move (only in Validate mode)
+//   - error: parsing, validation, or I/O error
+func LayoutDirectory(dir string, opts LayoutOptions) (*LayoutResult, error) {
+	paths, err := opts.findFiles(dir, false)
+	if err != nil {
+		return nil, fmt.Errorf("find files: %w", err)
+	}
+
+	mapping := opts.Mapping
+	if mapping == nil {
+		mapping = hcl.DefaultLayout()
+	}
+
+	// Parse every file first so a single bad file aborts before any writes
+	moduleFiles := make(map[string]*hclwrite.File)
+	for _, path := range paths {
+		if filepath.Ext(path) != ".tf" {
+			continue
+		}
+		_, hclFile, err := readAndParse(path, opts.Options)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		moduleFiles[filepath.Base(path)] = hclFile
+	}
+
+	moves, affected := hcl.PlanLayout(moduleFiles, mapping)
+	return applyPlan(dir, moves, affected, opts.Options)
+}
+
+// applyPlan sorts and writes the affected files of a planned layout or split
+// in dir and removes files the plan leaves empty. All files are sorted before
+// any is written. In DryRun and Validate modes nothing is written; the
+// returned error follows the conventions of LayoutDirectory.
+func applyPlan(dir string, moves []hcl.BlockMove, affected map[string]*hclwrite.File, opts Options) (*LayoutResult, error) {
+	result := &LayoutResult{Dir: dir, Moves: moves}
+	if len(moves) == 0 {
+		return result, ErrNoChanges
+	}
+
+	names := make([]string, 0, len(affected))
+	for name := range affected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	contents := make(map[string][]byte, len(names))
+	for _, name := range names {
+		if filepath.Base(name) != name || name == ".." {
+			return result, fmt.Errorf("invalid destination file name %q", name)
+		}
+
+		path := filepath.Join(dir, name)
+		file := affected[name]
+
+		if len(file.Body().Blocks()) == 0 {
+			result.Removed = append(result.Removed, path)
+			continue
+		}
+
+		formatted, rewrites, err := hcl.SortAndFormatHCLFileWithOptions(file, opts.sortOptions())
+		if err != nil {
+			return result, fmt.Errorf("%s: sort/format: %w", path, err)
+		}
+		if opts.OnRewrite != nil {
+			for _, rewrite := range rewrites {
+				opts.OnRewrite(path, rewrite)
+			}
+		}
+
+		contents[path] = []byte(formatted)
+		result.Written = append(result.Written, path)
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+	if opts.Validate {
+		return result, ErrNeedsSorting
+	}
+
+	for _, path := range result.Written {
+		if err := writeFile(opts.fsys(), path, contents[path]); err != nil {
+			return result, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, path := range result.Removed {
+		if err := removeFile(opts.fsys(), path); err != nil {
+			return result, fmt.Errorf("remove empty file: %w", err)
+		}
+	}
+
+	return result, nil
+}
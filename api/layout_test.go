@@ -0,0 +1,149 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/obergerkatz/sortTF/vfs"
+)
+
+// writeModule writes a map of file name to content into dir
+func writeModule(t *testing.T, dir string, sources map[string]string) {
+	t.Helper()
+	for name, content := range sources {
+		//nolint:gosec // G306: Test files can use 0644
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLayoutDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeModule(t, tmpDir, map[string]string{
+		"main.tf": `resource "aws_instance" "web" {
+  ami = var.ami
+}
+`,
+		"vars.tf": `variable "ami" {
+  type = string
+}
+
+output "id" {
+  value = aws_instance.web.id
+}
+`,
+	})
+
+	result, err := LayoutDirectory(tmpDir, LayoutOptions{})
+	if err != nil {
+		t.Fatalf("LayoutDirectory failed: %v", err)
+	}
+
+	if len(result.Moves) != 2 {
+		t.Errorf("Expected 2 moves, got %d: %v", len(result.Moves), result.Moves)
+	}
+	if len(result.Removed) != 1 || result.Removed[0] != filepath.Join(tmpDir, "vars.tf") {
+		t.Errorf("Expected vars.tf to be removed, got %v", result.Removed)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "vars.tf")); !os.IsNotExist(err) {
+		t.Error("Expected vars.tf to be deleted")
+	}
+
+	//nolint:gosec // G304: Test file path is controlled
+	variables, err := os.ReadFile(filepath.Join(tmpDir, "variables.tf"))
+	if err != nil {
+		t.Fatalf("Expected variables.tf to be created: %v", err)
+	}
+	if !strings.Contains(string(variables), `variable "ami"`) {
+		t.Errorf("Expected variable in variables.tf, got:\n%s", variables)
+	}
+
+	//nolint:gosec // G304: Test file path is controlled
+	outputs, err := os.ReadFile(filepath.Join(tmpDir, "outputs.tf"))
+	if err != nil || !strings.Contains(string(outputs), `output "id"`) {
+		t.Errorf("Expected output in outputs.tf, got: %s (err %v)", outputs, err)
+	}
+
+	// A second run finds nothing to move
+	if _, err := LayoutDirectory(tmpDir, LayoutOptions{}); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("Expected ErrNoChanges on second run, got: %v", err)
+	}
+}
+
+func TestLayoutDirectory_DryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `variable "ami" {
+  type = string
+}
+`
+	writeModule(t, tmpDir, map[string]string{"main.tf": content})
+
+	result, err := LayoutDirectory(tmpDir, LayoutOptions{Options: Options{DryRun: true}})
+	if err != nil {
+		t.Fatalf("LayoutDirectory dry run failed: %v", err)
+	}
+	if len(result.Moves) != 1 || len(result.Written) != 1 || len(result.Removed) != 1 {
+		t.Errorf("Unexpected plan: %+v", result)
+	}
+
+	// Nothing must change on disk
+	//nolint:gosec // G304: Test file path is controlled
+	if got, _ := os.ReadFile(filepath.Join(tmpDir, "main.tf")); string(got) != content {
+		t.Error("main.tf was modified in dry-run mode")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "variables.tf")); !os.IsNotExist(err) {
+		t.Error("variables.tf was created in dry-run mode")
+	}
+
+	// Validate mode reports that blocks need to move
+	if _, err := LayoutDirectory(tmpDir, LayoutOptions{Options: Options{Validate: true}}); !errors.Is(err, ErrNeedsSorting) {
+		t.Errorf("Expected ErrNeedsSorting in validate mode, got: %v", err)
+	}
+}
+
+func TestLayoutDirectory_InvalidFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeModule(t, tmpDir, map[string]string{
+		"a.tf": "variable \"ok\" {}\n",
+		"b.tf": "resource \"broken\" {\n",
+	})
+
+	if _, err := LayoutDirectory(tmpDir, LayoutOptions{}); err == nil {
+		t.Fatal("Expected error for invalid file")
+	}
+
+	// The valid file must not have been moved
+	if _, err := os.Stat(filepath.Join(tmpDir, "a.tf")); err != nil {
+		t.Errorf("a.tf should be untouched: %v", err)
+	}
+}
+
+// TestLayoutDirectory_FS tests moving blocks between files of an in-memory file system.
+func TestLayoutDirectory_FS(t *testing.T) {
+	fsys := vfs.NewMemFS(map[string][]byte{
+		"module/vars.tf": []byte("variable \"ami\" {\n  type = string\n}\n"),
+	})
+
+	result, err := LayoutDirectory("module", LayoutOptions{Options: Options{FS: fsys}})
+	if err != nil {
+		t.Fatalf("LayoutDirectory failed: %v", err)
+	}
+	if len(result.Written) != 1 || result.Written[0] != "module/variables.tf" {
+		t.Errorf("Expected module/variables.tf to be written, got %v", result.Written)
+	}
+
+	if _, err := fsys.Stat("module/vars.tf"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected module/vars.tf to be removed, got %v", err)
+	}
+	variables, err := fsys.ReadFile("module/variables.tf")
+	if err != nil || !strings.Contains(string(variables), `variable "ami"`) {
+		t.Errorf("Expected variable in module/variables.tf, got: %s (err %v)", variables, err)
+	}
+}
@@ -0,0 +1,52 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/obergerkatz/sortTF/hcl"
+)
+
+// ValidateModule checks the .tf files directly inside dir together, as one
+// Terraform module, for objects declared more than once: resources, data
+// sources, module calls, variables, outputs, provider configurations and
+// local values. Files are discovered in and read from opts.FS; the other
+// fields of opts are ignored. Files are not modified.
+//
+// Returns:
+//   - nil: every address in the module is unique
+//   - error: duplicates were found; hcl.ValidationDiagnostics returns one
+//     diagnostic per duplicate, with both source locations
+//   - error: a file could not be read or parsed
+func ValidateModule(dir string, opts Options) error {
+	parsed, err := parseModule(dir, opts)
+	if err != nil {
+		return err
+	}
+
+	return hcl.ValidateModule(parsed)
+}
+
+// parseModule parses the .tf files directly inside dir, the files that make
+// up one Terraform module, from opts.FS.
+func parseModule(dir string, opts Options) ([]*hcl.ParsedFile, error) {
+	paths, err := opts.findFiles(dir, false)
+	if err != nil {
+		return nil, fmt.Errorf("find files: %w", err)
+	}
+
+	var parsed []*hcl.ParsedFile
+	for _, path := range paths {
+		if filepath.Ext(path) != ".tf" {
+			continue
+		}
+		pf, err := hcl.ParseHCLFileFS(opts.fsys(), path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: parse: %w", path, err)
+		}
+		parsed = append(parsed, pf)
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,73 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/obergerkatz/sortTF/hcl"
+	"github.com/obergerkatz/sortTF/vfs"
+)
+
+func TestValidateModule(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeModule(t, tmpDir, map[string]string{
+		"main.tf":      "resource \"aws_s3_bucket\" \"logs\" {}\n",
+		"storage.tf":   "resource \"aws_s3_bucket\" \"logs\" {}\n",
+		"variables.tf": "variable \"region\" {}\n",
+		// Terragrunt files are not part of the module
+		"terragrunt.hcl": "locals {\n  region = \"a\"\n}\n",
+	})
+
+	err := ValidateModule(tmpDir, Options{})
+	diags := hcl.ValidationDiagnostics(err)
+	if len(diags) != 1 {
+		t.Fatalf("Expected 1 duplicate, got %v (err %v)", diags, err)
+	}
+	if diags[0].Subject.Filename != filepath.Join(tmpDir, "storage.tf") {
+		t.Errorf("Expected duplicate reported in storage.tf, got %s", diags[0].Subject.Filename)
+	}
+	if !strings.Contains(diags[0].Detail, filepath.Join(tmpDir, "main.tf")+":1:1") {
+		t.Errorf("Expected first declaration location in detail, got %q", diags[0].Detail)
+	}
+}
+
+func TestValidateModule_NoDuplicates(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeModule(t, tmpDir, map[string]string{
+		"main.tf":      "resource \"aws_s3_bucket\" \"logs\" {}\n",
+		"variables.tf": "variable \"region\" {}\n",
+	})
+
+	if err := ValidateModule(tmpDir, Options{}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestValidateModule_InvalidFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeModule(t, tmpDir, map[string]string{"main.tf": "resource \"broken\" {\n"})
+
+	err := ValidateModule(tmpDir, Options{})
+	if !hcl.IsHCLParseError(err) {
+		t.Errorf("Expected parse error, got %v", err)
+	}
+}
+
+// TestValidateModule_FS tests that the module is read from Options.FS
+func TestValidateModule_FS(t *testing.T) {
+	fsys := vfs.NewMemFS(map[string][]byte{
+		"modules/vpc/main.tf":    []byte("resource \"aws_vpc\" \"main\" {}\n"),
+		"modules/vpc/network.tf": []byte("resource \"aws_vpc\" \"main\" {}\n"),
+		"main.tf":                []byte("resource \"aws_vpc\" \"main\" {}\n"),
+	})
+
+	diags := hcl.ValidationDiagnostics(ValidateModule("modules/vpc", Options{FS: fsys}))
+	if len(diags) != 1 {
+		t.Fatalf("Expected 1 duplicate, got %v", diags)
+	}
+	if diags[0].Subject.Filename != "modules/vpc/network.tf" {
+		t.Errorf("Expected duplicate reported in modules/vpc/network.tf, got %s", diags[0].Subject.Filename)
+	}
+}
@@ -0,0 +1,117 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/obergerkatz/sortTF/diff"
+	"github.com/obergerkatz/sortTF/internal/files"
+)
+
+// Status is the outcome of processing one file.
+type Status int
+
+// Status constants, in the order a report would usually list them.
+const (
+	// StatusUnchanged means the file is already sorted.
+	StatusUnchanged Status = iota
+	// StatusChanged means the file was sorted and written.
+	StatusChanged
+	// StatusWouldChange means the file needs sorting but was not written
+	// (DryRun or Validate mode).
+	StatusWouldChange
+	// StatusFailed means the file could not be processed; Result.Err says why.
+	StatusFailed
+	// StatusSkipped means the file was not processed because it is not a
+	// Terraform or Terragrunt file, or because the run was cancelled.
+	StatusSkipped
+)
+
+// String returns the status as a lowercase word, for reports and logs.
+func (s Status) String() string {
+	switch s {
+	case StatusUnchanged:
+		return "unchanged"
+	case StatusChanged:
+		return "changed"
+	case StatusWouldChange:
+		return "would-change"
+	case StatusFailed:
+		return "failed"
+	case StatusSkipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+// Result is the outcome of sorting the content of one file.
+type Result struct {
+	// Path is the file name the content was sorted as. It appears in
+	// diagnostics and is passed to Options.OnRewrite.
+	Path string
+
+	// Status tells what happened to the file.
+	Status Status
+
+	// Original is the content before sorting.
+	Original []byte
+
+	// Sorted is the sorted and formatted content.
+	Sorted []byte
+
+	// Changed reports whether Sorted differs from Original.
+	Changed bool
+
+	// OriginalSize and SortedSize are the lengths of Original and Sorted in bytes.
+	OriginalSize int
+	SortedSize   int
+
+	// Duration is how long processing the file took, including I/O.
+	Duration time.Duration
+
+	// Err is the reason the file could not be processed. It is set when
+	// Status is StatusFailed, and when it is StatusSkipped because the run
+	// was cancelled.
+	Err error
+}
+
+// newResult returns the Result of sorting original into sorted. Nothing has
+// been written, so the status is StatusUnchanged or StatusWouldChange.
+func newResult(path string, original, sorted []byte) Result {
+	result := Result{
+		Path:         path,
+		Status:       StatusUnchanged,
+		Original:     original,
+		Sorted:       sorted,
+		OriginalSize: len(original),
+		SortedSize:   len(sorted),
+	}
+	if !bytes.Equal(original, sorted) {
+		result.Status = StatusWouldChange
+		result.Changed = true
+	}
+	return result
+}
+
+// Diff returns the line-level differences between Original and Sorted, or
+// nil if the content did not change. It is computed on each call, so that
+// runs that never look at the differences do not pay for them.
+func (r Result) Diff() []diff.Line {
+	if !r.Changed {
+		return nil
+	}
+	return diff.Lines(string(r.Original), string(r.Sorted))
+}
+
+// UnifiedDiff returns the changes from Original to Sorted as a unified diff
+// with context unchanged lines around each change, or an empty string if
+// the content did not change. The paths in the header are Path relative to
+// the root of its git repository, as files.RepoPath returns it, with a/ and
+// b/ prefixes, so the diff can be applied with git apply or patch -p1 from
+// the repository root.
+func (r Result) UnifiedDiff(context int) string {
+	name := files.RepoPath(r.Path)
+	return diff.Unified("a/"+name, "b/"+name, string(r.Original), string(r.Sorted), context)
+}
@@ -0,0 +1,68 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/obergerkatz/sortTF/diff"
+)
+
+func TestStatus_String(t *testing.T) {
+	tests := []struct {
+		status Status
+		want   string
+	}{
+		{StatusUnchanged, "unchanged"},
+		{StatusChanged, "changed"},
+		{StatusWouldChange, "would-change"},
+		{StatusFailed, "failed"},
+		{StatusSkipped, "skipped"},
+		{Status(42), "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.status.String(); got != tt.want {
+			t.Errorf("Status(%d).String() = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	unchanged := newResult("a.tf", []byte("x\n"), []byte("x\n"))
+	if unchanged.Status != StatusUnchanged || unchanged.Changed || unchanged.Diff() != nil {
+		t.Errorf("Expected unchanged result without diff, got %+v", unchanged)
+	}
+
+	changed := newResult("a.tf", []byte("b\na\n"), []byte("a\nb\n"))
+	if changed.Status != StatusWouldChange || !changed.Changed {
+		t.Errorf("Expected would-change result, got %+v", changed)
+	}
+	if changed.OriginalSize != 4 || changed.SortedSize != 4 {
+		t.Errorf("Unexpected sizes %d, %d", changed.OriginalSize, changed.SortedSize)
+	}
+	if !diff.Changed(changed.Diff()) {
+		t.Errorf("Expected a diff with changes, got %v", changed.Diff())
+	}
+}
+
+func TestResult_UnifiedDiff(t *testing.T) {
+	unchanged := newResult("a.tf", []byte("x\n"), []byte("x\n"))
+	if got := unchanged.UnifiedDiff(3); got != "" {
+		t.Errorf("Expected no diff for unchanged content, got %q", got)
+	}
+
+	// The paths are relative to the repository root, not to the working
+	// directory, and never absolute
+	repo := t.TempDir()
+	//nolint:gosec // G301: Test directories can use 0755 permissions
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	changed := newResult(filepath.Join(repo, "modules", "a.tf"), []byte("b\na\n"), []byte("a\nb\n"))
+	want := "--- a/modules/a.tf\n+++ b/modules/a.tf\n@@ -1,2 +1,2 @@\n-b\n a\n+b\n"
+	if got := changed.UnifiedDiff(3); got != want {
+		t.Errorf("UnifiedDiff() =\n%s\nwant:\n%s", got, want)
+	}
+}
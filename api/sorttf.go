@@ -20,11 +20,8 @@
 //	}
 //
 //	// Sort multiple files
-//	results := api.SortFiles(paths, api.Options{DryRun: true})
-//	for path, err := range results {
-//	    if err != nil {
-//	        fmt.Printf("%s: %v\n", path, err)
-//	    }
+//	for _, result := range api.SortFiles(paths, api.Options{DryRun: true}) {
+//	    fmt.Printf("%s: %s\n", result.Path, result.Status)
 //	}
 //
 //nolint:revive // var-naming: api is an appropriate package name for an API layer
@@ -32,14 +29,22 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
-	"os"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/obergerkatz/sortTF/hcl"
 	"github.com/obergerkatz/sortTF/internal/files"
+	"github.com/obergerkatz/sortTF/vfs"
 
-	hcllib "github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 )
 
@@ -51,6 +56,121 @@ type Options struct {
 	// Validate checks if files are sorted without modifying them.
 	// Returns ErrNeedsSorting if changes are needed.
 	Validate bool
+
+	// Normalize rewrites legacy expression syntax while sorting, such as
+	// "${var.name}" wrappers and quoted type constraints.
+	Normalize bool
+
+	// SortListAttributes names attributes whose list values are semantically
+	// sets (e.g., depends_on) and are sorted. Lists containing anything other
+	// than literal strings and simple references are left untouched.
+	SortListAttributes []string
+
+	// MaxLineWidth wraps list, tuple and object constructors that make a line
+	// longer than this many columns onto one element per line. Zero disables wrapping.
+	MaxLineWidth int
+
+	// CollapseCollections joins short multi-line collections back onto one line
+	// when they fit within MaxLineWidth.
+	CollapseCollections bool
+
+	// CheckIdempotence sorts the sorted content a second time and fails with
+	// an *IdempotenceError if that changes it, since sorting twice should
+	// never change anything.
+	CheckIdempotence bool
+
+	// SkipSchemaValidation disables the structural checks of core block bodies
+	// (for example, an output without value) that run before sorting.
+	// Block label validation always runs.
+	SkipSchemaValidation bool
+
+	// FS is the file system files are discovered in, read from and written
+	// to. Writing requires an implementation of vfs.WriteFS; otherwise only
+	// DryRun and Validate modes work, and writes fail with vfs.ErrReadOnly.
+	// If nil, the operating system's file system is used.
+	FS fs.FS
+
+	// Backup, if set, saves the original content of each file right before
+	// SortFile, SortFiles or SortDirectory overwrite it; see SiblingBackup and
+	// BackupDir. A file whose backup fails is not written.
+	Backup Backup
+
+	// Workers bounds how many files SortFiles, SortDirectory and their
+	// Context variants process at once. Zero uses one worker per CPU. A
+	// worker whose file ran out of time (see FileTimeout) only takes the
+	// next file once the abandoned sort has finished.
+	Workers int
+
+	// Atomic makes SortFiles, SortDirectory and their Context variants all or
+	// nothing: every file is sorted before any is written, nothing is written
+	// if any file fails, and files already written are restored if a later
+	// write fails. Files that are not written because of this are reported
+	// with StatusSkipped and ErrAtomicAborted. OnResult is only called once
+	// the writes are done. It has no effect in DryRun and Validate modes.
+	Atomic bool
+
+	// FileTimeout bounds the time spent on each file by SortFiles,
+	// SortDirectory and their Context variants, including the time spent
+	// sorting it. A file that runs out of time fails as soon as it does and
+	// is not written; its sort is abandoned and finishes in the background,
+	// still counted against Workers. Zero means no limit.
+	FileTimeout time.Duration
+
+	// OnRewrite, if set, is called for each normalization rewrite applied to a file.
+	// It is only called when Normalize is enabled.
+	OnRewrite func(path string, rewrite hcl.Rewrite)
+
+	// The following callbacks report the progress of SortFiles, SortDirectory
+	// and their Context variants. Within one run, the callbacks (including
+	// OnRewrite) are never called concurrently with each other, although
+	// they are called from worker goroutines. Slow callbacks slow the run down.
+
+	// OnDiscover, if set, is called once with the files about to be
+	// processed, in path order, before any of them is started.
+	OnDiscover func(paths []string)
+
+	// OnStart, if set, is called when a worker starts processing a file.
+	OnStart func(path string)
+
+	// OnResult, if set, is called with the Result of each file a worker
+	// processed, as soon as it is done. Files that were skipped are not
+	// reported here.
+	OnResult func(result Result)
+
+	// OnComplete, if set, is called once at the end of the run with the
+	// results for every path, in path order, including skipped files.
+	OnComplete func(results []Result)
+}
+
+// sortOptions returns the hcl sorting options corresponding to opts. The
+// equivalence check only runs when files are written.
+func (opts Options) sortOptions() hcl.SortOptions {
+	return hcl.SortOptions{
+		Normalize:            opts.Normalize,
+		SortListAttributes:   opts.SortListAttributes,
+		SkipEquivalenceCheck: opts.DryRun || opts.Validate,
+		FormatOptions: hcl.FormatOptions{
+			MaxLineWidth:        opts.MaxLineWidth,
+			CollapseCollections: opts.CollapseCollections,
+		},
+	}
+}
+
+// fsys returns the file system files are read from and written to.
+func (opts Options) fsys() fs.FS {
+	if opts.FS == nil {
+		return vfs.OSFS{}
+	}
+	return opts.FS
+}
+
+// findFiles discovers the Terraform and Terragrunt files in dir. On the
+// operating system's file system, paths use the OS path separator.
+func (opts Options) findFiles(dir string, recursive bool) ([]string, error) {
+	if opts.FS == nil {
+		return files.FindFiles(dir, recursive)
+	}
+	return files.FindFilesFS(opts.FS, dir, recursive)
 }
 
 // Sentinel errors for common conditions.
@@ -60,6 +180,19 @@ var (
 
 	// ErrNeedsSorting indicates a file needs sorting (used in Validate mode).
 	ErrNeedsSorting = errors.New("file needs sorting")
+
+	// ErrConcurrentModification indicates a file changed between being read
+	// and the sorted content being written, so it was left alone. Sorting it
+	// again picks up the change.
+	ErrConcurrentModification = errors.New("file was modified while it was being sorted")
+
+	// ErrNotIdempotent indicates that sorting the sorted content of a file
+	// again changed it. Errors wrapping it are *IdempotenceError values.
+	ErrNotIdempotent = errors.New("sorting the sorted content again changed it")
+
+	// ErrAtomicAborted indicates a file was not written, or its write was
+	// rolled back, because another file of an Atomic run failed.
+	ErrAtomicAborted = errors.New("not written because another file failed")
 )
 
 // GetSortedContent reads a file and returns its sorted and formatted content.
@@ -72,48 +205,110 @@ var (
 //   - changed bool: whether the content differs from the original
 //   - error: parsing, validation, or I/O error
 func GetSortedContent(path string) (content string, changed bool, err error) {
-	// Step 1: Read file
-	origContent, err := os.ReadFile(path) // #nosec G304 -- File path comes from user input, which is expected for a file processing tool
+	return GetSortedContentWithOptions(path, Options{})
+}
+
+// GetSortedContentWithOptions is like GetSortedContent but applies the optional
+// sorting passes enabled in opts (for example, Normalize).
+// The DryRun and Validate fields are ignored since the file is never modified.
+func GetSortedContentWithOptions(path string, opts Options) (content string, changed bool, err error) {
+	origContent, err := readFile(opts.fsys(), path)
 	if err != nil {
-		return "", false, fmt.Errorf("read file: %w", err)
+		return "", false, err
 	}
 
-	// Step 2: Parse and validate
-	parsed, err := hcl.ParseHCLFile(path)
+	result, err := SortBytes(origContent, path, opts)
 	if err != nil {
-		return "", false, fmt.Errorf("parse: %w", err)
+		return "", false, err
 	}
 
-	if err := hcl.ValidateRequiredBlockLabels(parsed); err != nil {
-		return "", false, fmt.Errorf("validate: %w", err)
+	return string(result.Sorted), result.Changed, nil
+}
+
+// readAndParse reads a file, parses and validates it, and parses it again
+// with hclwrite for sorting. Schema validation runs unless opts disables it.
+// Returns the original content and the hclwrite file.
+func readAndParse(path string, opts Options) ([]byte, *hclwrite.File, error) {
+	origContent, err := readFile(opts.fsys(), path)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Step 3: Sort and format
-	hclFile, diags := hclwrite.ParseConfig(origContent, path, hcllib.Pos{Line: 1, Column: 1})
-	if diags.HasErrors() {
-		return "", false, fmt.Errorf("parse for formatting: %w", diags)
+	hclFile, err := parseAndValidate(origContent, path, opts)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	formatted, err := hcl.SortAndFormatHCLFile(hclFile)
+	return origContent, hclFile, nil
+}
+
+// readFile reads the content of the file at path in fsys.
+func readFile(fsys fs.FS, path string) ([]byte, error) {
+	content, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	return content, nil
+}
+
+// fileVersion identifies the content of a file at the time it was read.
+type fileVersion struct {
+	modTime time.Time
+	hash    [sha256.Size]byte
+}
+
+// readVersion reads the file at path in fsys together with its version.
+func readVersion(fsys fs.FS, path string) ([]byte, fileVersion, error) {
+	// Stat before reading, so a write in between shows up as a newer
+	// modification time rather than going unnoticed
+	info, err := fs.Stat(fsys, path)
+	if err != nil {
+		return nil, fileVersion{}, fmt.Errorf("read file: %w", err)
+	}
+	content, err := readFile(fsys, path)
 	if err != nil {
-		return "", false, fmt.Errorf("sort/format: %w", err)
+		return nil, fileVersion{}, err
 	}
+	return content, fileVersion{modTime: info.ModTime(), hash: sha256.Sum256(content)}, nil
+}
 
-	// Step 4: Check if changed
-	hasChanges := !bytes.Equal(origContent, []byte(formatted))
+// check returns ErrConcurrentModification if the file at path in fsys is no
+// longer the version v, because it was modified or removed.
+func (v fileVersion) check(fsys fs.FS, path string) error {
+	info, err := fs.Stat(fsys, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrConcurrentModification
+	}
+	if err != nil {
+		return fmt.Errorf("check file: %w", err)
+	}
+	if !info.ModTime().Equal(v.modTime) {
+		return ErrConcurrentModification
+	}
 
-	return formatted, hasChanges, nil
+	// The modification time may be too coarse to reveal a quick edit
+	content, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return fmt.Errorf("check file: %w", err)
+	}
+	if sha256.Sum256(content) != v.hash {
+		return ErrConcurrentModification
+	}
+	return nil
 }
 
 // SortFile sorts and formats a single Terraform or Terragrunt file.
 //
 // It reads the file, parses and validates the HCL, sorts blocks and attributes,
-// applies formatting, and writes the result back atomically.
+// applies formatting, and writes the result back atomically, keeping the
+// file's mode, owner and symbolic links; see vfs.OSFS.WriteFile.
 //
 // Returns:
 //   - nil: file was successfully sorted and written
 //   - ErrNoChanges: file is already sorted (not an error condition)
 //   - ErrNeedsSorting: file needs sorting (only in Validate mode)
+//   - ErrConcurrentModification: the file changed while it was being sorted
+//     and was not written
 //   - error: parsing, validation, or I/O error
 //
 // Behavior based on Options:
@@ -121,84 +316,454 @@ func GetSortedContent(path string) (content string, changed bool, err error) {
 //   - Validate: returns ErrNeedsSorting if changes needed, doesn't modify
 //   - Normal: sorts and writes the file if changes are needed
 func SortFile(path string, opts Options) error {
-	// Get sorted content
-	formatted, changed, err := GetSortedContent(path)
+	_, err := ProcessFile(path, opts)
+	return err
+}
+
+// ProcessFile is like SortFile but also returns the Result, so callers can
+// show the original and sorted content, for example as a diff, without
+// reading or sorting the file again. The file is read and sorted once.
+//
+// The returned error follows the conventions of SortFile. The Result always
+// carries the path, status and duration; its content is filled in whenever
+// the file could be sorted, including when the error is ErrNoChanges or
+// ErrNeedsSorting.
+func ProcessFile(path string, opts Options) (Result, error) {
+	return ProcessFileContext(context.Background(), path, opts)
+}
+
+// ProcessFileContext is like ProcessFile but gives up as soon as ctx is
+// done, even in the middle of sorting, and never writes the file after that;
+// the returned error is then ctx.Err(). A sort given up on finishes in the
+// background, and its rewrites are not passed to opts.OnRewrite.
+func ProcessFileContext(ctx context.Context, path string, opts Options) (Result, error) {
+	return processFileContext(ctx, path, opts, nil)
+}
+
+// processFileContext is ProcessFileContext. If sorting is not nil, a sort
+// given up on is added to it until it finishes (see processFileUntilDone).
+func processFileContext(ctx context.Context, path string, opts Options, sorting *sync.WaitGroup) (Result, error) {
+	start := time.Now()
+	result, pending, err := processFileUntilDone(ctx, path, opts, sorting)
+	if pending != nil {
+		// Sorting may have taken long enough for the caller to give up
+		if err = ctx.Err(); err == nil {
+			err = pending.commit(opts)
+		}
+		if err == nil {
+			result.Status = StatusChanged
+		}
+	}
+	return finishResult(result, path, start, err), err
+}
+
+// finishResult fills in the path, duration and, for errors other than
+// ErrNoChanges and ErrNeedsSorting, the failure of a file's Result.
+func finishResult(result Result, path string, start time.Time, err error) Result {
+	result.Path = path
+	result.Duration = time.Since(start)
+
+	if err != nil && !errors.Is(err, ErrNoChanges) && !errors.Is(err, ErrNeedsSorting) {
+		result.Status = StatusFailed
+		result.Err = err
+	}
+	return result
+}
+
+// pendingWrite is the sorted content of a file that is ready to be written.
+type pendingWrite struct {
+	path     string
+	original []byte
+	sorted   []byte
+	version  fileVersion
+}
+
+// processFile reads and sorts the file at path. If opts says the file should
+// be written, it returns the pending write instead of writing it. See
+// ProcessFileContext.
+func processFile(ctx context.Context, path string, opts Options) (Result, *pendingWrite, error) {
+	if err := ctx.Err(); err != nil {
+		return Result{}, nil, err
+	}
+
+	origContent, version, err := readVersion(opts.fsys(), path)
 	if err != nil {
-		return err
+		return Result{}, nil, err
+	}
+
+	result, err := sortBytes(origContent, path, opts, true)
+	if err != nil {
+		return Result{}, nil, err
 	}
 
 	// No changes needed
-	if !changed {
-		return ErrNoChanges
+	if !result.Changed {
+		return result, nil, ErrNoChanges
 	}
 
 	// Handle modes
 	// Dry-run takes precedence over validate (non-destructive preview)
 	if opts.DryRun {
 		// Don't write, just indicate changes would be made
-		return nil
+		return result, nil, nil
 	}
 
 	if opts.Validate {
-		return ErrNeedsSorting
+		return result, nil, ErrNeedsSorting
 	}
 
-	// Write atomically (normal mode)
-	tmpFile := path + ".tmp"
-	// #nosec G306 -- 0644 is appropriate for Terraform configuration files that may be shared
-	if err := os.WriteFile(tmpFile, []byte(formatted), 0644); err != nil {
-		return fmt.Errorf("write temp file: %w", err)
-	}
+	return result, &pendingWrite{path: path, original: origContent, sorted: result.Sorted, version: version}, nil
+}
 
-	if err := os.Rename(tmpFile, path); err != nil {
-		// Try to clean up temp file
-		_ = os.Remove(tmpFile)
-		return fmt.Errorf("replace file: %w", err)
+// commit writes the sorted content atomically, unless someone else changed
+// the file since it was read. The backup is taken last, so it is only kept
+// for files that are about to be replaced.
+func (w *pendingWrite) commit(opts Options) error {
+	check := func() error {
+		if err := w.version.check(opts.fsys(), w.path); err != nil {
+			return err
+		}
+		if opts.Backup != nil {
+			return opts.Backup.Save(opts.fsys(), w.path, w.original, w.sorted)
+		}
+		return nil
 	}
+	return writeFileIf(opts.fsys(), w.path, w.sorted, check)
+}
 
+// rollback puts the original content of a committed file back, unless
+// someone else changed the file since it was written.
+func (w *pendingWrite) rollback(opts Options) error {
+	check := func() error {
+		content, err := fs.ReadFile(opts.fsys(), w.path)
+		if err != nil {
+			return fmt.Errorf("check file: %w", err)
+		}
+		if !bytes.Equal(content, w.sorted) {
+			return ErrConcurrentModification
+		}
+		return nil
+	}
+	if err := writeFileIf(opts.fsys(), w.path, w.original, check); err != nil {
+		return fmt.Errorf("roll back: %w", err)
+	}
 	return nil
 }
 
-// SortFiles sorts multiple files and returns results for each.
+// writeFile writes content to the file at path in fsys, which replaces the
+// file atomically if fsys supports it.
+func writeFile(fsys fs.FS, path string, content []byte) error {
+	return writeFileIf(fsys, path, content, nil)
+}
+
+// writeFileIf is like writeFile but calls check, if not nil, right before the
+// file is replaced, and leaves the file alone if check fails.
+func writeFileIf(fsys fs.FS, path string, content []byte, check func() error) error {
+	// New files get 0644, appropriate for shared Terraform configuration;
+	// existing files keep their mode
+	switch wfs := fsys.(type) {
+	case vfs.ConditionalWriteFS:
+		return wfs.WriteFileIf(path, content, 0644, check)
+	case vfs.WriteFS:
+		if check != nil {
+			if err := check(); err != nil {
+				return err
+			}
+		}
+		return wfs.WriteFile(path, content, 0644)
+	default:
+		return fmt.Errorf("write file: %w", vfs.ErrReadOnly)
+	}
+}
+
+// removeFile removes the file at path in fsys.
+func removeFile(fsys fs.FS, path string) error {
+	wfs, ok := fsys.(vfs.WriteFS)
+	if !ok {
+		return fmt.Errorf("remove file: %w", vfs.ErrReadOnly)
+	}
+	return wfs.Remove(path)
+}
+
+// SortFiles sorts multiple files and returns a Result for each, in path order.
 //
-// It processes files independently and continues on error, allowing you to
-// see results for all files even if some fail.
+// It processes files independently, with up to opts.Workers at once, and
+// continues on error, allowing you to see results for all files even if some
+// fail. Paths without a .tf or .hcl extension are skipped.
 //
-// Returns a map of filePath -> error, where:
-//   - nil: file was successfully sorted
-//   - ErrNoChanges: file was already sorted (not an error)
-//   - ErrNeedsSorting: file needs sorting (Validate mode)
-//   - error: file failed to process
+// Each Result's Status tells what happened:
+//   - StatusChanged: file was successfully sorted and written
+//   - StatusUnchanged: file was already sorted
+//   - StatusWouldChange: file needs sorting (DryRun or Validate mode)
+//   - StatusFailed: file failed to process or ran out of time; Err says why
+//   - StatusSkipped: file is not a Terraform or Terragrunt file
 //
 // Example:
 //
-//	results := SortFiles(paths, Options{})
-//	for path, err := range results {
-//	    if err != nil && !errors.Is(err, ErrNoChanges) {
-//	        fmt.Printf("Error processing %s: %v\n", path, err)
+//	for _, result := range SortFiles(paths, Options{}) {
+//	    if result.Status == StatusFailed {
+//	        fmt.Printf("Error processing %s: %v\n", result.Path, result.Err)
 //	    }
 //	}
-func SortFiles(paths []string, opts Options) map[string]error {
-	results := make(map[string]error)
-	for _, path := range paths {
-		results[path] = SortFile(path, opts)
-	}
+func SortFiles(paths []string, opts Options) []Result {
+	results, _ := SortFilesContext(context.Background(), paths, opts)
 	return results
 }
 
+// SortFilesContext is like SortFiles but stops scheduling files as soon as
+// ctx is done. Files that were not started, or were stopped before being
+// written, are reported with StatusSkipped and ctx.Err() as their Err.
+//
+// Returns the results for every path, in path order, and ctx.Err() if the
+// run was cancelled.
+func SortFilesContext(ctx context.Context, paths []string, opts Options) ([]Result, error) {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+
+	results := make([]Result, len(sorted))
+	for i, path := range sorted {
+		results[i] = Result{Path: path, Status: StatusSkipped}
+	}
+
+	ev := newEvents(opts)
+	if opts.OnRewrite != nil {
+		opts.OnRewrite = ev.rewrite
+	}
+
+	var supported []int
+	for i, path := range sorted {
+		if isSupportedPath(path) {
+			supported = append(supported, i)
+		}
+	}
+	discovered := make([]string, len(supported))
+	for n, i := range supported {
+		discovered[n] = sorted[i]
+	}
+	ev.discover(discovered)
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, len(supported))
+
+	// In Atomic mode, files are only written, and reported, once all are sorted
+	atomic := opts.Atomic && !opts.DryRun && !opts.Validate
+	pending := make([]*pendingWrite, len(sorted))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A sort given up on at its FileTimeout keeps running until it
+			// finishes; the worker waits for it before taking another file,
+			// so no more than workers files are sorted at once
+			var sorting sync.WaitGroup
+			for i := range jobs {
+				sorting.Wait()
+				ev.start(sorted[i])
+				results[i], pending[i] = sortOne(ctx, sorted[i], opts, atomic, &sorting)
+				if !atomic && results[i].Status != StatusSkipped {
+					ev.result(results[i])
+				}
+			}
+		}()
+	}
+
+	// Stop handing out files once ctx is done
+schedule:
+	for _, i := range supported {
+		select {
+		case <-ctx.Done():
+			break schedule
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if atomic {
+		commitAll(ctx, results, pending, opts)
+		for _, i := range supported {
+			if results[i].Status != StatusSkipped {
+				ev.result(results[i])
+			}
+		}
+	}
+
+	err := ctx.Err()
+	if err != nil {
+		for _, i := range supported {
+			if results[i].Status == StatusSkipped && results[i].Err == nil {
+				results[i].Err = err
+			}
+		}
+	}
+
+	ev.complete(results)
+	return results, err
+}
+
+// sortOne processes one file for SortFilesContext, applying opts.FileTimeout.
+// If deferWrite is set, a file that needs writing is returned as a pending
+// write, with StatusWouldChange, instead of being written. A sort given up on
+// is added to sorting until it finishes.
+func sortOne(ctx context.Context, path string, opts Options, deferWrite bool, sorting *sync.WaitGroup) (Result, *pendingWrite) {
+	fileCtx := ctx
+	if opts.FileTimeout > 0 {
+		var cancel context.CancelFunc
+		fileCtx, cancel = context.WithTimeout(ctx, opts.FileTimeout)
+		defer cancel()
+	}
+
+	var result Result
+	var pending *pendingWrite
+	var err error
+	if deferWrite {
+		start := time.Now()
+		result, pending, err = processFileUntilDone(fileCtx, path, opts, sorting)
+		result = finishResult(result, path, start, err)
+	} else {
+		result, err = processFileContext(fileCtx, path, opts, sorting)
+	}
+
+	switch {
+	case err == nil || result.Status != StatusFailed:
+	case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+		// The whole run was cancelled, not just this file
+		result.Status = StatusSkipped
+	case errors.Is(err, context.DeadlineExceeded):
+		result.Err = fmt.Errorf("timed out after %s: %w", opts.FileTimeout, err)
+	}
+	return result, pending
+}
+
+// processFileUntilDone is like processFile, but returns ctx.Err() as soon as
+// ctx is done, even in the middle of sorting a large file. The abandoned
+// sort finishes in the background without effect: the rewrites it applies
+// are only passed to opts.OnRewrite once the file is sorted in time. If
+// sorting is not nil, the sort is added to it until it finishes, so callers
+// can wait for abandoned sorts.
+func processFileUntilDone(ctx context.Context, path string, opts Options, sorting *sync.WaitGroup) (Result, *pendingWrite, error) {
+	if ctx.Done() == nil {
+		return processFile(ctx, path, opts)
+	}
+
+	var rewrites []hcl.Rewrite
+	onRewrite := opts.OnRewrite
+	if onRewrite != nil {
+		opts.OnRewrite = func(_ string, rewrite hcl.Rewrite) {
+			rewrites = append(rewrites, rewrite)
+		}
+	}
+
+	type outcome struct {
+		result  Result
+		pending *pendingWrite
+		err     error
+	}
+	done := make(chan outcome, 1)
+	if sorting != nil {
+		sorting.Add(1)
+	}
+	go func() {
+		if sorting != nil {
+			defer sorting.Done()
+		}
+		result, pending, err := processFile(ctx, path, opts)
+		done <- outcome{result, pending, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return Result{}, nil, ctx.Err()
+	case out := <-done:
+		for _, rewrite := range rewrites {
+			onRewrite(path, rewrite)
+		}
+		return out.result, out.pending, out.err
+	}
+}
+
+// commitAll writes the pending files of an Atomic run in path order,
+// updating their results. Nothing is written if any file failed or ctx is
+// done. If a write fails, the files written before it are rolled back.
+func commitAll(ctx context.Context, results []Result, pending []*pendingWrite, opts Options) {
+	var abort error
+	for _, result := range results {
+		if result.Status == StatusFailed {
+			abort = ErrAtomicAborted
+			break
+		}
+	}
+
+	var written []int
+	for i, w := range pending {
+		if w == nil {
+			continue
+		}
+		if abort == nil {
+			// A cancelled run is reported as such, as SortFilesContext does
+			abort = ctx.Err()
+		}
+		if abort != nil {
+			results[i].Status = StatusSkipped
+			results[i].Err = abort
+			continue
+		}
+
+		if err := w.commit(opts); err != nil {
+			results[i].Status = StatusFailed
+			results[i].Err = err
+			abort = ErrAtomicAborted
+			continue
+		}
+		results[i].Status = StatusChanged
+		written = append(written, i)
+	}
+
+	if abort == nil {
+		return
+	}
+	for _, i := range slices.Backward(written) {
+		if err := pending[i].rollback(opts); err != nil {
+			results[i].Status = StatusFailed
+			results[i].Err = err
+			continue
+		}
+		results[i].Status = StatusSkipped
+		results[i].Err = abort
+	}
+}
+
+// isSupportedPath reports whether path has a .tf or .hcl extension.
+func isSupportedPath(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".tf" || ext == ".hcl"
+}
+
 // SortDirectory sorts all Terraform/Terragrunt files in a directory.
 //
 // It finds all .tf and .hcl files in the directory (optionally recursive)
 // and sorts them according to the provided options.
 //
-// Returns a map of filePath -> error for all discovered files.
-func SortDirectory(dir string, recursive bool, opts Options) (map[string]error, error) {
+// Returns a Result for every discovered file, in path order, as SortFiles does.
+func SortDirectory(dir string, recursive bool, opts Options) ([]Result, error) {
+	return SortDirectoryContext(context.Background(), dir, recursive, opts)
+}
+
+// SortDirectoryContext is like SortDirectory but stops scheduling files as
+// soon as ctx is done, as SortFilesContext does.
+func SortDirectoryContext(ctx context.Context, dir string, recursive bool, opts Options) ([]Result, error) {
 	// Find all files
-	paths, err := files.FindFiles(dir, recursive)
+	paths, err := opts.findFiles(dir, recursive)
 	if err != nil {
 		return nil, fmt.Errorf("find files: %w", err)
 	}
 
 	// Sort all files
-	return SortFiles(paths, opts), nil
+	return SortFilesContext(ctx, paths, opts)
 }
@@ -2,10 +2,20 @@
 package api
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/obergerkatz/sortTF/hcl"
+	"github.com/obergerkatz/sortTF/vfs"
 )
 
 func TestSortFile(t *testing.T) {
@@ -56,6 +66,55 @@ variable "environment" {
 	}
 }
 
+func TestProcessFile(t *testing.T) {
+	const unsorted = "variable \"b\" {\n  type = string\n}\nvariable \"a\" {\n  type = string\n}\n"
+	const sorted = "variable \"a\" {\n  type = string\n}\n\nvariable \"b\" {\n  type = string\n}\n"
+
+	tests := []struct {
+		name       string
+		content    string
+		opts       Options
+		wantErr    error
+		wantStatus Status
+		wantFile   string
+	}{
+		{name: "writes sorted content", content: unsorted, wantStatus: StatusChanged, wantFile: sorted},
+		{name: "dry run", content: unsorted, opts: Options{DryRun: true}, wantStatus: StatusWouldChange, wantFile: unsorted},
+		{name: "validate", content: unsorted, opts: Options{Validate: true}, wantErr: ErrNeedsSorting, wantStatus: StatusWouldChange, wantFile: unsorted},
+		{name: "already sorted", content: sorted, wantErr: ErrNoChanges, wantStatus: StatusUnchanged, wantFile: sorted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "test.tf")
+			//nolint:gosec // G306: Test files can use 0644 permissions
+			if err := os.WriteFile(testFile, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := ProcessFile(testFile, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ProcessFile() error = %v, want %v", err, tt.wantErr)
+			}
+			if string(result.Original) != tt.content || string(result.Sorted) != sorted {
+				t.Errorf("Unexpected result: %+v", result)
+			}
+			if result.Status != tt.wantStatus || result.Path != testFile {
+				t.Errorf("Status = %s, path %s; want %s", result.Status, result.Path, tt.wantStatus)
+			}
+
+			//nolint:gosec // G304: Test file path is controlled
+			content, err := os.ReadFile(testFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.wantFile {
+				t.Errorf("File content = %q, want %q", content, tt.wantFile)
+			}
+		})
+	}
+}
+
 func TestSortFile_Validate(t *testing.T) {
 	tmpDir := t.TempDir()
 	testFile := filepath.Join(tmpDir, "test.tf")
@@ -177,9 +236,9 @@ func TestSortFiles(t *testing.T) {
 		t.Errorf("Expected 2 results, got %d", len(results))
 	}
 
-	for path, err := range results {
-		if err != nil {
-			t.Errorf("File %s failed: %v", path, err)
+	for _, result := range results {
+		if result.Status != StatusChanged {
+			t.Errorf("File %s: status %s, err %v", result.Path, result.Status, result.Err)
 		}
 	}
 }
@@ -468,25 +527,37 @@ func TestSortFiles_MixedResults(t *testing.T) {
 	// Add non-existent file
 	nonexistentFile := filepath.Join(tmpDir, "nonexistent.tf")
 
-	results := SortFiles([]string{validFile, invalidFile, nonexistentFile}, Options{})
+	// Add file of another type
+	textFile := filepath.Join(tmpDir, "notes.txt")
 
-	if len(results) != 3 {
-		t.Errorf("Expected 3 results, got %d", len(results))
-	}
+	results := SortFiles([]string{validFile, invalidFile, nonexistentFile, textFile}, Options{})
 
-	// Valid file should succeed
-	if results[validFile] != nil {
-		t.Errorf("Valid file should succeed, got: %v", results[validFile])
+	// Results come back in path order
+	expected := []struct {
+		path   string
+		status Status
+	}{
+		{invalidFile, StatusFailed},
+		{nonexistentFile, StatusFailed},
+		{textFile, StatusSkipped},
+		{validFile, StatusChanged},
 	}
-
-	// Invalid file should error
-	if results[invalidFile] == nil {
-		t.Error("Invalid file should error")
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+	for i, want := range expected {
+		got := results[i]
+		if got.Path != want.path || got.Status != want.status {
+			t.Errorf("results[%d] = %s %s, want %s %s", i, got.Path, got.Status, want.path, want.status)
+		}
+		if (got.Status == StatusFailed) != (got.Err != nil) {
+			t.Errorf("results[%d]: Err %v does not match status %s", i, got.Err, got.Status)
+		}
 	}
 
-	// Nonexistent file should error
-	if results[nonexistentFile] == nil {
-		t.Error("Nonexistent file should error")
+	valid := results[3]
+	if valid.OriginalSize != len(validContent) || valid.SortedSize != len(valid.Sorted) || len(valid.Diff()) == 0 {
+		t.Errorf("Expected sizes and diff for valid file, got %+v", valid)
 	}
 }
 
@@ -661,8 +732,670 @@ func TestSortDirectory_WithValidate(t *testing.T) {
 		t.Errorf("Expected 1 result, got %d", len(results))
 	}
 
-	// Check that the result is ErrNeedsSorting
-	if !errors.Is(results[file1], ErrNeedsSorting) {
-		t.Errorf("Expected ErrNeedsSorting, got: %v", results[file1])
+	// Check that the file needs sorting
+	if results[0].Status != StatusWouldChange || results[0].Err != nil {
+		t.Errorf("Expected StatusWouldChange, got: %s (%v)", results[0].Status, results[0].Err)
+	}
+}
+
+// TestSortFile_Normalize tests that Normalize rewrites legacy syntax and reports each rewrite
+func TestSortFile_Normalize(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+
+	content := `variable "ami" {
+  type = "string"
+}
+
+resource "aws_instance" "web" {
+  ami = "${var.ami}"
+}
+`
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var reported []hcl.Rewrite
+	opts := Options{
+		Normalize: true,
+		OnRewrite: func(path string, rewrite hcl.Rewrite) {
+			if path != testFile {
+				t.Errorf("OnRewrite path = %q, want %q", path, testFile)
+			}
+			reported = append(reported, rewrite)
+		},
+	}
+
+	if err := SortFile(testFile, opts); err != nil {
+		t.Fatalf("SortFile failed: %v", err)
+	}
+
+	if len(reported) != 2 {
+		t.Errorf("Expected 2 rewrites reported, got %d: %v", len(reported), reported)
+	}
+
+	result, _ := os.ReadFile(testFile) //nolint:gosec // G304: Test file path is controlled
+	if contains(string(result), "${") || contains(string(result), `"string"`) {
+		t.Errorf("Legacy syntax remains after normalization:\n%s", result)
+	}
+
+	// Without Normalize, the file is left alone once sorted
+	if err := SortFile(testFile, Options{}); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("Expected ErrNoChanges on normalized file, got: %v", err)
+	}
+}
+
+// TestGetSortedContentWithOptions_MaxLineWidth tests that long collections are wrapped
+func TestGetSortedContentWithOptions_MaxLineWidth(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+
+	content := `resource "aws_instance" "web" {
+  vpc_security_group_ids = ["sg-0123456789abcdef0", "sg-0123456789abcdef1", "sg-0123456789abcdef2"]
+}
+`
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a width, the file is already sorted
+	if _, changed, err := GetSortedContent(testFile); err != nil || changed {
+		t.Fatalf("Expected no changes without a width, got changed=%v err=%v", changed, err)
+	}
+
+	sorted, changed, err := GetSortedContentWithOptions(testFile, Options{MaxLineWidth: 80})
+	if err != nil {
+		t.Fatalf("GetSortedContentWithOptions failed: %v", err)
+	}
+	if !changed {
+		t.Error("Expected changes when wrapping at 80 columns")
+	}
+	if !contains(sorted, "    \"sg-0123456789abcdef2\",\n  ]") {
+		t.Errorf("Expected wrapped list with trailing comma, got:\n%s", sorted)
+	}
+}
+
+// TestSortFile_SortListAttributes tests sorting of set-like list attributes
+func TestSortFile_SortListAttributes(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+
+	content := `resource "aws_instance" "web" {
+  depends_on = [aws_subnet.b, aws_iam_role.a]
+}
+`
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SortFile(testFile, Options{SortListAttributes: []string{"depends_on"}}); err != nil {
+		t.Fatalf("SortFile failed: %v", err)
+	}
+
+	result, _ := os.ReadFile(testFile) //nolint:gosec // G304: Test file path is controlled
+	if !contains(string(result), "[aws_iam_role.a, aws_subnet.b]") {
+		t.Errorf("Expected sorted depends_on, got:\n%s", result)
+	}
+}
+
+// TestSortFile_SchemaValidation tests that schema errors stop sorting unless disabled
+func TestSortFile_SchemaValidation(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+
+	content := `output "id" {
+  description = "ID"
+}
+`
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := SortFile(testFile, Options{})
+	if !hcl.IsSchemaError(err) {
+		t.Fatalf("Expected schema error, got: %v", err)
+	}
+
+	if err := SortFile(testFile, Options{SkipSchemaValidation: true}); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("Expected ErrNoChanges with schema validation disabled, got: %v", err)
+	}
+}
+
+// TestSortFilesContext tests cancellation, worker bounds and per-file timeouts
+func TestSortFilesContext(t *testing.T) {
+	const unsorted = "variable \"b\" {\n  type = string\n}\nvariable \"a\" {\n  type = string\n}\n"
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		opts       Options
+		wantErr    error
+		wantStatus Status
+		wantResult error
+	}{
+		{
+			name:       "single worker",
+			ctx:        context.Background(),
+			opts:       Options{Workers: 1},
+			wantStatus: StatusChanged,
+		},
+		{
+			name:       "more workers than files",
+			ctx:        context.Background(),
+			opts:       Options{Workers: 64},
+			wantStatus: StatusChanged,
+		},
+		{
+			name:       "cancelled before start",
+			ctx:        cancelled,
+			wantErr:    context.Canceled,
+			wantStatus: StatusSkipped,
+			wantResult: context.Canceled,
+		},
+		{
+			name:       "file timeout",
+			ctx:        context.Background(),
+			opts:       Options{FileTimeout: time.Nanosecond},
+			wantStatus: StatusFailed,
+			wantResult: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			var paths []string
+			for _, name := range []string{"c.tf", "a.tf", "b.tf"} {
+				path := filepath.Join(tmpDir, name)
+				//nolint:gosec // G306: Test files can use 0644 permissions
+				if err := os.WriteFile(path, []byte(unsorted), 0644); err != nil {
+					t.Fatal(err)
+				}
+				paths = append(paths, path)
+			}
+
+			results, err := SortFilesContext(tt.ctx, paths, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SortFilesContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(results) != len(paths) {
+				t.Fatalf("Expected %d results, got %d", len(paths), len(results))
+			}
+
+			for i, name := range []string{"a.tf", "b.tf", "c.tf"} {
+				result := results[i]
+				if result.Path != filepath.Join(tmpDir, name) {
+					t.Errorf("results[%d].Path = %s, want %s", i, result.Path, name)
+				}
+				if result.Status != tt.wantStatus || !errors.Is(result.Err, tt.wantResult) {
+					t.Errorf("results[%d] = %s (%v), want %s (%v)", i, result.Status, result.Err, tt.wantStatus, tt.wantResult)
+				}
+
+				// Nothing is written unless the file was sorted
+				content, err := os.ReadFile(result.Path) //nolint:gosec // G304: Test file path from t.TempDir()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if written := string(content) != unsorted; written != (result.Status == StatusChanged) {
+					t.Errorf("%s: written = %v with status %s", name, written, result.Status)
+				}
+			}
+		})
+	}
+}
+
+// TestSortFilesContext_SlowSort tests that FileTimeout stops waiting for a
+// file in the middle of sorting it, not only between reading, sorting and
+// writing
+func TestSortFilesContext_SlowSort(t *testing.T) {
+	// Sorting this takes far longer than the timeout
+	var content strings.Builder
+	for i := 2000; i > 0; i-- {
+		fmt.Fprintf(&content, "variable \"v%04d\" {\n  type = \"string\"\n}\n\n", i)
+	}
+
+	tests := []struct {
+		name   string
+		dryRun bool
+	}{
+		{"dry run", true},
+		{"write", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "main.tf")
+			//nolint:gosec // G306: Test files can use 0644 permissions
+			if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var mu sync.Mutex
+			rewrites := 0
+			opts := Options{
+				DryRun:      tt.dryRun,
+				Normalize:   true,
+				FileTimeout: 10 * time.Millisecond,
+				OnRewrite: func(string, hcl.Rewrite) {
+					mu.Lock()
+					defer mu.Unlock()
+					rewrites++
+				},
+			}
+
+			results, err := SortFilesContext(context.Background(), []string{path}, opts)
+			if err != nil {
+				t.Fatalf("SortFilesContext() error = %v", err)
+			}
+			if results[0].Status != StatusFailed || !errors.Is(results[0].Err, context.DeadlineExceeded) {
+				t.Errorf("Expected a timeout, got %s (%v)", results[0].Status, results[0].Err)
+			}
+
+			// The abandoned sort reports nothing and writes nothing
+			mu.Lock()
+			defer mu.Unlock()
+			if rewrites != 0 {
+				t.Errorf("Expected no rewrites reported for a file that timed out, got %d", rewrites)
+			}
+			written, err := os.ReadFile(path) //nolint:gosec // G304: Test file path from t.TempDir()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(written) != content.String() {
+				t.Error("Expected a file that timed out to be left alone")
+			}
+		})
+	}
+}
+
+// slowFS is a file system whose files take delay to open. It records how
+// many opens were in progress at once.
+type slowFS struct {
+	fs.FS
+	delay time.Duration
+
+	mu           sync.Mutex
+	active, peak int
+}
+
+// Open opens name after the delay.
+func (f *slowFS) Open(name string) (fs.File, error) {
+	f.mu.Lock()
+	f.active++
+	f.peak = max(f.peak, f.active)
+	f.mu.Unlock()
+
+	time.Sleep(f.delay)
+
+	f.mu.Lock()
+	f.active--
+	f.mu.Unlock()
+	return f.FS.Open(name)
+}
+
+// TestSortFilesContext_TimeoutKeepsWorker tests that a worker whose file ran
+// out of time only takes the next file once the abandoned file is done, so
+// no more than Workers files are processed at once
+func TestSortFilesContext_TimeoutKeepsWorker(t *testing.T) {
+	fsys := &slowFS{FS: fstest.MapFS{}, delay: 50 * time.Millisecond}
+	var paths []string
+	for _, name := range []string{"a.tf", "b.tf", "c.tf"} {
+		fsys.FS.(fstest.MapFS)[name] = &fstest.MapFile{Data: []byte("variable \"b\" {}\n\nvariable \"a\" {}\n")}
+		paths = append(paths, name)
+	}
+
+	opts := Options{FS: fsys, DryRun: true, Workers: 1, FileTimeout: 10 * time.Millisecond}
+	results, err := SortFilesContext(context.Background(), paths, opts)
+	if err != nil {
+		t.Fatalf("SortFilesContext() error = %v", err)
+	}
+	for _, result := range results {
+		if result.Status != StatusFailed || !errors.Is(result.Err, context.DeadlineExceeded) {
+			t.Errorf("%s: expected a timeout, got %s (%v)", result.Path, result.Status, result.Err)
+		}
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.peak != 1 {
+		t.Errorf("Expected at most 1 file processed at once with 1 worker, got %d", fsys.peak)
+	}
+}
+
+// TestSortDirectoryContext tests that a cancelled run reports every file as skipped
+func TestSortDirectoryContext(t *testing.T) {
+	tmpDir := t.TempDir()
+	//nolint:gosec // G306: Test files can use 0644 permissions
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte("variable \"a\" {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := SortDirectoryContext(ctx, tmpDir, false, Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(results) != 1 || results[0].Status != StatusSkipped {
+		t.Errorf("Expected one skipped result, got %+v", results)
+	}
+}
+
+// TestSortDirectory_FS tests sorting files in an in-memory file system.
+func TestSortDirectory_FS(t *testing.T) {
+	unsorted := "variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n"
+	fsys := vfs.NewMemFS(map[string][]byte{
+		"main.tf":             []byte(unsorted),
+		"modules/vpc/main.tf": []byte(unsorted),
+		"README.md":           []byte(unsorted),
+	})
+
+	results, err := SortDirectory(".", true, Options{FS: fsys})
+	if err != nil {
+		t.Fatalf("SortDirectory() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %+v", results)
+	}
+
+	for _, result := range results {
+		if result.Status != StatusChanged {
+			t.Errorf("%s: status = %s, want %s (err %v)", result.Path, result.Status, StatusChanged, result.Err)
+		}
+		got, err := fsys.ReadFile(result.Path)
+		if err != nil {
+			t.Fatalf("ReadFile(%q) error = %v", result.Path, err)
+		}
+		if string(got) != string(result.Sorted) {
+			t.Errorf("%s was not written:\n%s", result.Path, got)
+		}
+	}
+}
+
+// TestSortFile_ReadOnlyFS tests that read-only file systems support dry runs
+// but fail to write.
+func TestSortFile_ReadOnlyFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.tf": &fstest.MapFile{Data: []byte("variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n")},
+	}
+
+	tests := []struct {
+		name       string
+		opts       Options
+		wantErr    error
+		wantStatus Status
+	}{
+		{"dry run", Options{FS: fsys, DryRun: true}, nil, StatusWouldChange},
+		{"validate", Options{FS: fsys, Validate: true}, ErrNeedsSorting, StatusWouldChange},
+		{"write", Options{FS: fsys}, vfs.ErrReadOnly, StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ProcessFile("main.tf", tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ProcessFile() error = %v, want %v", err, tt.wantErr)
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("ProcessFile() status = %s, want %s", result.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// TestSortFile_PreservesModeAndSymlink tests that sorting keeps the file mode
+// and writes through symbolic links.
+func TestSortFile_PreservesModeAndSymlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "shared.tf")
+	if err := os.WriteFile(target, []byte("variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(tmpDir, "main.tf")
+	if err := os.Symlink("shared.tf", link); err != nil {
+		t.Skip("Symlink not supported on this system")
+	}
+
+	if err := SortFile(link, Options{}); err != nil {
+		t.Fatalf("SortFile() error = %v", err)
+	}
+
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Error("Expected main.tf to remain a symbolic link")
+	}
+	info, err = os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 to be kept, got %v", info.Mode().Perm())
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	if got, _ := os.ReadFile(target); !strings.HasPrefix(string(got), "variable \"a\"") {
+		t.Errorf("Expected target to be sorted, got:\n%s", got)
+	}
+}
+
+// TestSortFile_ConcurrentModification tests that a file edited while it is
+// being sorted is left alone.
+func TestSortFile_ConcurrentModification(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+	//nolint:gosec // G306: Test files can use 0644 permissions
+	if err := os.WriteFile(testFile, []byte("output \"b\" {\n  value = \"${var.b}\"\n}\n\noutput \"a\" {\n  value = 1\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	edited := "output \"a\" {\n  value = 2\n}\n"
+	opts := Options{
+		Normalize: true,
+		// Rewrites are reported while sorting, after the file was read
+		OnRewrite: func(string, hcl.Rewrite) {
+			//nolint:gosec // G306: Test files can use 0644 permissions
+			if err := os.WriteFile(testFile, []byte(edited), 0644); err != nil {
+				t.Fatal(err)
+			}
+		},
+	}
+
+	result, err := ProcessFile(testFile, opts)
+	if !errors.Is(err, ErrConcurrentModification) {
+		t.Fatalf("Expected ErrConcurrentModification, got %v", err)
+	}
+	if result.Status != StatusFailed {
+		t.Errorf("Expected status %s, got %s", StatusFailed, result.Status)
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	if got, _ := os.ReadFile(testFile); string(got) != edited {
+		t.Errorf("Expected the edit to be kept, got:\n%s", got)
+	}
+
+	// Sorting again picks up the edit
+	if err := SortFile(testFile, Options{}); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("Expected ErrNoChanges on retry, got %v", err)
+	}
+}
+
+// TestFileVersion_Check tests detecting modified and removed files.
+func TestFileVersion_Check(t *testing.T) {
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	original := fstest.MapFS{"main.tf": &fstest.MapFile{Data: []byte("a = 1\n"), ModTime: modTime}}
+	_, version, err := readVersion(original, "main.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		wantErr error
+	}{
+		{"unchanged", original, nil},
+		{"touched", fstest.MapFS{"main.tf": &fstest.MapFile{Data: []byte("a = 1\n"), ModTime: modTime.Add(time.Second)}}, ErrConcurrentModification},
+		{"same time, new content", fstest.MapFS{"main.tf": &fstest.MapFile{Data: []byte("a = 2\n"), ModTime: modTime}}, ErrConcurrentModification},
+		{"removed", fstest.MapFS{}, ErrConcurrentModification},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := version.check(tt.fsys, "main.tf"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// failingWriteFS is a MemFS whose writes fail once fail says so.
+type failingWriteFS struct {
+	*vfs.MemFS
+
+	mu     sync.Mutex
+	writes int
+	fail   func(name string, writes int) bool
+}
+
+func (f *failingWriteFS) WriteFileIf(name string, data []byte, perm fs.FileMode, check func() error) error {
+	f.mu.Lock()
+	f.writes++
+	fail := f.fail(name, f.writes)
+	f.mu.Unlock()
+	if fail {
+		return errors.New("disk full")
+	}
+	return f.MemFS.WriteFileIf(name, data, perm, check)
+}
+
+// TestSortFiles_Atomic tests that an Atomic run writes all files or none.
+func TestSortFiles_Atomic(t *testing.T) {
+	unsorted := "variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n"
+	sorted := "variable \"a\" {\n  type = string\n}\n\nvariable \"b\" {\n  type = string\n}\n"
+
+	tests := []struct {
+		name        string
+		invalid     bool
+		fail        func(name string, writes int) bool
+		wantStatus  map[string]Status
+		wantErr     map[string]error
+		wantContent string
+	}{
+		{
+			name:        "all succeed",
+			fail:        func(string, int) bool { return false },
+			wantStatus:  map[string]Status{"a.tf": StatusChanged, "b.tf": StatusChanged, "c.tf": StatusChanged},
+			wantErr:     map[string]error{},
+			wantContent: sorted,
+		},
+		{
+			name:        "invalid file",
+			invalid:     true,
+			fail:        func(string, int) bool { return false },
+			wantStatus:  map[string]Status{"a.tf": StatusSkipped, "b.tf": StatusSkipped, "c.tf": StatusFailed},
+			wantErr:     map[string]error{"a.tf": ErrAtomicAborted, "b.tf": ErrAtomicAborted},
+			wantContent: unsorted,
+		},
+		{
+			name:        "write fails",
+			fail:        func(name string, _ int) bool { return name == "c.tf" },
+			wantStatus:  map[string]Status{"a.tf": StatusSkipped, "b.tf": StatusSkipped, "c.tf": StatusFailed},
+			wantErr:     map[string]error{"a.tf": ErrAtomicAborted, "b.tf": ErrAtomicAborted},
+			wantContent: unsorted,
+		},
+		{
+			name:        "rollback fails",
+			fail:        func(_ string, writes int) bool { return writes >= 3 },
+			wantStatus:  map[string]Status{"a.tf": StatusFailed, "b.tf": StatusFailed, "c.tf": StatusFailed},
+			wantErr:     map[string]error{},
+			wantContent: sorted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string][]byte{"a.tf": []byte(unsorted), "b.tf": []byte(unsorted), "c.tf": []byte(unsorted)}
+			if tt.invalid {
+				files["c.tf"] = []byte("variable {\n")
+			}
+			fsys := &failingWriteFS{MemFS: vfs.NewMemFS(files), fail: tt.fail}
+
+			var reported []string
+			opts := Options{
+				FS:       fsys,
+				Atomic:   true,
+				OnResult: func(result Result) { reported = append(reported, result.Path) },
+			}
+			results := SortFiles([]string{"c.tf", "a.tf", "b.tf"}, opts)
+
+			for _, result := range results {
+				if result.Status != tt.wantStatus[result.Path] {
+					t.Errorf("%s: status = %s, want %s (err %v)", result.Path, result.Status, tt.wantStatus[result.Path], result.Err)
+				}
+				if want := tt.wantErr[result.Path]; want != nil && !errors.Is(result.Err, want) {
+					t.Errorf("%s: err = %v, want %v", result.Path, result.Err, want)
+				}
+				if result.Path == "c.tf" {
+					continue
+				}
+				got, err := fsys.ReadFile(result.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != tt.wantContent {
+					t.Errorf("%s content:\n%s\nwant:\n%s", result.Path, got, tt.wantContent)
+				}
+			}
+
+			for _, path := range reported {
+				if tt.wantStatus[path] == StatusSkipped {
+					t.Errorf("OnResult called for skipped file %s", path)
+				}
+			}
+		})
+	}
+}
+
+// TestSortFiles_AtomicDryRun tests that Atomic does not change dry runs.
+func TestSortFiles_AtomicDryRun(t *testing.T) {
+	fsys := vfs.NewMemFS(map[string][]byte{
+		"a.tf": []byte("variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n"),
+		"b.tf": []byte("variable {\n"),
+	})
+
+	results := SortFiles([]string{"a.tf", "b.tf"}, Options{FS: fsys, Atomic: true, DryRun: true})
+	if results[0].Status != StatusWouldChange {
+		t.Errorf("a.tf: status = %s, want %s", results[0].Status, StatusWouldChange)
+	}
+	if results[1].Status != StatusFailed {
+		t.Errorf("b.tf: status = %s, want %s", results[1].Status, StatusFailed)
+	}
+}
+
+// TestOptions_SortOptions tests that the equivalence check only runs when
+// files are written
+func TestOptions_SortOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		wantSkip bool
+	}{
+		{"write", Options{}, false},
+		{"dry run", Options{DryRun: true}, true},
+		{"validate", Options{Validate: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.sortOptions().SkipEquivalenceCheck; got != tt.wantSkip {
+				t.Errorf("sortOptions().SkipEquivalenceCheck = %v, want %v", got, tt.wantSkip)
+			}
+		})
 	}
 }
@@ -0,0 +1,88 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/obergerkatz/sortTF/hcl"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// ErrFileExists indicates that a split would write blocks into a file that
+// already exists while merging was not requested.
+var ErrFileExists = errors.New("destination file already exists")
+
+// SplitOptions configures SplitFile.
+type SplitOptions struct {
+	// Options configures sorting of the resulting files and the run mode.
+	// With DryRun or Validate set, the plan is computed but nothing is written.
+	Options
+
+	// Rule chooses the file each top-level block is moved to.
+	// If nil, hcl.SplitByType() is used.
+	Rule hcl.SplitRule
+
+	// Merge allows blocks to be added to destination files that already exist.
+	// Without it, SplitFile fails with ErrFileExists before writing anything.
+	Merge bool
+}
+
+// SplitFile breaks a Terraform file into several files in the same directory,
+// grouping its top-level blocks with opts.Rule. Blocks the rule does not assign
+// stay in the original file, which is removed if it ends up empty. Every
+// resulting file is sorted and formatted.
+//
+// Returns the result together with:
+//   - nil: the split was applied (or would be, in DryRun mode)
+//   - ErrNoChanges: the rule keeps every block in the original file
+//   - ErrNeedsSorting: blocks need to move (only in Validate mode)
+//   - ErrFileExists: a destination file exists and Merge is not set
+//   - error: parsing, validation, or I/O error
+func SplitFile(path string, opts SplitOptions) (*LayoutResult, error) {
+	rule := opts.Rule
+	if rule == nil {
+		rule = hcl.SplitByType()
+	}
+
+	_, hclFile, err := readAndParse(path, opts.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	source := filepath.Base(path)
+	splitFiles := map[string]*hclwrite.File{source: hclFile}
+
+	// Find destinations first so existing files are detected before any write
+	moves, _ := hcl.PlanSplit(splitFiles, source, rule)
+	for _, move := range moves {
+		if _, seen := splitFiles[move.To]; seen {
+			continue
+		}
+
+		destPath := filepath.Join(dir, move.To)
+		if _, err := fs.Stat(opts.fsys(), destPath); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("check destination: %w", err)
+		}
+
+		if !opts.Merge {
+			return &LayoutResult{Dir: dir, Moves: moves}, fmt.Errorf("%w: %s", ErrFileExists, destPath)
+		}
+
+		_, destFile, err := readAndParse(destPath, opts.Options)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", destPath, err)
+		}
+		splitFiles[move.To] = destFile
+	}
+
+	moves, affected := hcl.PlanSplit(splitFiles, source, rule)
+	return applyPlan(dir, moves, affected, opts.Options)
+}
@@ -0,0 +1,160 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/obergerkatz/sortTF/hcl"
+)
+
+const splitSource = `resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+
+resource "aws_iam_role" "app" {
+  name = "app"
+}
+
+locals {
+  name = "app"
+}
+
+resource "aws_iam_policy" "app" {
+  name = "app"
+}
+`
+
+func TestSplitFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeModule(t, tmpDir, map[string]string{"main.tf": splitSource})
+
+	result, err := SplitFile(filepath.Join(tmpDir, "main.tf"), SplitOptions{Rule: hcl.SplitByResourcePrefix()})
+	if err != nil {
+		t.Fatalf("SplitFile failed: %v", err)
+	}
+	if len(result.Moves) != 3 {
+		t.Errorf("Expected 3 moves, got %d: %v", len(result.Moves), result.Moves)
+	}
+
+	//nolint:gosec // G304: Test file path is controlled
+	iam, err := os.ReadFile(filepath.Join(tmpDir, "iam.tf"))
+	if err != nil {
+		t.Fatalf("Expected iam.tf to be created: %v", err)
+	}
+	// Resulting files are sorted by block type and label
+	expectedIAM := `resource "aws_iam_policy" "app" {
+  name = "app"
+}
+
+resource "aws_iam_role" "app" {
+  name = "app"
+}
+`
+	if string(iam) != expectedIAM {
+		t.Errorf("Unexpected iam.tf:\ngot:\n%s\nwant:\n%s", iam, expectedIAM)
+	}
+
+	//nolint:gosec // G304: Test file path is controlled
+	main, err := os.ReadFile(filepath.Join(tmpDir, "main.tf"))
+	if err != nil || strings.Contains(string(main), "resource") || !strings.Contains(string(main), "locals") {
+		t.Errorf("Expected only locals to remain in main.tf, got: %s (err %v)", main, err)
+	}
+
+	// A second run finds nothing to move
+	if _, err := SplitFile(filepath.Join(tmpDir, "main.tf"), SplitOptions{Rule: hcl.SplitByResourcePrefix()}); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("Expected ErrNoChanges on second run, got: %v", err)
+	}
+}
+
+func TestSplitFile_DefaultRule(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeModule(t, tmpDir, map[string]string{"main.tf": splitSource})
+
+	result, err := SplitFile(filepath.Join(tmpDir, "main.tf"), SplitOptions{})
+	if err != nil {
+		t.Fatalf("SplitFile failed: %v", err)
+	}
+
+	// Everything leaves main.tf, which is removed
+	if len(result.Removed) != 1 || result.Removed[0] != filepath.Join(tmpDir, "main.tf") {
+		t.Errorf("Expected main.tf to be removed, got %v", result.Removed)
+	}
+	for _, name := range []string{"resources.tf", "locals.tf"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, name)); err != nil {
+			t.Errorf("Expected %s to be created: %v", name, err)
+		}
+	}
+}
+
+func TestSplitFile_ExistingDestination(t *testing.T) {
+	tmpDir := t.TempDir()
+	existing := `resource "aws_iam_user" "ci" {
+  name = "ci"
+}
+`
+	writeModule(t, tmpDir, map[string]string{"main.tf": splitSource, "iam.tf": existing})
+	path := filepath.Join(tmpDir, "main.tf")
+	rule := hcl.SplitByLabelPattern(regexp.MustCompile(`^aws_(\w+?)_`))
+
+	// Without Merge nothing is written
+	if _, err := SplitFile(path, SplitOptions{Rule: rule}); !errors.Is(err, ErrFileExists) {
+		t.Fatalf("Expected ErrFileExists, got: %v", err)
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	if got, _ := os.ReadFile(path); string(got) != splitSource {
+		t.Error("main.tf was modified despite ErrFileExists")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "s3.tf")); !os.IsNotExist(err) {
+		t.Error("s3.tf was created despite ErrFileExists")
+	}
+
+	// With Merge the existing blocks are kept
+	if _, err := SplitFile(path, SplitOptions{Rule: rule, Merge: true}); err != nil {
+		t.Fatalf("SplitFile with Merge failed: %v", err)
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	iam, err := os.ReadFile(filepath.Join(tmpDir, "iam.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"aws_iam_user" "ci"`, `"aws_iam_role" "app"`, `"aws_iam_policy" "app"`} {
+		if !strings.Contains(string(iam), want) {
+			t.Errorf("Expected %s in merged iam.tf, got:\n%s", want, iam)
+		}
+	}
+}
+
+func TestSplitFile_DryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeModule(t, tmpDir, map[string]string{"main.tf": splitSource})
+	path := filepath.Join(tmpDir, "main.tf")
+
+	result, err := SplitFile(path, SplitOptions{Options: Options{DryRun: true}, Rule: hcl.SplitByResourcePrefix()})
+	if err != nil {
+		t.Fatalf("SplitFile dry run failed: %v", err)
+	}
+	if len(result.Moves) != 3 || len(result.Written) != 3 {
+		t.Errorf("Unexpected plan: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "iam.tf")); !os.IsNotExist(err) {
+		t.Error("iam.tf was created in dry-run mode")
+	}
+
+	if _, err := SplitFile(path, SplitOptions{Options: Options{Validate: true}, Rule: hcl.SplitByResourcePrefix()}); !errors.Is(err, ErrNeedsSorting) {
+		t.Errorf("Expected ErrNeedsSorting in validate mode, got: %v", err)
+	}
+}
+
+func TestSplitFile_InvalidFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeModule(t, tmpDir, map[string]string{"main.tf": "resource \"broken\" {\n"})
+
+	if _, err := SplitFile(filepath.Join(tmpDir, "main.tf"), SplitOptions{}); err == nil {
+		t.Fatal("Expected error for invalid file")
+	}
+}
@@ -0,0 +1,71 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/obergerkatz/sortTF/hcl"
+)
+
+// FindUnused returns the variables and local values declared in the .tf files
+// directly inside dir that no expression in those files references. Files are
+// discovered in and read from opts.FS; the other fields of opts are ignored.
+// Files are not modified.
+//
+// Each result carries the source range of the declaration; its Diagnostic
+// method describes it as a warning. Returns an error if a file could not be
+// read or parsed.
+func FindUnused(dir string, opts Options) ([]hcl.UnusedDeclaration, error) {
+	parsed, err := parseModule(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return hcl.FindUnused(parsed), nil
+}
+
+// RemoveUnused deletes the given declarations, as returned by FindUnused, from
+// the files that declare them. Each affected file is sorted and formatted with
+// opts before it is written. In DryRun mode nothing is written.
+//
+// Returns the paths of the files that were (or, in DryRun mode, would be)
+// rewritten, in path order.
+func RemoveUnused(unused []hcl.UnusedDeclaration, opts Options) ([]string, error) {
+	byFile := make(map[string][]string)
+	for _, decl := range unused {
+		byFile[decl.Range.Filename] = append(byFile[decl.Range.Filename], decl.Address)
+	}
+
+	paths := make([]string, 0, len(byFile))
+	for path := range byFile {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var written []string
+	for _, path := range paths {
+		_, hclFile, err := readAndParse(path, opts)
+		if err != nil {
+			return written, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if hcl.RemoveDeclarations(hclFile, byFile[path]) == 0 {
+			continue
+		}
+
+		formatted, _, err := hcl.SortAndFormatHCLFileWithOptions(hclFile, opts.sortOptions())
+		if err != nil {
+			return written, fmt.Errorf("%s: sort/format: %w", path, err)
+		}
+
+		if !opts.DryRun {
+			if err := writeFile(opts.fsys(), path, []byte(formatted)); err != nil {
+				return written, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		written = append(written, path)
+	}
+
+	return written, nil
+}
@@ -0,0 +1,104 @@
+//nolint:revive // var-naming: api is an appropriate package name for an API layer
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/obergerkatz/sortTF/vfs"
+)
+
+func TestFindUnused(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeModule(t, tmpDir, map[string]string{
+		"main.tf":      "locals {\n  name = var.name\n  tmp  = 1\n}\n\nresource \"aws_s3_bucket\" \"logs\" {\n  bucket = local.name\n}\n",
+		"variables.tf": "variable \"name\" {}\n\nvariable \"region\" {}\n",
+	})
+
+	unused, err := FindUnused(tmpDir, Options{})
+	if err != nil {
+		t.Fatalf("FindUnused() error = %v", err)
+	}
+
+	want := []string{"local.tmp", "var.region"}
+	if len(unused) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, unused)
+	}
+	for i, address := range want {
+		if unused[i].Address != address {
+			t.Errorf("unused[%d] = %s, want %s", i, unused[i].Address, address)
+		}
+	}
+	if unused[1].Range.Filename != filepath.Join(tmpDir, "variables.tf") {
+		t.Errorf("Expected var.region in variables.tf, got %s", unused[1].Range.Filename)
+	}
+}
+
+// TestFindUnused_FS tests that the module is read from Options.FS
+func TestFindUnused_FS(t *testing.T) {
+	fsys := vfs.NewMemFS(map[string][]byte{
+		"modules/vpc/main.tf":      []byte("output \"name\" {\n  value = var.name\n}\n"),
+		"modules/vpc/variables.tf": []byte("variable \"name\" {}\n\nvariable \"region\" {}\n"),
+	})
+
+	unused, err := FindUnused("modules/vpc", Options{FS: fsys})
+	if err != nil {
+		t.Fatalf("FindUnused() error = %v", err)
+	}
+	if len(unused) != 1 || unused[0].Address != "var.region" {
+		t.Fatalf("Expected var.region, got %v", unused)
+	}
+	if unused[0].Range.Filename != "modules/vpc/variables.tf" {
+		t.Errorf("Expected var.region in modules/vpc/variables.tf, got %s", unused[0].Range.Filename)
+	}
+}
+
+func TestRemoveUnused(t *testing.T) {
+	tests := []struct {
+		name     string
+		dryRun   bool
+		expected string
+	}{
+		{
+			name:     "removes declarations",
+			expected: "variable \"name\" {\n  type = string\n}\n",
+		},
+		{
+			name:     "dry run keeps file",
+			dryRun:   true,
+			expected: "variable \"region\" {}\n\nvariable \"name\" {\n  type = string\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writeModule(t, tmpDir, map[string]string{
+				"main.tf":      "output \"name\" {\n  value = var.name\n}\n",
+				"variables.tf": "variable \"region\" {}\n\nvariable \"name\" {\n  type = string\n}\n",
+			})
+
+			unused, err := FindUnused(tmpDir, Options{})
+			if err != nil {
+				t.Fatalf("FindUnused() error = %v", err)
+			}
+
+			written, err := RemoveUnused(unused, Options{DryRun: tt.dryRun})
+			if err != nil {
+				t.Fatalf("RemoveUnused() error = %v", err)
+			}
+			if len(written) != 1 || written[0] != filepath.Join(tmpDir, "variables.tf") {
+				t.Errorf("Expected variables.tf rewritten, got %v", written)
+			}
+
+			content, err := os.ReadFile(filepath.Join(tmpDir, "variables.tf")) //nolint:gosec // G304: Test file path from t.TempDir()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.expected {
+				t.Errorf("Unexpected content:\n%s\nwant:\n%s", content, tt.expected)
+			}
+		})
+	}
+}
@@ -3,9 +3,9 @@
 // This package implements the main CLI execution logic including:
 //   - Command-line argument parsing via config package
 //   - File discovery and validation
-//   - Concurrent file processing with worker pools
+//   - Concurrent, interruptible file processing via the api package
 //   - Colorized output and error reporting
-//   - Unified diff generation for dry-run mode
+//   - Unified, side-by-side and word diffs for dry-run mode
 //
 // The main entry point is RunCLI which handles all execution modes:
 // normal, dry-run, validate, and verbose.
@@ -13,17 +13,20 @@ package cli
 
 import (
 	"bytes"
+	"context"
 	stderrors "errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"runtime"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/obergerkatz/sortTF/api"
 	"github.com/obergerkatz/sortTF/config"
+	"github.com/obergerkatz/sortTF/diff"
+	"github.com/obergerkatz/sortTF/hcl"
 	"github.com/obergerkatz/sortTF/internal/errors"
 	"github.com/obergerkatz/sortTF/internal/files"
 
@@ -37,6 +40,9 @@ var (
 	successColor = color.New(color.FgGreen, color.Bold)
 	infoColor    = color.New(color.FgBlue, color.Bold)
 	fileColor    = color.New(color.FgCyan)
+
+	deletedColor  = color.New(color.FgRed)
+	insertedColor = color.New(color.FgGreen)
 )
 
 // RunCLI is the main entry point for CLI execution.
@@ -55,6 +61,13 @@ func RunCLI(args []string) int {
 // This is primarily used for testing to capture and verify output.
 // The behavior is otherwise identical to RunCLI.
 func RunCLIWithWriters(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "split" {
+		return runSplit(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "restore" {
+		return runRestore(args[1:], stdout, stderr)
+	}
+
 	config, err := config.ParseFlags(args, stderr)
 	if err != nil {
 		if err.Error() == "help" {
@@ -123,6 +136,11 @@ func runMainLogic(config *config.Config, stdout, stderr io.Writer) int {
 			return 1
 		}
 	} else {
+		if config.Layout {
+			_, _ = errorColor.Fprintf(stderr, "❌ --layout requires a directory, got file '%s'\n", fileColor.Sprint(config.Root))
+			return 1
+		}
+
 		// It's a file - check if it's a supported file type
 		if !isSupportedFile(config.Root) {
 			_, _ = errorColor.Fprintf(stderr, "❌ File '%s' is not a supported file type (.tf or .hcl)\n", fileColor.Sprint(config.Root))
@@ -136,15 +154,26 @@ func runMainLogic(config *config.Config, stdout, stderr io.Writer) int {
 		return 0
 	}
 
-	if config.Verbose {
-		_, _ = infoColor.Fprintf(stdout, "📁 Found %d files:\n", len(filePaths))
-		for _, f := range filePaths {
-			_, _ = fmt.Fprintf(stdout, "   %s\n", fileColor.Sprint(f))
+	if config.Layout {
+		if config.Verbose {
+			printFound(stdout, filePaths)
 		}
+		return runLayout(filePaths, config, stdout, stderr)
+	}
+
+	// Process files concurrently; an interrupt stops scheduling new files
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	processedCount, errorCount := processFiles(ctx, filePaths, config, stdout, stderr)
+
+	// Validate mode also checks each module's files together
+	if config.Validate && fileInfo.IsDir() {
+		errorCount += checkModules(filePaths, config, stderr)
 	}
 
-	// Process files (concurrently for better performance)
-	processedCount, errorCount := processFilesConcurrent(filePaths, config, stdout, stderr)
+	if config.Unused {
+		errorCount += checkUnused(filePaths, config, stdout, stderr)
+	}
 
 	// Print summary
 	if config.DryRun {
@@ -165,6 +194,108 @@ func runMainLogic(config *config.Config, stdout, stderr io.Writer) int {
 	return 0
 }
 
+// runLayout moves blocks to their canonical files in every module directory
+// containing discovered files. Each directory is treated as one module.
+// In dry-run and validate modes the planned moves are printed but not applied.
+// Returns an exit code suitable for os.Exit.
+func runLayout(filePaths []string, config *config.Config, stdout, stderr io.Writer) int {
+	mapping := hcl.DefaultLayout()
+	for blockType, file := range config.LayoutMapping {
+		mapping[blockType] = file
+	}
+
+	dirs := moduleDirs(filePaths)
+	opts := api.LayoutOptions{Options: apiOptions(config, stdout), Mapping: mapping}
+	moveCount := 0
+	errorCount := 0
+
+	for _, dir := range dirs {
+		result, err := api.LayoutDirectory(dir, opts)
+		if err != nil && !stderrors.Is(err, api.ErrNoChanges) && !stderrors.Is(err, api.ErrNeedsSorting) {
+			errorCount++
+			if diagErr := diagnosticError(dir, err, config.DiagnosticWidth); diagErr != nil {
+				errors.PrintError(diagErr, stderr)
+			} else {
+				errors.PrintError(errors.New("layout", fmt.Errorf("failed to lay out %s: %w", dir, err)), stderr)
+			}
+			continue
+		}
+
+		moveCount += len(result.Moves)
+		for _, move := range result.Moves {
+			_, _ = fmt.Fprintf(stdout, "📦 %s: %s → %s\n", move.Address,
+				fileColor.Sprint(filepath.Join(dir, move.From)), fileColor.Sprint(filepath.Join(dir, move.To)))
+		}
+
+		if config.DryRun || config.Validate {
+			continue
+		}
+		for _, path := range result.Written {
+			_, _ = successColor.Fprintf(stdout, "✅ Updated: %s\n", fileColor.Sprint(path))
+		}
+		for _, path := range result.Removed {
+			_, _ = successColor.Fprintf(stdout, "🗑️  Removed empty file: %s\n", fileColor.Sprint(path))
+		}
+	}
+
+	switch {
+	case moveCount == 0:
+		_, _ = successColor.Fprintf(stdout, "✅ Checked %d modules, all blocks are in their canonical files\n", len(dirs))
+	case config.DryRun || config.Validate:
+		_, _ = infoColor.Fprintf(stdout, "📊 Checked %d modules, %d blocks would be moved\n", len(dirs), moveCount)
+	default:
+		_, _ = successColor.Fprintf(stdout, "✅ Checked %d modules, moved %d blocks\n", len(dirs), moveCount)
+	}
+
+	if errorCount > 0 {
+		_, _ = errorColor.Fprintf(stderr, "❌ Encountered %d errors\n", errorCount)
+		return 1
+	}
+	if config.Validate && !config.DryRun && moveCount > 0 {
+		return 1
+	}
+	return 0
+}
+
+// moduleDirs returns the directories containing the given files in discovery
+// order. Each directory is treated as one Terraform module.
+func moduleDirs(filePaths []string) []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, path := range filePaths {
+		dir := filepath.Dir(path)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// checkModules runs the module-level validation pass over every module
+// directory containing discovered files, printing each duplicate declaration.
+// Files that fail to parse are skipped here since processing reports them.
+// Returns the number of modules with problems.
+func checkModules(filePaths []string, config *config.Config, stderr io.Writer) int {
+	errorCount := 0
+	for _, dir := range moduleDirs(filePaths) {
+		err := api.ValidateModule(dir, api.Options{FS: config.FS})
+		if err == nil || hcl.IsHCLParseError(err) {
+			continue
+		}
+
+		errorCount++
+		if len(hcl.ValidationDiagnostics(err)) == 0 {
+			errors.PrintError(errors.New("validate", fmt.Errorf("failed to check module %s: %w", dir, err)), stderr)
+			continue
+		}
+		moduleErr := errors.NewWithKind("validate", errors.KindValidation, fmt.Errorf("duplicate declarations in module %s", dir))
+		moduleErr.Details = renderDiagnostics(err, config.DiagnosticWidth)
+		errors.PrintError(moduleErr, stderr)
+	}
+	return errorCount
+}
+
 // isSupportedFile checks if the file has a supported extension (.tf or .hcl).
 // Returns true for Terraform and Terragrunt files, false otherwise.
 func isSupportedFile(filePath string) bool {
@@ -172,264 +303,347 @@ func isSupportedFile(filePath string) bool {
 	return ext == ".tf" || ext == ".hcl"
 }
 
-// processFile handles sorting and formatting of a single file.
-// It uses the api.SortFile API and handles different modes (normal, dry-run, validate).
-// Returns nil on success, errors.ErrNoChanges if file is already sorted,
-// or an error if processing fails.
-func processFile(filePath string, config *config.Config, stdout, _ io.Writer) error {
-	if config.Verbose {
-		_, _ = infoColor.Fprintf(stdout, "🔄 Processing: %s\n", fileColor.Sprint(filePath))
+// apiOptions builds the library options for a run from the CLI configuration.
+// Normalization rewrites are reported to stdout as they are applied.
+func apiOptions(config *config.Config, stdout io.Writer) api.Options {
+	return api.Options{
+		DryRun:               config.DryRun,
+		Validate:             config.Validate,
+		CheckIdempotence:     config.CheckIdempotence,
+		Normalize:            config.Normalize,
+		SortListAttributes:   config.SortLists,
+		MaxLineWidth:         config.MaxLineWidth,
+		CollapseCollections:  config.Collapse,
+		SkipSchemaValidation: config.NoSchema,
+		Workers:              config.Workers,
+		FileTimeout:          config.FileTimeout,
+		Atomic:               config.Atomic,
+		FS:                   config.FS,
+		OnRewrite: func(path string, rewrite hcl.Rewrite) {
+			printRewrite(stdout, config, path, rewrite)
+		},
+	}
+}
+
+// printFound lists the files found for processing, for verbose output.
+func printFound(stdout io.Writer, filePaths []string) {
+	_, _ = infoColor.Fprintf(stdout, "📁 Found %d files:\n", len(filePaths))
+	for _, f := range filePaths {
+		_, _ = fmt.Fprintf(stdout, "   %s\n", fileColor.Sprint(f))
 	}
+}
 
-	// Use the library API to sort the file
-	opts := api.Options{
-		DryRun:   config.DryRun,
-		Validate: config.Validate,
+// printRewrite reports a normalization rewrite applied to a file, as one
+// that would be applied in dry-run and validate mode, where nothing is written.
+func printRewrite(stdout io.Writer, config *config.Config, path string, rewrite hcl.Rewrite) {
+	verb := "Normalized"
+	if config.DryRun || config.Validate {
+		verb = "Would normalize"
 	}
+	_, _ = infoColor.Fprintf(stdout, "🔧 %s %s: %s\n", verb, fileColor.Sprint(path), rewrite)
+}
 
-	err := api.SortFile(filePath, opts)
+// reportResult prints the outcome of processing one file according to the
+// mode (normal, dry-run, validate), with a diff for files that would change.
+// Returns nil on success, errors.ErrNoChanges if the file is already sorted,
+// or an error if the file needs sorting in validate mode or failed.
+func reportResult(result api.Result, config *config.Config, stdout io.Writer) error {
+	filePath := result.Path
+	if config.Verbose && result.Changed {
+		_, _ = fmt.Fprintf(stdout, "   %d → %d bytes, %d lines differ, %s\n",
+			result.OriginalSize, result.SortedSize, changedLines(result.Diff()), result.Duration.Round(time.Microsecond))
+	}
 
-	// Handle results based on error type
-	if stderrors.Is(err, api.ErrNoChanges) {
+	switch result.Status {
+	case api.StatusUnchanged:
 		// File is already sorted - not an error
 		if config.Verbose {
 			_, _ = successColor.Fprintf(stdout, "✅ No changes needed: %s\n", fileColor.Sprint(filePath))
 		}
 		return fmt.Errorf("%w: %s", errors.ErrNoChanges, filePath)
-	}
 
-	if stderrors.Is(err, api.ErrNeedsSorting) {
+	case api.StatusWouldChange:
+		// Dry-run takes precedence over validate
+		if config.DryRun {
+			_, _ = warningColor.Fprintf(stdout, "📝 Would update: %s\n", fileColor.Sprint(filePath))
+			printDiff(result, config, stdout)
+			return nil
+		}
+
 		// Validate mode: file needs sorting, show diff
 		_, _ = warningColor.Fprintf(stdout, "⚠️  Needs update: %s\n", fileColor.Sprint(filePath))
+		printDiff(result, config, stdout)
+		return errors.New("validate", fmt.Errorf("file needs update: %s", filePath))
 
-		// Get original and sorted content for diff
-		origContent, _ := os.ReadFile(filePath) // #nosec G304 -- User input expected for file tool
-		sortedContent, _, _ := api.GetSortedContent(filePath)
+	case api.StatusChanged:
+		// Normal mode: file was actually written
+		_, _ = successColor.Fprintf(stdout, "✅ Updated: %s\n", fileColor.Sprint(filePath))
+		return nil
+	}
 
-		if origContent != nil && sortedContent != "" {
-			printUnifiedDiff(string(origContent), sortedContent, filePath, stdout)
-		}
+	if diagErr := diagnosticError(filePath, result.Err, config.DiagnosticWidth); diagErr != nil {
+		return diagErr
+	}
 
-		return errors.New("validate", fmt.Errorf("file needs update: %s", filePath))
+	var eqErr *hcl.EquivalenceError
+	if stderrors.As(result.Err, &eqErr) {
+		sortErr := errors.NewWithKind("sort", errors.KindSorting, fmt.Errorf("not writing %s, sorting would change its meaning", filePath))
+		for _, difference := range eqErr.Differences {
+			sortErr.Details += "   - " + difference + "\n"
+		}
+		return sortErr
 	}
 
-	if err == nil {
-		// Success - check which mode
-		if config.DryRun {
-			// Dry-run mode: show what would change
-			_, _ = warningColor.Fprintf(stdout, "📝 Would update: %s\n", fileColor.Sprint(filePath))
+	var idemErr *api.IdempotenceError
+	if stderrors.As(result.Err, &idemErr) {
+		sortErr := errors.NewWithKind("sort", errors.KindSorting, fmt.Errorf("sorting %s again changes it; the first pass is not stable", filePath))
+		sortErr.Details = passDiff(idemErr.Diff)
+		return sortErr
+	}
 
-			// Get original and sorted content for diff
-			origContent, _ := os.ReadFile(filePath) // #nosec G304 -- File path comes from user input, which is expected for a file processing tool
-			sortedContent, _, _ := api.GetSortedContent(filePath)
+	// Some other error occurred
+	return errors.New("processFile", fmt.Errorf("failed to process %s: %w", filePath, result.Err))
+}
 
-			if origContent != nil && sortedContent != "" {
-				printUnifiedDiff(string(origContent), sortedContent, filePath, stdout)
-			}
+// passContext is the number of unchanged lines shown around each change in
+// the diff between two sorting passes.
+const passContext = 2
+
+// passDiff renders the differences between the first and second sorting
+// pass as hunks, showing each change with a few unchanged lines around it.
+func passDiff(lines []diff.Line) string {
+	var out strings.Builder
+	out.WriteString("   --- first pass\n   +++ second pass\n")
+	for _, hunk := range diff.Hunks(lines, passContext) {
+		out.WriteString("   " + hunk.Header() + "\n")
+		for _, line := range hunk.Lines {
+			out.WriteString("   " + line.Op.String() + line.Text + "\n")
+		}
+	}
+	return out.String()
+}
 
-			return nil
+// changedLines counts the inserted and deleted lines of a diff.
+func changedLines(lines []diff.Line) int {
+	count := 0
+	for _, line := range lines {
+		if line.Op != diff.Equal {
+			count++
 		}
+	}
+	return count
+}
 
-		// Normal mode: file was actually written
-		_, _ = successColor.Fprintf(stdout, "✅ Updated: %s\n", fileColor.Sprint(filePath))
+// diagnosticError turns a parse or validation error into an error that
+// renders every diagnostic with its position and source snippet when printed.
+// Color follows the CLI's color mode. Returns nil if err carries no diagnostics.
+func diagnosticError(path string, err error, width int) error {
+	details := renderDiagnostics(err, width)
+	if details == "" {
 		return nil
 	}
 
-	// Some other error occurred
-	return errors.New("processFile", fmt.Errorf("failed to process %s: %w", filePath, err))
+	var diagErr *errors.Error
+	switch {
+	case hcl.IsHCLParseError(err):
+		diagErr = errors.NewWithKind("parse", errors.KindParsing, fmt.Errorf("syntax errors in %s", path))
+	case hcl.IsSchemaError(err):
+		diagErr = errors.NewWithKind("validate", errors.KindValidation, fmt.Errorf("invalid block bodies in %s", path))
+	default:
+		diagErr = errors.NewWithKind("validate", errors.KindValidation, fmt.Errorf("invalid blocks in %s", path))
+	}
+	diagErr.Details = details
+	return diagErr
 }
 
-// fileResult holds the result of processing a single file in concurrent mode.
-// It contains the file path, any error that occurred, buffered output,
-// and flags indicating the result type (no changes needed, successfully processed, etc.).
-type fileResult struct {
-	path          string // Path of the processed file
-	err           error  // Error that occurred, or nil
-	stdout        string // Buffered standard output
-	stderr        string // Buffered standard error
-	noChanges     bool   // True if file was already sorted
-	processedFile bool   // True if file was successfully processed/modified
+// renderDiagnostics renders the diagnostics carried by err with their source
+// snippets, in color if the CLI's color mode allows it. Returns "" if err
+// carries no diagnostics.
+func renderDiagnostics(err error, width int) string {
+	diags, sources := hcl.ErrorDiagnostics(err)
+	if len(diags) == 0 {
+		return ""
+	}
+
+	var details bytes.Buffer
+	_ = hcl.WriteDiagnostics(&details, diags, sources, hcl.DiagnosticOptions{Width: width, Color: !color.NoColor})
+	return details.String()
 }
 
-// processFilesConcurrent processes multiple files concurrently using a worker pool.
-// It automatically determines worker count based on CPU cores (2x for I/O overlap).
-// Falls back to serial processing for < 4 files or when verbose mode is enabled.
+// maxRetries bounds how often files that changed while being sorted are
+// sorted again without asking, when --yes answers the prompt.
+const maxRetries = 3
+
+// processFiles sorts the files with sortFiles. Files that someone else
+// modified while they were being sorted are left alone; processFiles then
+// offers to sort them again, as often as they keep changing. With --atomic,
+// all files are sorted again, since none was written. With --backup-dir,
+// the backup's manifest is written once all files are done.
 //
 // Returns (processedCount, errorCount) where:
 //   - processedCount: files that were successfully sorted/modified
-//   - errorCount: files that encountered errors
-func processFilesConcurrent(filePaths []string, config *config.Config, stdout, stderr io.Writer) (int, int) {
-	if len(filePaths) == 0 {
-		return 0, 0
-	}
-
-	// Determine worker count: min(numFiles, numCPU * 2)
-	// Use 2x CPU count to keep CPUs busy during I/O
-	numWorkers := runtime.NumCPU() * 2
-	if numWorkers > len(filePaths) {
-		numWorkers = len(filePaths)
-	}
-	if numWorkers < 1 {
-		numWorkers = 1
-	}
-
-	// For small numbers of files or verbose mode, process serially to maintain order
-	if len(filePaths) < 4 || config.Verbose {
-		return processFilesSerial(filePaths, config, stdout, stderr)
-	}
-
-	// Create channels
-	jobs := make(chan string, len(filePaths))
-	results := make(chan fileResult, len(filePaths))
-
-	// Start workers
-	var wg sync.WaitGroup
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for path := range jobs {
-				// Process file with buffered output
-				var outBuf, errBuf bytes.Buffer
-				err := processFile(path, config, &outBuf, &errBuf)
-
-				// Send result
-				result := fileResult{
-					path:   path,
-					err:    err,
-					stdout: outBuf.String(),
-					stderr: errBuf.String(),
-				}
-
-				if err == nil {
-					result.processedFile = true
-				} else if stderrors.Is(err, errors.ErrNoChanges) {
-					result.noChanges = true
-				}
-
-				results <- result
-			}
-		}()
+//   - errorCount: files that encountered errors, plus one if the run was interrupted
+func processFiles(ctx context.Context, filePaths []string, config *config.Config, stdout, stderr io.Writer) (int, int) {
+	backup := backupFor(config)
+	var patch *runPatch
+	if config.Patch != "" {
+		patch = &runPatch{path: config.Patch, context: config.DiffContext}
 	}
+	processedCount, errorCount, modified := sortFiles(ctx, filePaths, config, backup, patch, stdout, stderr)
 
-	// Send jobs
-	for _, path := range filePaths {
-		jobs <- path
-	}
-	close(jobs)
-
-	// Wait for workers in background
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	// Collect results and write output in order received
-	// (order doesn't matter for performance, but makes output readable)
-	processedCount := 0
-	errorCount := 0
-
-	for result := range results {
-		// Write output atomically
-		if result.stdout != "" {
-			_, _ = io.WriteString(stdout, result.stdout) // Ignore write errors to stdout
+	for attempt := 1; len(modified) > 0 && ctx.Err() == nil; attempt++ {
+		question := fmt.Sprintf("%d files changed while being sorted. Sort them again?", len(modified))
+		if config.Yes {
+			if attempt > maxRetries {
+				break
+			}
+		} else if !confirm(question, stdout) {
+			break
 		}
-		if result.stderr != "" {
-			_, _ = io.WriteString(stderr, result.stderr) // Ignore write errors to stderr
+
+		// The retried files were counted as errors; they are counted again below
+		errorCount -= len(modified)
+		retry := modified
+		if config.Atomic {
+			retry = filePaths
 		}
+		var processed, errs int
+		processed, errs, modified = sortFiles(ctx, retry, config, backup, patch, stdout, stderr)
+		processedCount += processed
+		errorCount += errs
+	}
 
-		// Count results
-		//nolint:revive,gocritic // empty-block and ifElseChain: This structure is clearer than alternatives
-		if result.noChanges {
-			// Already sorted, don't count as error or processed
-		} else if result.err != nil {
+	if dir, ok := backup.(*api.BackupDir); ok {
+		if err := dir.Close(); err != nil {
 			errorCount++
-			errors.PrintError(result.err, stderr)
-		} else if result.processedFile {
-			processedCount++
+			errors.PrintError(errors.New("backup", err), stderr)
+		} else if dir.Len() > 0 {
+			_, _ = infoColor.Fprintf(stdout, "💾 Saved %d original files in %s (undo with: sorttf restore %s)\n", dir.Len(), dir.Dir(), dir.Dir())
 		}
 	}
 
-	return processedCount, errorCount
-}
-
-// processFilesSerial processes files sequentially one at a time.
-// This is used for small batches (< 4 files) or when verbose mode is enabled
-// to maintain readable output order. Returns the same counts as processFilesConcurrent.
-func processFilesSerial(filePaths []string, config *config.Config, stdout, stderr io.Writer) (int, int) {
-	processedCount := 0
-	errorCount := 0
-
-	for _, filePath := range filePaths {
-		if err := processFile(filePath, config, stdout, stderr); err != nil {
-			//nolint:revive // empty-block: Explicit no-op for clarity
-			if stderrors.Is(err, errors.ErrNoChanges) {
-				// Already sorted, don't count as error or processed
-			} else {
-				errorCount++
-				errors.PrintError(err, stderr)
-				if config.Validate {
-					// In validate mode, continue processing but will exit with error
-					continue
-				}
-			}
+	if patch != nil {
+		if count, err := patch.write(); err != nil {
+			errorCount++
+			errors.PrintError(errors.NewWithPath("patch", patch.path, err), stderr)
 		} else {
-			processedCount++
+			_, _ = infoColor.Fprintf(stdout, "🩹 Wrote the changes of %d files to %s (apply with: git apply %s)\n", count, patch.path, patch.path)
 		}
 	}
 
 	return processedCount, errorCount
 }
 
-// printUnifiedDiff prints a unified diff between original and formatted content.
-// It displays the changes in a readable format with +/- prefixes for added/removed lines.
-// Shows context lines (first and last 3 lines) to help locate changes.
-// Used in dry-run and validate modes to show what would change.
-func printUnifiedDiff(a, b, filePath string, out io.Writer) {
-	if a == b {
-		_, _ = fmt.Fprintf(out, "(No changes)\n")
-		return
+// backupFor returns the backup selected by --backup or --backup-dir, or nil.
+func backupFor(config *config.Config) api.Backup {
+	switch {
+	case config.BackupDir != "":
+		return api.NewBackupDir(config.BackupDir)
+	case config.Backup:
+		return api.SiblingBackup{}
+	default:
+		return nil
 	}
+}
 
-	// Split into lines for easier comparison
-	linesA := strings.Split(a, "\n")
-	linesB := strings.Split(b, "\n")
-
-	_, _ = fmt.Fprintf(out, "--- %s (original)\n", filePath)
-	_, _ = fmt.Fprintf(out, "+++ %s (formatted)\n", filePath)
-	_, _ = fmt.Fprintf(out, "@@ Changes @@\n")
-
-	// Simple line-by-line diff
-	maxLines := len(linesA)
-	if len(linesB) > maxLines {
-		maxLines = len(linesB)
+// sortFiles sorts the files with api.SortFilesContext, which bounds
+// concurrency and stops scheduling files once ctx is done. Output is driven
+// by the run's events: each file is reported as soon as it is done. Verbose
+// mode processes one file at a time, unless --workers says otherwise, so its
+// output stays in order.
+//
+// Each file is saved to backup, if not nil, before it is overwritten. Files
+// that would change are added to patch, if not nil.
+// Returns the counts of processFiles and the files that were not written
+// because they changed while being sorted, which are counted as errors.
+func sortFiles(ctx context.Context, filePaths []string, config *config.Config, backup api.Backup, patch *runPatch, stdout, stderr io.Writer) (int, int, []string) {
+	if len(filePaths) == 0 {
+		return 0, 0, nil
 	}
 
-	for i := 0; i < maxLines; i++ {
-		lineA := ""
-		lineB := ""
+	processedCount := 0
+	errorCount := 0
+	skippedCount := 0
+	abortedCount := 0
+	var modified []string
+
+	// Event callbacks are never called concurrently, so they can share the
+	// writers and counters without locking
+	rewrites := make(map[string][]hcl.Rewrite)
+	opts := apiOptions(config, stdout)
+	opts.Backup = backup
+	if config.Verbose && config.Workers == 0 {
+		opts.Workers = 1
+	}
+	opts.OnDiscover = func(paths []string) {
+		if config.Verbose {
+			printFound(stdout, paths)
+		}
+	}
+	opts.OnStart = func(path string) {
+		if config.Verbose {
+			_, _ = infoColor.Fprintf(stdout, "🔄 Processing: %s\n", fileColor.Sprint(path))
+		}
+	}
+	// Rewrites are reported with their file rather than as they happen,
+	// since other files may be reported in between
+	opts.OnRewrite = func(path string, rewrite hcl.Rewrite) {
+		rewrites[path] = append(rewrites[path], rewrite)
+	}
+	opts.OnResult = func(result api.Result) {
+		for _, rewrite := range rewrites[result.Path] {
+			printRewrite(stdout, config, result.Path, rewrite)
+		}
 
-		if i < len(linesA) {
-			lineA = linesA[i]
+		if stderrors.Is(result.Err, api.ErrConcurrentModification) {
+			modified = append(modified, result.Path)
 		}
-		if i < len(linesB) {
-			lineB = linesB[i]
+		if patch != nil && result.Status == api.StatusWouldChange {
+			patch.add(result)
 		}
 
-		if lineA != lineB {
-			if lineA != "" {
-				_, _ = fmt.Fprintf(out, "-%s\n", lineA)
-			}
-			if lineB != "" {
-				_, _ = fmt.Fprintf(out, "+%s\n", lineB)
+		err := reportResult(result, config, stdout)
+		switch {
+		case err == nil:
+			processedCount++
+		case stderrors.Is(err, errors.ErrNoChanges):
+			// Already sorted, don't count as error or processed
+		default:
+			errorCount++
+			errors.PrintError(err, stderr)
+		}
+	}
+	opts.OnComplete = func(results []api.Result) {
+		for _, result := range results {
+			if result.Status == api.StatusSkipped {
+				skippedCount++
 			}
-		} else {
-			// Show context (first few and last few lines)
-			if i < 3 || i >= maxLines-3 {
-				_, _ = fmt.Fprintf(out, " %s\n", lineA)
-			} else if i == 3 && maxLines > 6 {
-				_, _ = fmt.Fprintf(out, "...\n")
+			if stderrors.Is(result.Err, api.ErrAtomicAborted) {
+				abortedCount++
 			}
 		}
 	}
+
+	if _, err := api.SortFilesContext(ctx, filePaths, opts); err != nil {
+		errorCount++
+		_, _ = errorColor.Fprintf(stderr, "⏹️  Interrupted, %d files were not processed\n", skippedCount)
+	}
+	if abortedCount > 0 {
+		_, _ = errorColor.Fprintf(stderr, "⏹️  --atomic: %d files were not written because other files failed\n", abortedCount)
+	}
+
+	return processedCount, errorCount, modified
+}
+
+// printUnifiedDiff prints the changes of result as a unified diff, with
+// context unchanged lines around each change and paths relative to the
+// repository root, so it can be applied with git apply or patch -p1.
+// Used in dry-run and validate modes to show what would change.
+func printUnifiedDiff(result api.Result, context int, out io.Writer) {
+	if !result.Changed {
+		_, _ = fmt.Fprintf(out, "(No changes)\n")
+		return
+	}
+
+	_, _ = fmt.Fprint(out, result.UnifiedDiff(context))
 }
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -62,7 +63,7 @@ resource "aws_s3_bucket" "data" {
 	// Run the benchmark
 	for i := 0; i < b.N; i++ {
 		var stdout, stderr bytes.Buffer
-		processFilesConcurrent(files, config, &stdout, &stderr)
+		processFiles(context.Background(), files, config, &stdout, &stderr)
 	}
 }
 
@@ -111,6 +112,7 @@ resource "aws_s3_bucket" "data" {
 		DryRun:    false,
 		Verbose:   false,
 		Validate:  false,
+		Workers:   1,
 	}
 
 	b.ResetTimer()
@@ -118,6 +120,73 @@ resource "aws_s3_bucket" "data" {
 	// Run the benchmark
 	for i := 0; i < b.N; i++ {
 		var stdout, stderr bytes.Buffer
-		processFilesSerial(files, config, &stdout, &stderr)
+		processFiles(context.Background(), files, config, &stdout, &stderr)
+	}
+}
+
+// BenchmarkProcessFilesDryRun measures dry-run processing, which also renders a diff for every file
+func BenchmarkProcessFilesDryRun(b *testing.B) {
+	benchmarkProcessFilesMode(b, &config.Config{DryRun: true, Workers: 1})
+}
+
+// BenchmarkProcessFilesValidate measures validate-mode processing, which also renders a diff for every file
+func BenchmarkProcessFilesValidate(b *testing.B) {
+	benchmarkProcessFilesMode(b, &config.Config{Validate: true, Workers: 1})
+}
+
+// BenchmarkProcessFilesWrite measures sorting files in place, the only mode
+// that checks the sorted content with hcl.CheckEquivalent before writing it
+func BenchmarkProcessFilesWrite(b *testing.B) {
+	benchmarkProcessFilesMode(b, &config.Config{Workers: 1})
+}
+
+// benchmarkProcessFilesMode measures serial processing of 50 unsorted files.
+// In modes that write, the files are made unsorted again before each
+// iteration, so every iteration does the same work.
+func benchmarkProcessFilesMode(b *testing.B, config *config.Config) {
+	b.Helper()
+	tmpDir := b.TempDir()
+	config.Root = tmpDir
+
+	unsortedContent := `resource "aws_instance" "test" {
+  instance_type = "t2.micro"
+  ami = "ami-123"
+}
+
+variable "environment" {
+  type = string
+}
+
+resource "aws_s3_bucket" "data" {
+  bucket_name = "test"
+  acl = "private"
+}
+`
+
+	files := make([]string, 0, 50)
+	for i := 1; i <= 50; i++ {
+		files = append(files, filepath.Join(tmpDir, fmt.Sprintf("file%d.tf", i)))
+	}
+	writeUnsorted := func() {
+		for _, filePath := range files {
+			//nolint:gosec // G306: Benchmark test files can use 0644 permissions
+			if err := os.WriteFile(filePath, []byte(unsortedContent), 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	writeUnsorted()
+	writes := !config.DryRun && !config.Validate
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if writes && i > 0 {
+			b.StopTimer()
+			writeUnsorted()
+			b.StartTimer()
+		}
+		var stdout, stderr bytes.Buffer
+		processFiles(context.Background(), files, config, &stdout, &stderr)
 	}
 }
@@ -2,11 +2,20 @@ package cli
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/obergerkatz/sortTF/api"
+	"github.com/obergerkatz/sortTF/config"
+	"github.com/obergerkatz/sortTF/diff"
+	"github.com/obergerkatz/sortTF/hcl"
+	"github.com/obergerkatz/sortTF/internal/errors"
+	"github.com/obergerkatz/sortTF/vfs"
 )
 
 // TestRunCLI_Help tests that help flag works correctly
@@ -278,6 +287,28 @@ func TestRunCLI_Verbose(t *testing.T) {
 	}
 }
 
+// TestRunCLI_VerboseResultDetails tests that verbose output reports sizes and changed lines
+func TestRunCLI_VerboseResultDetails(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+
+	content := "variable \"b\" {\n  type = string\n}\nvariable \"a\" {\n  type = string\n}\n"
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"--verbose", "--dry-run", testFile}, &stdout, &stderr); exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr.String())
+	}
+
+	want := fmt.Sprintf("%d → %d bytes, ", len(content), len(content)+1)
+	if !strings.Contains(stdout.String(), want) || !strings.Contains(stdout.String(), "lines differ") {
+		t.Errorf("Expected %q in verbose output, got: %s", want, stdout.String())
+	}
+}
+
 // TestRunCLI_Recursive tests recursive directory processing
 func TestRunCLI_Recursive(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -451,6 +482,73 @@ func TestRunCLI_InvalidSyntax(t *testing.T) {
 	}
 }
 
+// TestRunCLI_ValidationProblems tests that every invalid block, nested ones
+// included, is reported with its file:line:col position
+func TestRunCLI_ValidationProblems(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "invalid.tf")
+
+	content := `resource "aws_instance" {
+  ami = "ami-123"
+}
+
+output {
+  value = 1
+}
+
+resource "aws_instance" "web" {
+  dynamic {
+    content {}
+  }
+}
+`
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{testFile}, &stdout, &stderr); exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+
+	for _, want := range []string{
+		"--> " + testFile + ":1:1", "resource block must have exactly 2 labels",
+		"--> " + testFile + ":5:1", "output block must have exactly 1 label",
+		"--> " + testFile + ":10:3", "dynamic block inside resource must have exactly 1 label",
+	} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("Expected %q in stderr, got: %s", want, stderr.String())
+		}
+	}
+}
+
+// TestRunCLI_SyntaxErrorSnippet tests that syntax errors show the offending source line
+func TestRunCLI_SyntaxErrorSnippet(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "broken.tf")
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte("locals {\n  name = \n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"--diagnostic-width", "40", testFile}, &stdout, &stderr); exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+
+	for _, want := range []string{"Syntax error", "--> " + testFile + ":2:10", "2 |   name =", "^"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("Expected %q in stderr, got: %s", want, stderr.String())
+		}
+	}
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if strings.HasPrefix(line, "  Expected") && len(line) > 40 {
+			t.Errorf("Expected detail wrapped at 40 columns, got line %q", line)
+		}
+	}
+}
+
 // TestIsSupportedFile tests the file type checking
 func TestIsSupportedFile(t *testing.T) {
 	tests := []struct {
@@ -484,34 +582,53 @@ func TestPrintUnifiedDiff(t *testing.T) {
 		name      string
 		original  string
 		formatted string
-		wantDiff  bool
+		context   int
+		expected  string
 	}{
 		{
 			name:      "no changes",
 			original:  "variable \"x\" {\n  type = string\n}\n",
 			formatted: "variable \"x\" {\n  type = string\n}\n",
-			wantDiff:  false,
+			context:   3,
+			expected:  "(No changes)\n",
 		},
 		{
 			name:      "attribute reordered",
 			original:  "resource \"x\" \"y\" {\n  b = 1\n  a = 2\n}\n",
 			formatted: "resource \"x\" \"y\" {\n  a = 2\n  b = 1\n}\n",
-			wantDiff:  true,
+			context:   3,
+			expected: "--- a/dir/test.tf\n+++ b/dir/test.tf\n@@ -1,4 +1,4 @@\n" +
+				" resource \"x\" \"y\" {\n-  b = 1\n   a = 2\n+  b = 1\n }\n",
+		},
+		{
+			name:      "moved block keeps the lines after it equal",
+			original:  "b = 1\nc = 2\nd = 3\ne = 4\nf = 5\ng = 6\nh = 7\na = 0\n",
+			formatted: "a = 0\nb = 1\nc = 2\nd = 3\ne = 4\nf = 5\ng = 6\nh = 7\n",
+			context:   1,
+			expected: "--- a/dir/test.tf\n+++ b/dir/test.tf\n" +
+				"@@ -1 +1,2 @@\n+a = 0\n b = 1\n@@ -7,2 +8 @@\n h = 7\n-a = 0\n",
 		},
 	}
 
+	// The paths are relative to the repository root, even for absolute paths
+	repo := t.TempDir()
+	//nolint:gosec // G301: Test directories can use 0755 permissions
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var buf bytes.Buffer
-			printUnifiedDiff(tt.original, tt.formatted, "test.tf", &buf)
-			output := buf.String()
-
-			hasDiff := strings.Contains(output, "+") || strings.Contains(output, "-")
-			if tt.wantDiff && !hasDiff {
-				t.Error("Expected diff output, got none")
+			result := api.Result{
+				Path:     filepath.Join(repo, "dir", "test.tf"),
+				Original: []byte(tt.original),
+				Sorted:   []byte(tt.formatted),
+				Changed:  tt.original != tt.formatted,
 			}
-			if !tt.wantDiff && hasDiff && !strings.Contains(output, "No changes") {
-				t.Errorf("Expected no diff, got: %s", output)
+			printUnifiedDiff(result, tt.context, &buf)
+			if buf.String() != tt.expected {
+				t.Errorf("printUnifiedDiff() =\n%s\nwant:\n%s", buf.String(), tt.expected)
 			}
 		})
 	}
@@ -842,3 +959,505 @@ func TestRunCLI_UnsupportedFileType(t *testing.T) {
 		t.Errorf("Expected 'not a supported file type' in stderr, got: %s", stderrOutput)
 	}
 }
+
+// TestRunCLI_Normalize tests that --normalize reports rewrites and updates the file
+func TestRunCLI_Normalize(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+
+	content := `resource "aws_instance" "example" {
+  ami = "${var.ami}"
+}
+`
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	exitCode := RunCLIWithWriters([]string{"--normalize", "--dry-run", testFile}, &stdout, &stderr)
+
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr.String())
+	}
+
+	stdoutOutput := stdout.String()
+	if !strings.Contains(stdoutOutput, "Would normalize") || !strings.Contains(stdoutOutput, `"${var.ami}" → var.ami`) {
+		t.Errorf("Expected normalization report in output, got: %s", stdoutOutput)
+	}
+	if !strings.Contains(stdoutOutput, "+  ami = var.ami") {
+		t.Errorf("Expected diff to include normalized expression, got: %s", stdoutOutput)
+	}
+}
+
+// TestPrintRewrite tests that rewrites are reported as would-be rewrites
+// when nothing is written
+func TestPrintRewrite(t *testing.T) {
+	rewrite := hcl.Rewrite{Address: "variable.region", Attribute: "default", Before: `"${var.a}"`, After: "var.a"}
+	tests := []struct {
+		name   string
+		config config.Config
+		want   string
+	}{
+		{"write", config.Config{}, "🔧 Normalized main.tf: "},
+		{"dry run", config.Config{DryRun: true}, "🔧 Would normalize main.tf: "},
+		{"validate", config.Config{Validate: true}, "🔧 Would normalize main.tf: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printRewrite(&out, &tt.config, "main.tf", rewrite)
+			if want := tt.want + rewrite.String() + "\n"; out.String() != want {
+				t.Errorf("printRewrite() = %q, want %q", out.String(), want)
+			}
+		})
+	}
+}
+
+// TestRunCLI_MaxLineWidth tests that --max-line-width wraps long lists
+func TestRunCLI_MaxLineWidth(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+
+	content := `resource "aws_instance" "example" {
+  vpc_security_group_ids = ["sg-0123456789abcdef0", "sg-0123456789abcdef1", "sg-0123456789abcdef2"]
+}
+`
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	exitCode := RunCLIWithWriters([]string{"--max-line-width", "80", testFile}, &stdout, &stderr)
+
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr.String())
+	}
+
+	//nolint:gosec // G304: Test file path is controlled
+	result, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(result), "vpc_security_group_ids = [\n    \"sg-0123456789abcdef0\",\n") {
+		t.Errorf("Expected list to be wrapped, got:\n%s", result)
+	}
+}
+
+// TestRunCLI_StyleGuidePreset tests that the style-guide preset sorts depends_on lists
+func TestRunCLI_StyleGuidePreset(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+
+	content := `resource "aws_instance" "example" {
+  depends_on = [aws_subnet.b, aws_iam_role.a]
+}
+`
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The default preset leaves the list alone
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"--validate", testFile}, &stdout, &stderr); exitCode != 0 {
+		t.Errorf("Expected exit code 0 without preset, got %d. Stdout: %s", exitCode, stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	exitCode := RunCLIWithWriters([]string{"--preset", "style-guide", testFile}, &stdout, &stderr)
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr.String())
+	}
+
+	//nolint:gosec // G304: Test file path is controlled
+	result, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(result), "depends_on = [aws_iam_role.a, aws_subnet.b]") {
+		t.Errorf("Expected depends_on to be sorted, got:\n%s", result)
+	}
+}
+
+// TestRunCLI_Layout tests moving blocks to canonical files
+func TestRunCLI_Layout(t *testing.T) {
+	tmpDir := t.TempDir()
+	mainFile := filepath.Join(tmpDir, "main.tf")
+
+	content := `variable "region" {
+  type = string
+}
+
+resource "aws_instance" "example" {
+  ami = "ami-12345"
+}
+`
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(mainFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dry run prints the planned move without changing anything
+	var stdout, stderr bytes.Buffer
+	exitCode := RunCLIWithWriters([]string{"--layout", "--dry-run", tmpDir}, &stdout, &stderr)
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "variable.region") || !strings.Contains(stdout.String(), "would be moved") {
+		t.Errorf("Expected planned move in output, got: %s", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "variables.tf")); !os.IsNotExist(err) {
+		t.Error("variables.tf should not be created in dry-run mode")
+	}
+
+	// Validate fails while blocks are out of place
+	stdout.Reset()
+	stderr.Reset()
+	if exitCode := RunCLIWithWriters([]string{"--layout", "--validate", tmpDir}, &stdout, &stderr); exitCode != 1 {
+		t.Errorf("Expected exit code 1 in validate mode, got %d", exitCode)
+	}
+
+	// Apply the layout
+	stdout.Reset()
+	stderr.Reset()
+	if exitCode := RunCLIWithWriters([]string{"--layout", tmpDir}, &stdout, &stderr); exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr.String())
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	variables, err := os.ReadFile(filepath.Join(tmpDir, "variables.tf"))
+	if err != nil || !strings.Contains(string(variables), `variable "region"`) {
+		t.Errorf("Expected variable in variables.tf, got: %s (err %v)", variables, err)
+	}
+}
+
+// TestRunCLI_LayoutRequiresDirectory tests that --layout rejects a single file
+func TestRunCLI_LayoutRequiresDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte("variable \"a\" {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"--layout", testFile}, &stdout, &stderr); exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "requires a directory") {
+		t.Errorf("Expected directory error, got: %s", stderr.String())
+	}
+}
+
+// TestRunCLI_NoSchema tests that --no-schema skips structural checks
+func TestRunCLI_NoSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+
+	content := `module "vpc" {
+  cidr = "10.0.0.0/16"
+}
+`
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{testFile}, &stdout, &stderr); exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), `The argument "source" is required`) {
+		t.Errorf("Expected missing source error, got: %s", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if exitCode := RunCLIWithWriters([]string{"--no-schema", testFile}, &stdout, &stderr); exitCode != 0 {
+		t.Errorf("Expected exit code 0 with --no-schema, got %d. Stderr: %s", exitCode, stderr.String())
+	}
+}
+
+// TestRunCLI_ValidateDuplicates tests that --validate reports duplicate addresses across files
+func TestRunCLI_ValidateDuplicates(t *testing.T) {
+	tmpDir := t.TempDir()
+	sources := map[string]string{
+		"main.tf":    "variable \"region\" {}\n",
+		"network.tf": "variable \"region\" {}\n",
+	}
+	for name, content := range sources {
+		//nolint:gosec // G306: Test files can use 0644
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without --validate only each file is checked
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{tmpDir}, &stdout, &stderr); exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if exitCode := RunCLIWithWriters([]string{"--validate", tmpDir}, &stdout, &stderr); exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	for _, want := range []string{
+		"duplicate declarations in module",
+		"--> " + filepath.Join(tmpDir, "network.tf") + ":1:1",
+		"already declared at " + filepath.Join(tmpDir, "main.tf") + ":1:1",
+	} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("Expected %q in stderr, got: %s", want, stderr.String())
+		}
+	}
+}
+
+// TestProcessFiles_Interrupted tests that a cancelled run reports the files it skipped
+func TestProcessFiles_Interrupted(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte("variable \"b\" {}\nvariable \"a\" {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var stdout, stderr bytes.Buffer
+	processed, errorCount := processFiles(ctx, []string{testFile}, &config.Config{Root: tmpDir}, &stdout, &stderr)
+	if processed != 0 || errorCount != 1 {
+		t.Errorf("Expected 0 processed and 1 error, got %d and %d", processed, errorCount)
+	}
+	if !strings.Contains(stderr.String(), "Interrupted, 1 files were not processed") {
+		t.Errorf("Expected interrupt message, got: %s", stderr.String())
+	}
+}
+
+// TestRunCLI_FileTimeout tests that files running out of time fail without being written
+func TestRunCLI_FileTimeout(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+	content := "variable \"b\" {}\nvariable \"a\" {}\n"
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"--file-timeout", "1ns", "--workers", "2", testFile}, &stdout, &stderr); exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "timed out after 1ns") {
+		t.Errorf("Expected timeout error, got: %s", stderr.String())
+	}
+
+	//nolint:gosec // G304: Test file path is controlled
+	if got, _ := os.ReadFile(testFile); string(got) != content {
+		t.Errorf("Expected file to be left alone, got:\n%s", got)
+	}
+}
+
+// TestRunCLI_VerboseEventOrder tests that verbose output follows the run's events in file order
+func TestRunCLI_VerboseEventOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"b.tf", "a.tf"} {
+		//nolint:gosec // G306: Test files can use 0644
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("variable \"x\" {\n  type = string\n}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"--verbose", tmpDir}, &stdout, &stderr); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr.String())
+	}
+
+	output := stdout.String()
+	order := []string{
+		"Found 2 files",
+		"Processing: " + filepath.Join(tmpDir, "a.tf"),
+		"No changes needed: " + filepath.Join(tmpDir, "a.tf"),
+		"Processing: " + filepath.Join(tmpDir, "b.tf"),
+		"No changes needed: " + filepath.Join(tmpDir, "b.tf"),
+	}
+	last := -1
+	for _, want := range order {
+		i := strings.Index(output, want)
+		if i <= last {
+			t.Fatalf("Expected %q after the previous line, got:\n%s", want, output)
+		}
+		last = i
+	}
+}
+
+// editingFS simulates someone saving each file once while sortTF is sorting it.
+type editingFS struct {
+	vfs.OSFS
+	edit   string
+	edited map[string]bool
+}
+
+// WriteFileIf edits the file on its first write, before writing it.
+func (e *editingFS) WriteFileIf(name string, data []byte, perm fs.FileMode, check func() error) error {
+	if !e.edited[name] {
+		e.edited[name] = true
+		//nolint:gosec // G306: Test files can use 0644
+		if err := os.WriteFile(name, []byte(e.edit), 0644); err != nil {
+			return err
+		}
+	}
+	return e.OSFS.WriteFileIf(name, data, perm, check)
+}
+
+// TestProcessFiles_ConcurrentModification tests that files edited while being
+// sorted are kept and sorted again when the user agrees.
+func TestProcessFiles_ConcurrentModification(t *testing.T) {
+	edit := "variable \"c\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n"
+	sortedEdit := "variable \"a\" {\n  type = string\n}\n\nvariable \"c\" {\n  type = string\n}\n"
+
+	tests := []struct {
+		name          string
+		input         string
+		yes           bool
+		wantProcessed int
+		wantErrors    int
+		wantContent   string
+	}{
+		{"retry", "y\n", false, 1, 0, sortedEdit},
+		{"decline", "n\n", false, 0, 1, edit},
+		{"yes flag", "", true, 1, 0, sortedEdit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			testFile := filepath.Join(tmpDir, "main.tf")
+			//nolint:gosec // G306: Test files can use 0644
+			if err := os.WriteFile(testFile, []byte("variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			oldStdin := stdin
+			stdin = strings.NewReader(tt.input)
+			defer func() { stdin = oldStdin }()
+
+			var stdout, stderr bytes.Buffer
+			cfg := &config.Config{Root: tmpDir, Yes: tt.yes, FS: &editingFS{edit: edit, edited: make(map[string]bool)}}
+			processed, errorCount := processFiles(context.Background(), []string{testFile}, cfg, &stdout, &stderr)
+			if processed != tt.wantProcessed || errorCount != tt.wantErrors {
+				t.Errorf("Expected %d processed and %d errors, got %d and %d", tt.wantProcessed, tt.wantErrors, processed, errorCount)
+			}
+			if !strings.Contains(stderr.String(), "modified while it was being sorted") {
+				t.Errorf("Expected concurrent modification error, got: %s", stderr.String())
+			}
+			if prompted := strings.Contains(stdout.String(), "1 files changed while being sorted. Sort them again?"); prompted == tt.yes {
+				t.Errorf("Expected prompt %v, got: %s", !tt.yes, stdout.String())
+			}
+			//nolint:gosec // G304: Test file path is controlled
+			if got, _ := os.ReadFile(testFile); string(got) != tt.wantContent {
+				t.Errorf("Expected content:\n%s\ngot:\n%s", tt.wantContent, got)
+			}
+		})
+	}
+}
+
+// TestRunCLI_Backup tests saving originals next to the sorted files
+func TestRunCLI_Backup(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+	content := "variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n"
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"--backup", tmpDir}, &stdout, &stderr); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	if got, err := os.ReadFile(testFile + ".orig"); err != nil || string(got) != content {
+		t.Errorf("Expected main.tf.orig to hold the original, got %q (err %v)", got, err)
+	}
+}
+
+// TestRunCLI_Atomic tests that --atomic writes nothing when a file fails.
+func TestRunCLI_Atomic(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := "variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n"
+	files := map[string]string{
+		"a.tf": content,
+		"b.tf": content,
+		"c.tf": "variable {\n",
+	}
+	for name, data := range files {
+		//nolint:gosec // G306: Test files can use 0644
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"--atomic", tmpDir}, &stdout, &stderr); exitCode != 1 {
+		t.Fatalf("Expected exit code 1, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "2 files were not written because other files failed") {
+		t.Errorf("Expected the aborted files to be reported, got: %s", stderr.String())
+	}
+	for _, name := range []string{"a.tf", "b.tf"} {
+		//nolint:gosec // G304: Test file path is controlled
+		if got, _ := os.ReadFile(filepath.Join(tmpDir, name)); string(got) != content {
+			t.Errorf("Expected %s to be left alone, got:\n%s", name, got)
+		}
+	}
+}
+
+// TestReportResult_NotEquivalent tests that a file whose sorted content would
+// change its meaning is reported with every difference
+func TestReportResult_NotEquivalent(t *testing.T) {
+	result := api.Result{
+		Path:   "main.tf",
+		Status: api.StatusFailed,
+		Err: &hcl.HCLError{
+			Op:   "SortAndFormatHCLFile",
+			Kind: hcl.KindSorting,
+			Err:  &hcl.EquivalenceError{Differences: []string{`attribute "inputs" was lost`, `block backend "s3" was lost`}},
+		},
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := reportResult(result, &config.Config{}, &stdout)
+	errors.PrintError(err, &stderr)
+
+	for _, want := range []string{"Sorting error", "not writing main.tf", `- attribute "inputs" was lost`, `- block backend "s3" was lost`} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("Expected %q in output, got: %s", want, stderr.String())
+		}
+	}
+}
+
+// TestReportResult_NotIdempotent tests that a file whose sorted content
+// changes when sorted again is reported with the diff between the passes
+func TestReportResult_NotIdempotent(t *testing.T) {
+	first := "a = 1\n\n\nb = 2\nc = 3\nd = 4\ne = 5\nf = 6\n"
+	second := "a = 1\n\nb = 2\nc = 3\nd = 4\ne = 5\nf = 6\n"
+	result := api.Result{
+		Path:   "main.tf",
+		Status: api.StatusFailed,
+		Err:    &api.IdempotenceError{First: []byte(first), Second: []byte(second), Diff: diff.Lines(first, second)},
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := reportResult(result, &config.Config{}, &stdout)
+	errors.PrintError(err, &stderr)
+
+	want := "   --- first pass\n   +++ second pass\n   @@ -1,5 +1,4 @@\n    a = 1\n    \n   -\n    b = 2\n    c = 3\n"
+	if !strings.Contains(stderr.String(), "sorting main.tf again changes it") || !strings.Contains(stderr.String(), want) {
+		t.Errorf("Expected the diff between the passes, got:\n%s", stderr.String())
+	}
+}
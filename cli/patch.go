@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/obergerkatz/sortTF/api"
+	"github.com/obergerkatz/sortTF/internal/files"
+)
+
+// runPatch collects the changes of a dry-run or validate run, for --patch.
+type runPatch struct {
+	path    string       // File the patch is written to
+	context int          // Unchanged lines around each change
+	results []api.Result // Files that would change
+}
+
+// add records the changes of a file that would change.
+func (p *runPatch) add(result api.Result) {
+	p.results = append(p.results, result)
+}
+
+// write writes the collected changes to the patch file as one patch in git
+// format, with the files in path order. Returns the number of files in it.
+func (p *runPatch) write() (int, error) {
+	slices.SortFunc(p.results, func(a, b api.Result) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	var out strings.Builder
+	for _, result := range p.results {
+		out.WriteString(gitDiff(result, p.context))
+	}
+
+	//nolint:gosec // G306: The patch is meant to be shared, like the files it changes
+	if err := os.WriteFile(p.path, []byte(out.String()), 0644); err != nil {
+		return 0, err
+	}
+	return len(p.results), nil
+}
+
+// gitDiff returns the changes of result as a git diff: a "diff --git" line
+// followed by the unified diff of the result.
+func gitDiff(result api.Result, context int) string {
+	name := files.RepoPath(result.Path)
+	return fmt.Sprintf("diff --git a/%s b/%s\n", name, name) + result.UnifiedDiff(context)
+}
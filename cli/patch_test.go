@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunCLI_Patch tests writing the changes of a dry run to one patch that
+// git apply accepts at the repository root
+func TestRunCLI_Patch(t *testing.T) {
+	repo := t.TempDir()
+	dir := filepath.Join(repo, "infra")
+	//nolint:gosec // G301: Test directories can use 0755 permissions
+	for _, d := range []string{filepath.Join(repo, ".git"), filepath.Join(dir, "vpc")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unsorted := "variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n"
+	sorted := "variable \"a\" {\n  type = string\n}\n\nvariable \"b\" {\n  type = string\n}\n"
+	for name, content := range map[string]string{"main.tf": unsorted, "vpc/main.tf": unsorted, "ok.tf": "variable \"c\" {\n  type = string\n}\n"} {
+		//nolint:gosec // G306: Test files can use 0644
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	patchFile := filepath.Join(t.TempDir(), "out.patch")
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"--dry-run", "--recursive", "--patch", patchFile, dir}, &stdout, &stderr); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Wrote the changes of 2 files to "+patchFile) {
+		t.Errorf("Expected the patch to be reported, got: %s", stdout.String())
+	}
+
+	//nolint:gosec // G304: Test file path is controlled
+	patch, err := os.ReadFile(patchFile)
+	if err != nil {
+		t.Fatalf("Failed to read patch: %v", err)
+	}
+	if !strings.HasPrefix(string(patch), "diff --git a/infra/main.tf b/infra/main.tf\n--- a/infra/main.tf\n+++ b/infra/main.tf\n@@ ") ||
+		!strings.Contains(string(patch), "\ndiff --git a/infra/vpc/main.tf b/infra/vpc/main.tf\n") ||
+		strings.Contains(string(patch), "ok.tf") {
+		t.Errorf("Unexpected patch:\n%s", patch)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return
+	}
+	cmd := exec.Command("git", "apply", patchFile)
+	cmd.Dir = repo
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git apply failed: %v\n%s", err, output)
+	}
+	for _, name := range []string{"main.tf", "vpc/main.tf"} {
+		//nolint:gosec // G304: Test file path is controlled
+		if got, _ := os.ReadFile(filepath.Join(dir, name)); string(got) != sorted {
+			t.Errorf("Expected %s to be sorted after git apply, got:\n%s", name, got)
+		}
+	}
+}
+
+// TestRunCLI_PatchNoChanges tests that a run without changes writes an empty patch
+func TestRunCLI_PatchNoChanges(t *testing.T) {
+	dir := t.TempDir()
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte("variable \"a\" {\n  type = string\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	patchFile := filepath.Join(t.TempDir(), "out.patch")
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"--validate", "--patch", patchFile, dir}, &stdout, &stderr); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	if patch, err := os.ReadFile(patchFile); err != nil || len(patch) != 0 {
+		t.Errorf("Expected an empty patch, got %q, %v", patch, err)
+	}
+}
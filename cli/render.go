@@ -0,0 +1,473 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/obergerkatz/sortTF/api"
+	"github.com/obergerkatz/sortTF/config"
+	"github.com/obergerkatz/sortTF/diff"
+
+	hcllib "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// defaultDiffWidth is the width of side-by-side diffs when neither
+// --diff-width, the terminal nor the COLUMNS environment variable gives one.
+const defaultDiffWidth = 80
+
+// printDiff prints what sorting changes in a file in dry-run and validate
+// mode, in the style selected with --diff-style. With --detect-moves, blocks
+// that only moved are described first and left out of the diff.
+func printDiff(result api.Result, cfg *config.Config, out io.Writer) {
+	original, sorted := string(result.Original), string(result.Sorted)
+
+	var moves []blockMove
+	if cfg.DetectMoves {
+		moves = detectMoves(result.Original, result.Sorted)
+		for _, move := range moves {
+			_, _ = infoColor.Fprintf(out, "↕️  block `%s` moved from L%d to L%d\n", move.address, move.from, move.to)
+		}
+	}
+
+	// Only the unified style shows changes to the final line break, and only
+	// without moves left out can its output be applied
+	unified := cfg.DiffStyle == config.DiffStyleUnified || cfg.DiffStyle == ""
+	var lines []diff.Line
+	if !unified {
+		lines = result.Diff()
+	}
+	if (unified || !diff.Changed(lines)) && len(moves) == 0 {
+		printUnifiedDiff(result, cfg.DiffContext, out)
+		return
+	}
+
+	// Moved blocks are taken out of both sides before diffing the rest
+	var oldNumbers, newNumbers []int
+	if len(moves) > 0 {
+		var oldLines, newLines []string
+		oldLines, oldNumbers = keepLines(diff.SplitLines(original), moves, func(move blockMove) lineRange { return move.oldRange })
+		newLines, newNumbers = keepLines(diff.SplitLines(sorted), moves, func(move blockMove) lineRange { return move.newRange })
+		lines = diff.Lines(joinLines(oldLines), joinLines(newLines))
+	}
+
+	hunks := numberedHunks(lines, cfg.DiffContext, oldNumbers, newNumbers)
+	switch cfg.DiffStyle {
+	case config.DiffStyleSideBySide:
+		printSideBySide(hunks, diffWidth(cfg, out), out)
+	case config.DiffStyleWords:
+		printWordDiff(hunks, out)
+	default:
+		printHunks(hunks, out)
+	}
+}
+
+// diffWidth returns the width of side-by-side diffs: --diff-width if given,
+// else the width of the terminal, the COLUMNS environment variable or
+// defaultDiffWidth.
+func diffWidth(cfg *config.Config, out io.Writer) int {
+	if cfg.DiffWidth > 0 {
+		return cfg.DiffWidth
+	}
+	if width := terminalWidth(out); width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultDiffWidth
+}
+
+// numberedLine is a line of a hunk with its line numbers in the original and
+// the sorted content, 0 on the side it does not appear on.
+type numberedLine struct {
+	diff.Line
+	oldLine, newLine int
+}
+
+// numberedHunk is a hunk whose lines carry their line numbers.
+type numberedHunk struct {
+	header string
+	lines  []numberedLine
+}
+
+// numberedHunks groups a diff into hunks with context unchanged lines around
+// each change and numbers their lines. If lines were taken out of the
+// content before diffing, oldNumbers and newNumbers map the line numbers of
+// what is left to the full content; nil means nothing was taken out.
+func numberedHunks(lines []diff.Line, context int, oldNumbers, newNumbers []int) []numberedHunk {
+	var hunks []numberedHunk
+	for _, hunk := range diff.Hunks(lines, context) {
+		header := diff.Hunk{
+			OldStart: hunkStart(oldNumbers, hunk.OldStart, hunk.OldLines),
+			OldLines: hunk.OldLines,
+			NewStart: hunkStart(newNumbers, hunk.NewStart, hunk.NewLines),
+			NewLines: hunk.NewLines,
+		}.Header()
+		numbered := numberedHunk{header: header}
+		oldLine, newLine := hunk.OldStart, hunk.NewStart
+		for _, line := range hunk.Lines {
+			n := numberedLine{Line: line}
+			if line.Op != diff.Insert {
+				n.oldLine = lineNumber(oldNumbers, oldLine)
+				oldLine++
+			}
+			if line.Op != diff.Delete {
+				n.newLine = lineNumber(newNumbers, newLine)
+				newLine++
+			}
+			numbered.lines = append(numbered.lines, n)
+		}
+		hunks = append(hunks, numbered)
+	}
+	return hunks
+}
+
+// lineNumber maps line n to its number in the full content, as described
+// for numberedHunks.
+func lineNumber(numbers []int, n int) int {
+	if numbers == nil {
+		return n
+	}
+	return numbers[n-1]
+}
+
+// hunkStart maps the start of one side of a hunk to the full content. An
+// empty range starts after the line before it.
+func hunkStart(numbers []int, start, count int) int {
+	switch {
+	case count > 0:
+		return lineNumber(numbers, start)
+	case start > 1:
+		return lineNumber(numbers, start-1) + 1
+	default:
+		return 1
+	}
+}
+
+// printHunks prints numbered hunks in unified format. Since moved blocks are
+// left out, there is no file header: the output is for reading, not for git
+// apply.
+func printHunks(hunks []numberedHunk, out io.Writer) {
+	for _, hunk := range hunks {
+		_, _ = fmt.Fprintln(out, hunk.header)
+		for _, line := range hunk.lines {
+			_, _ = fmt.Fprintln(out, line.Op.String()+line.Text)
+		}
+	}
+}
+
+// linePair is a row of a side-by-side or word diff: an unchanged line, a
+// deleted line, an inserted line, or a deleted line with the inserted line
+// that replaces it. The side a line is missing from is nil.
+type linePair struct {
+	left, right *numberedLine
+}
+
+// pairLines turns the lines of a hunk into rows. Within each run of changes,
+// the n-th deleted line is paired with the n-th inserted line.
+func pairLines(lines []numberedLine) []linePair {
+	var pairs []linePair
+	for i := 0; i < len(lines); {
+		if lines[i].Op == diff.Equal {
+			pairs = append(pairs, linePair{left: &lines[i], right: &lines[i]})
+			i++
+			continue
+		}
+
+		var deleted, inserted []*numberedLine
+		for ; i < len(lines) && lines[i].Op != diff.Equal; i++ {
+			if lines[i].Op == diff.Delete {
+				deleted = append(deleted, &lines[i])
+			} else {
+				inserted = append(inserted, &lines[i])
+			}
+		}
+		for j := range max(len(deleted), len(inserted)) {
+			var pair linePair
+			if j < len(deleted) {
+				pair.left = deleted[j]
+			}
+			if j < len(inserted) {
+				pair.right = inserted[j]
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// printSideBySide prints numbered hunks in two columns that fit in width:
+// the original content on the left, the sorted content on the right. As in
+// diff -y, the marker between them is "|" for a changed line, "<" for a
+// deleted and ">" for an inserted one.
+func printSideBySide(hunks []numberedHunk, width int, out io.Writer) {
+	largest := 0
+	for _, hunk := range hunks {
+		for _, line := range hunk.lines {
+			largest = max(largest, line.oldLine, line.newLine)
+		}
+	}
+	digits := len(strconv.Itoa(largest))
+	// Each column holds a line number, a space and the text; " | " separates them
+	textWidth := max((width-3)/2-digits-1, 10)
+
+	for _, hunk := range hunks {
+		_, _ = fmt.Fprintln(out, hunk.header)
+		for _, pair := range pairLines(hunk.lines) {
+			marker := " "
+			switch {
+			case pair.left == nil:
+				marker = ">"
+			case pair.right == nil:
+				marker = "<"
+			case pair.left.Op != diff.Equal:
+				marker = "|"
+			}
+
+			leftCell := strings.Repeat(" ", digits+1+textWidth)
+			if pair.left != nil {
+				leftCell = fmt.Sprintf("%*d %s", digits, pair.left.oldLine, fitColumn(pair.left.Text, textWidth))
+				if pair.left.Op == diff.Delete {
+					leftCell = deletedColor.Sprint(leftCell)
+				}
+			}
+			rightCell := ""
+			if pair.right != nil {
+				rightCell = fmt.Sprintf("%*d %s", digits, pair.right.newLine, strings.TrimRight(fitColumn(pair.right.Text, textWidth), " "))
+				if pair.right.Op == diff.Insert {
+					rightCell = insertedColor.Sprint(rightCell)
+				}
+			}
+			_, _ = fmt.Fprintln(out, strings.TrimRight(leftCell+" "+marker+" "+rightCell, " "))
+		}
+	}
+}
+
+// fitColumn pads or truncates text to exactly width characters, marking
+// truncated text with "…". Tabs count as two spaces.
+func fitColumn(text string, width int) string {
+	runes := []rune(strings.ReplaceAll(text, "\t", "  "))
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
+
+// printWordDiff prints numbered hunks with each changed line once, marking
+// deleted words as [-words-] and inserted words as {+words+}, like
+// git diff --word-diff=plain.
+func printWordDiff(hunks []numberedHunk, out io.Writer) {
+	for _, hunk := range hunks {
+		_, _ = fmt.Fprintln(out, hunk.header)
+		for _, pair := range pairLines(hunk.lines) {
+			switch {
+			case pair.left == nil:
+				_, _ = fmt.Fprintln(out, markWords(diff.Insert, pair.right.Text))
+			case pair.right == nil:
+				_, _ = fmt.Fprintln(out, markWords(diff.Delete, pair.left.Text))
+			case pair.left.Op == diff.Equal:
+				_, _ = fmt.Fprintln(out, pair.left.Text)
+			default:
+				_, _ = fmt.Fprintln(out, wordDiff(pair.left.Text, pair.right.Text))
+			}
+		}
+	}
+}
+
+// wordDiff renders the word-level changes from one line to another on a
+// single line, marking consecutive changes of the same kind together.
+func wordDiff(a, b string) string {
+	var out strings.Builder
+	words := diff.Words(a, b)
+	for i := 0; i < len(words); {
+		op := words[i].Op
+		var run strings.Builder
+		for ; i < len(words) && words[i].Op == op; i++ {
+			run.WriteString(words[i].Text)
+		}
+		out.WriteString(markWords(op, run.String()))
+	}
+	return out.String()
+}
+
+// markWords marks deleted or inserted text for a word diff.
+func markWords(op diff.Op, text string) string {
+	switch op {
+	case diff.Delete:
+		return deletedColor.Sprint("[-" + text + "-]")
+	case diff.Insert:
+		return insertedColor.Sprint("{+" + text + "+}")
+	default:
+		return text
+	}
+}
+
+// blockMove is a top-level block that sorting moved without changing it.
+type blockMove struct {
+	address  string // Dotted block address, e.g. "resource.aws_s3_bucket.logs"
+	from, to int    // First line of the block in the original and sorted content
+
+	// The lines left out of the diff in the original and sorted content:
+	// the block and a blank line next to it
+	oldRange, newRange lineRange
+}
+
+// lineRange is a range of lines, from start to end inclusive.
+type lineRange struct {
+	start, end int
+}
+
+// contains reports whether line is in the range.
+func (r lineRange) contains(line int) bool {
+	return line >= r.start && line <= r.end
+}
+
+// detectMoves finds the top-level blocks that appear unchanged in both the
+// original and the sorted content but in a different order. Each original
+// block is paired with the first unpaired identical block with the same
+// address; the largest blocks that keep their relative order stay, and the
+// others are reported as moved. Between blocks of the same size, the one
+// that stays closer to its line stays. Returns nil if either content does
+// not parse.
+func detectMoves(original, sorted []byte) []blockMove {
+	origLines, sortedLines := diff.SplitLines(string(original)), diff.SplitLines(string(sorted))
+	origSpans, sortedSpans := blockSpans(original), blockSpans(sorted)
+	// A line of a block outweighs any distance a block can move
+	lineWeight := len(origLines) + len(sortedLines) + 1
+
+	var origPaired, sortedPaired []lineRange
+	var addresses []string
+	var positions, weights []int
+	used := make([]bool, len(sortedSpans))
+	for _, span := range origSpans {
+		for j, candidate := range sortedSpans {
+			if used[j] || candidate.address != span.address ||
+				!slices.Equal(origLines[span.start-1:span.end], sortedLines[candidate.start-1:candidate.end]) {
+				continue
+			}
+			used[j] = true
+			origPaired = append(origPaired, span.lineRange)
+			sortedPaired = append(sortedPaired, candidate.lineRange)
+			addresses = append(addresses, span.address)
+			positions = append(positions, j)
+			distance := max(span.start-candidate.start, candidate.start-span.start)
+			weights = append(weights, (span.end-span.start+1)*lineWeight-distance)
+			break
+		}
+	}
+
+	var moves []blockMove
+	origClaimed, sortedClaimed := make(map[int]bool), make(map[int]bool)
+	for i, stays := range heaviestIncreasing(positions, weights) {
+		if stays {
+			continue
+		}
+		moves = append(moves, blockMove{
+			address:  addresses[i],
+			from:     origPaired[i].start,
+			to:       sortedPaired[i].start,
+			oldRange: withBlankLine(origPaired[i], origLines, origClaimed),
+			newRange: withBlankLine(sortedPaired[i], sortedLines, sortedClaimed),
+		})
+	}
+	return moves
+}
+
+// heaviestIncreasing returns which values form the increasing subsequence
+// with the largest total weight.
+func heaviestIncreasing(values, weights []int) []bool {
+	total := make([]int, len(values))
+	prev := make([]int, len(values))
+	best := -1
+	for i := range values {
+		total[i], prev[i] = weights[i], -1
+		for j := range i {
+			if values[j] < values[i] && total[j]+weights[i] > total[i] {
+				total[i], prev[i] = total[j]+weights[i], j
+			}
+		}
+		if best < 0 || total[i] > total[best] {
+			best = i
+		}
+	}
+
+	in := make([]bool, len(values))
+	for i := best; i >= 0; i = prev[i] {
+		in[i] = true
+	}
+	return in
+}
+
+// withBlankLine returns r extended by the blank line after it, or else the
+// one before it, so a moved block takes its separating blank line along. A
+// blank line is taken by one block only; claimed records the taken lines.
+func withBlankLine(r lineRange, lines []string, claimed map[int]bool) lineRange {
+	free := func(line int) bool {
+		return line >= 1 && line <= len(lines) && !claimed[line] && strings.TrimSpace(lines[line-1]) == ""
+	}
+	switch {
+	case free(r.end + 1):
+		r.end++
+		claimed[r.end] = true
+	case free(r.start - 1):
+		r.start--
+		claimed[r.start] = true
+	}
+	return r
+}
+
+// keepLines returns the lines outside the ranges of the moves, as given by
+// rangeOf, with their line numbers.
+func keepLines(lines []string, moves []blockMove, rangeOf func(blockMove) lineRange) ([]string, []int) {
+	var kept []string
+	var numbers []int
+	for i, line := range lines {
+		if slices.ContainsFunc(moves, func(move blockMove) bool { return rangeOf(move).contains(i + 1) }) {
+			continue
+		}
+		kept = append(kept, line)
+		numbers = append(numbers, i+1)
+	}
+	return kept, numbers
+}
+
+// joinLines joins lines into a text with a line break after every line.
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// blockSpan is the line range of a top-level block with its address.
+type blockSpan struct {
+	address string
+	lineRange
+}
+
+// blockSpans returns the spans of the top-level blocks in src, or nil if src
+// does not parse.
+func blockSpans(src []byte) []blockSpan {
+	file, diags := hclsyntax.ParseConfig(src, "", hcllib.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil
+	}
+
+	spans := make([]blockSpan, 0, len(body.Blocks))
+	for _, block := range body.Blocks {
+		spans = append(spans, blockSpan{
+			address:   strings.Join(append([]string{block.Type}, block.Labels...), "."),
+			lineRange: lineRange{start: block.Range().Start.Line, end: block.Range().End.Line},
+		})
+	}
+	return spans
+}
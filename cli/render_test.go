@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/obergerkatz/sortTF/api"
+	"github.com/obergerkatz/sortTF/config"
+)
+
+const (
+	renderOriginal = `resource "aws_instance" "web" {
+  instance_type = "t3.micro"
+  ami = "ami-1"
+}
+
+output "id" {
+  value = aws_instance.web.id
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+
+variable "region" {
+  type = string
+}
+`
+	renderSorted = `variable "region" {
+  type = string
+}
+
+resource "aws_instance" "web" {
+  ami           = "ami-1"
+  instance_type = "t3.micro"
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+
+output "id" {
+  value = aws_instance.web.id
+}
+`
+)
+
+// renderResult returns the result of sorting renderOriginal into renderSorted.
+func renderResult() api.Result {
+	return api.Result{
+		Path:     "main.tf",
+		Status:   api.StatusWouldChange,
+		Original: []byte(renderOriginal),
+		Sorted:   []byte(renderSorted),
+		Changed:  true,
+	}
+}
+
+// TestPrintDiff tests the diff styles, with and without move detection
+func TestPrintDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		expected string
+	}{
+		{
+			name: "unified with moves",
+			cfg:  config.Config{DiffStyle: config.DiffStyleUnified, DiffContext: 1, DetectMoves: true},
+			expected: "↕️  block `output.id` moved from L6 to L14\n" +
+				"↕️  block `variable.region` moved from L14 to L1\n" +
+				"@@ -1,4 +5,4 @@\n" +
+				" resource \"aws_instance\" \"web\" {\n" +
+				"+  ami           = \"ami-1\"\n" +
+				"   instance_type = \"t3.micro\"\n" +
+				"-  ami = \"ami-1\"\n" +
+				" }\n",
+		},
+		{
+			name: "side by side with moves",
+			cfg:  config.Config{DiffStyle: config.DiffStyleSideBySide, DiffContext: 3, DiffWidth: 70, DetectMoves: true},
+			expected: "↕️  block `output.id` moved from L6 to L14\n" +
+				"↕️  block `variable.region` moved from L14 to L1\n" +
+				"@@ -1,6 +5,6 @@\n" +
+				" 1 resource \"aws_instance\" \"web\"…    5 resource \"aws_instance\" \"web\"…\n" +
+				"                                  >  6   ami           = \"ami-1\"\n" +
+				" 2   instance_type = \"t3.micro\"      7   instance_type = \"t3.micro\"\n" +
+				" 3   ami = \"ami-1\"                <\n" +
+				" 4 }                                 8 }\n" +
+				" 5                                   9\n" +
+				"10 resource \"aws_s3_bucket\" \"log…   10 resource \"aws_s3_bucket\" \"log…\n",
+		},
+		{
+			name: "words with moves",
+			cfg:  config.Config{DiffStyle: config.DiffStyleWords, DiffContext: 0, DetectMoves: true},
+			expected: "↕️  block `output.id` moved from L6 to L14\n" +
+				"↕️  block `variable.region` moved from L14 to L1\n" +
+				"@@ -1,0 +6 @@\n" +
+				"{+  ami           = \"ami-1\"+}\n" +
+				"@@ -3 +7,0 @@\n" +
+				"[-  ami = \"ami-1\"-]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printDiff(renderResult(), &tt.cfg, &out)
+			if out.String() != tt.expected {
+				t.Errorf("printDiff() =\n%s\nwant:\n%s", out.String(), tt.expected)
+			}
+		})
+	}
+}
+
+// TestPrintDiff_Unified tests that the unified style without moves prints a
+// diff that can be applied
+func TestPrintDiff_Unified(t *testing.T) {
+	repo := t.TempDir()
+	//nolint:gosec // G301: Test directories can use 0755 permissions
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	result := renderResult()
+	result.Path = filepath.Join(repo, "main.tf")
+
+	var out bytes.Buffer
+	printDiff(result, &config.Config{DiffStyle: config.DiffStyleUnified, DiffContext: 3}, &out)
+	if !strings.HasPrefix(out.String(), "--- a/main.tf\n+++ b/main.tf\n@@ -1,16 +1,16 @@\n") ||
+		!strings.Contains(out.String(), "\n+variable \"region\" {\n") {
+		t.Errorf("Expected a unified diff, got:\n%s", out.String())
+	}
+}
+
+// TestDetectMoves tests which blocks are reported as moved
+func TestDetectMoves(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		sorted   string
+		expected []string
+	}{
+		{
+			name:     "nothing moved",
+			original: "variable \"a\" {}\n\nvariable \"b\" {}\n",
+			sorted:   "variable \"a\" {}\n\nvariable \"b\" {}\n",
+		},
+		{
+			name:     "smaller block moves",
+			original: "variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {}\n",
+			sorted:   "variable \"a\" {}\n\nvariable \"b\" {\n  type = string\n}\n",
+			expected: []string{"variable.a 5 1 4-5 1-2"},
+		},
+		{
+			name:     "changed blocks are not paired",
+			original: "variable \"b\" {\n  type    = string\n}\n\nvariable \"a\" {}\n",
+			sorted:   "variable \"a\" {}\n\nvariable \"b\" {\n  type = string\n}\n",
+		},
+		{
+			name:     "closer block stays",
+			original: "variable \"c\" {}\n\nvariable \"b\" {}\n\nvariable \"a\" {}\n",
+			sorted:   "variable \"a\" {}\n\nvariable \"b\" {}\n\nvariable \"c\" {}\n",
+			expected: []string{"variable.c 1 5 1-2 4-5", "variable.a 5 1 4-5 1-2"},
+		},
+		{
+			name:     "invalid content",
+			original: "variable \"b\" {\n",
+			sorted:   "variable \"b\" {}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, move := range detectMoves([]byte(tt.original), []byte(tt.sorted)) {
+				got = append(got, fmt.Sprintf("%s %d %d %d-%d %d-%d", move.address, move.from, move.to,
+					move.oldRange.start, move.oldRange.end, move.newRange.start, move.newRange.end))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("detectMoves() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestHeaviestIncreasing tests choosing the blocks that stay in place
+func TestHeaviestIncreasing(t *testing.T) {
+	tests := []struct {
+		values, weights []int
+		expected        string
+	}{
+		{nil, nil, ""},
+		{[]int{0, 1, 2}, []int{1, 1, 1}, "+++"},
+		{[]int{1, 0}, []int{1, 1}, "+-"},
+		{[]int{1, 0}, []int{1, 5}, "-+"},
+		{[]int{3, 0, 1, 2}, []int{2, 1, 1, 1}, "-+++"},
+		{[]int{3, 0, 1, 2}, []int{4, 1, 1, 1}, "+---"},
+	}
+
+	for _, tt := range tests {
+		var got strings.Builder
+		for _, in := range heaviestIncreasing(tt.values, tt.weights) {
+			if in {
+				got.WriteString("+")
+			} else {
+				got.WriteString("-")
+			}
+		}
+		if got.String() != tt.expected {
+			t.Errorf("heaviestIncreasing(%v, %v) = %q, want %q", tt.values, tt.weights, got.String(), tt.expected)
+		}
+	}
+}
+
+// TestWordDiff tests marking changed words within a line
+func TestWordDiff(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected string
+	}{
+		{`  ami = "ami-1"`, `  ami           = "ami-1"`, `  ami[- -]{+           +}= "ami-1"`},
+		{`  count = 1`, `  count = 2`, `  count = [-1-]{+2+}`},
+		{`  tags = {}`, `  tags = { Name = "web" }`, `  tags = {{+ Name = "web" +}}`},
+	}
+
+	for _, tt := range tests {
+		if got := wordDiff(tt.a, tt.b); got != tt.expected {
+			t.Errorf("wordDiff(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+// TestFitColumn tests padding and truncating side-by-side columns
+func TestFitColumn(t *testing.T) {
+	tests := []struct {
+		text     string
+		width    int
+		expected string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcde", 5, "abcde"},
+		{"abcdef", 5, "abcd…"},
+		{"\tx", 4, "  x "},
+		{"äöü", 4, "äöü "},
+	}
+
+	for _, tt := range tests {
+		if got := fitColumn(tt.text, tt.width); got != tt.expected {
+			t.Errorf("fitColumn(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.expected)
+		}
+	}
+}
+
+// TestDiffWidth tests where the width of side-by-side diffs comes from
+func TestDiffWidth(t *testing.T) {
+	var out bytes.Buffer
+
+	t.Setenv("COLUMNS", "")
+	if got := diffWidth(&config.Config{}, &out); got != defaultDiffWidth {
+		t.Errorf("diffWidth() = %d, want the default %d", got, defaultDiffWidth)
+	}
+
+	t.Setenv("COLUMNS", "132")
+	if got := diffWidth(&config.Config{}, &out); got != 132 {
+		t.Errorf("diffWidth() = %d, want 132 from COLUMNS", got)
+	}
+	if got := diffWidth(&config.Config{DiffWidth: 100}, &out); got != 100 {
+		t.Errorf("diffWidth() = %d, want 100 from --diff-width", got)
+	}
+}
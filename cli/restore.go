@@ -0,0 +1,67 @@
+package cli
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+
+	"github.com/obergerkatz/sortTF/api"
+	"github.com/obergerkatz/sortTF/config"
+	"github.com/obergerkatz/sortTF/internal/errors"
+)
+
+// runRestore executes the restore command: it puts back the original files
+// saved by a run with --backup-dir. Files edited since that run are left
+// alone unless --force is given. In dry-run mode nothing is written.
+// Returns an exit code suitable for os.Exit.
+func runRestore(args []string, stdout, stderr io.Writer) int {
+	cfg, err := config.ParseRestoreFlags(args, stderr)
+	if err != nil {
+		if err.Error() == "help" {
+			return 0
+		}
+		errors.PrintError(err, stderr)
+		return 2 // Usage error
+	}
+
+	opts := api.RestoreOptions{
+		Options: api.Options{DryRun: cfg.DryRun},
+		Force:   cfg.Force,
+	}
+	results, err := api.RestoreBackup(cfg.Dir, opts)
+	if err != nil {
+		errors.PrintError(errors.NewWithPath("restore", cfg.Dir, err), stderr)
+		return 1
+	}
+
+	restored, failed := 0, 0
+	for _, result := range results {
+		switch result.Status {
+		case api.StatusChanged:
+			restored++
+			_, _ = successColor.Fprintf(stdout, "♻️  Restored: %s\n", fileColor.Sprint(result.Path))
+		case api.StatusWouldChange:
+			restored++
+			_, _ = warningColor.Fprintf(stdout, "📝 Would restore: %s\n", fileColor.Sprint(result.Path))
+		case api.StatusUnchanged:
+			_, _ = successColor.Fprintf(stdout, "✅ Already restored: %s\n", fileColor.Sprint(result.Path))
+		default:
+			failed++
+			err := result.Err
+			if stderrors.Is(err, api.ErrModifiedSinceBackup) {
+				err = fmt.Errorf("%w (use --force to discard the changes)", err)
+			}
+			errors.PrintError(errors.NewWithPath("restore", result.Path, err), stderr)
+		}
+	}
+
+	if cfg.DryRun {
+		_, _ = infoColor.Fprintf(stdout, "📊 %d files would be restored\n", restored)
+	} else {
+		_, _ = infoColor.Fprintf(stdout, "📊 Restored %d files\n", restored)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
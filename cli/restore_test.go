@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const (
+	restoreUnsorted = "variable \"b\" {\n  type = string\n}\n\nvariable \"a\" {\n  type = string\n}\n"
+	restoreSorted   = "variable \"a\" {\n  type = string\n}\n\nvariable \"b\" {\n  type = string\n}\n"
+)
+
+// backupRun sorts dir with --backup-dir and returns the created backup directory.
+func backupRun(t *testing.T, dir string) string {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	backups := filepath.Join(dir, ".sorttf-backups")
+	if exitCode := RunCLIWithWriters([]string{"--recursive", "--backup-dir", backups, dir}, &stdout, &stderr); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	match := regexp.MustCompile(`Saved 1 original files in (\S+) `).FindStringSubmatch(stdout.String())
+	if match == nil {
+		t.Fatalf("Expected backup message, got: %s", stdout.String())
+	}
+	return match[1]
+}
+
+// TestRunRestore tests undoing a run with a backup directory
+func TestRunRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(restoreUnsorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := backupRun(t, tmpDir)
+	//nolint:gosec // G304: Test file path is controlled
+	if got, _ := os.ReadFile(testFile); string(got) != restoreSorted {
+		t.Fatalf("Expected main.tf to be sorted, got:\n%s", got)
+	}
+
+	// A second recursive run does not pick up the backups
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"--recursive", "--validate", tmpDir}, &stdout, &stderr); exitCode != 0 {
+		t.Errorf("Expected the backups to be ignored, got exit code %d: %s", exitCode, stdout.String())
+	}
+
+	stdout.Reset()
+	if exitCode := RunCLIWithWriters([]string{"restore", "--dry-run", backup}, &stdout, &stderr); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Would restore") || !strings.Contains(stdout.String(), "1 files would be restored") {
+		t.Errorf("Expected dry-run output, got: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	if exitCode := RunCLIWithWriters([]string{"restore", backup}, &stdout, &stderr); exitCode != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Restored: "+testFile) || !strings.Contains(stdout.String(), "Restored 1 files") {
+		t.Errorf("Expected restore output, got: %s", stdout.String())
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	if got, _ := os.ReadFile(testFile); string(got) != restoreUnsorted {
+		t.Errorf("Expected main.tf to be restored, got:\n%s", got)
+	}
+}
+
+// TestRunRestore_ModifiedSinceBackup tests that edits made after the run are kept
+func TestRunRestore_ModifiedSinceBackup(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "main.tf")
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(restoreUnsorted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := backupRun(t, tmpDir)
+	edited := restoreSorted + "\nvariable \"c\" {\n  type = string\n}\n"
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(testFile, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"restore", backup}, &stdout, &stderr); exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "modified after it was backed up (use --force") {
+		t.Errorf("Expected modification error, got: %s", stderr.String())
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	if got, _ := os.ReadFile(testFile); string(got) != edited {
+		t.Errorf("Expected the edit to be kept, got:\n%s", got)
+	}
+
+	stderr.Reset()
+	if exitCode := RunCLIWithWriters([]string{"restore", "--force", backup}, &stdout, &stderr); exitCode != 0 {
+		t.Errorf("Expected exit code 0 with --force, got %d: %s", exitCode, stderr.String())
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	if got, _ := os.ReadFile(testFile); string(got) != restoreUnsorted {
+		t.Errorf("Expected main.tf to be restored, got:\n%s", got)
+	}
+}
+
+// TestRunRestore_Errors tests usage errors and missing backups
+func TestRunRestore_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantErr  string
+	}{
+		{"help", []string{"restore", "--help"}, 0, ""},
+		{"no directory", []string{"restore"}, 2, "a backup directory is required"},
+		{"not a backup", []string{"restore", t.TempDir()}, 1, "read manifest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if exitCode := RunCLIWithWriters(tt.args, &stdout, &stderr); exitCode != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantCode, exitCode)
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("Expected %q in stderr, got: %s", tt.wantErr, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/obergerkatz/sortTF/api"
+	"github.com/obergerkatz/sortTF/config"
+	"github.com/obergerkatz/sortTF/hcl"
+	"github.com/obergerkatz/sortTF/internal/errors"
+)
+
+// runSplit executes the split command: it breaks one Terraform file into
+// several sorted files grouped by the configured rule. In dry-run and validate
+// modes the planned moves are printed but not applied.
+// Returns an exit code suitable for os.Exit.
+func runSplit(args []string, stdout, stderr io.Writer) int {
+	cfg, err := config.ParseSplitFlags(args, stderr)
+	if err != nil {
+		if err.Error() == "help" {
+			return 0
+		}
+		errors.PrintError(err, stderr)
+		return 2 // Usage error
+	}
+
+	opts := api.SplitOptions{
+		Options: apiOptions(&cfg.Config, stdout),
+		Rule:    splitRule(cfg),
+		Merge:   cfg.Merge,
+	}
+
+	result, err := api.SplitFile(cfg.Root, opts)
+	switch {
+	case stderrors.Is(err, api.ErrNoChanges):
+		_, _ = successColor.Fprintf(stdout, "✅ Nothing to split in %s\n", fileColor.Sprint(cfg.Root))
+		return 0
+	case stderrors.Is(err, api.ErrFileExists):
+		errors.PrintError(errors.NewWithPath("split", cfg.Root, fmt.Errorf("%w (use --merge to add blocks to it)", err)), stderr)
+		return 1
+	case err != nil && !stderrors.Is(err, api.ErrNeedsSorting):
+		if diagErr := diagnosticError(cfg.Root, err, cfg.DiagnosticWidth); diagErr != nil {
+			errors.PrintError(diagErr, stderr)
+		} else {
+			errors.PrintError(errors.NewWithPath("split", cfg.Root, err), stderr)
+		}
+		return 1
+	}
+
+	dir := result.Dir
+	for _, move := range result.Moves {
+		_, _ = fmt.Fprintf(stdout, "📦 %s: %s → %s\n", move.Address,
+			fileColor.Sprint(filepath.Join(dir, move.From)), fileColor.Sprint(filepath.Join(dir, move.To)))
+	}
+
+	if cfg.DryRun || cfg.Validate {
+		_, _ = infoColor.Fprintf(stdout, "📊 %d blocks would be moved\n", len(result.Moves))
+		if cfg.Validate && !cfg.DryRun {
+			return 1
+		}
+		return 0
+	}
+
+	for _, path := range result.Written {
+		_, _ = successColor.Fprintf(stdout, "✅ Updated: %s\n", fileColor.Sprint(path))
+	}
+	for _, path := range result.Removed {
+		_, _ = successColor.Fprintf(stdout, "🗑️  Removed empty file: %s\n", fileColor.Sprint(path))
+	}
+	_, _ = successColor.Fprintf(stdout, "✅ Moved %d blocks\n", len(result.Moves))
+	return 0
+}
+
+// splitRule returns the grouping rule selected by the split configuration.
+func splitRule(cfg *config.SplitConfig) hcl.SplitRule {
+	switch cfg.By {
+	case config.SplitByPrefix:
+		return hcl.SplitByResourcePrefix()
+	case config.SplitByRegex:
+		return hcl.SplitByLabelPattern(cfg.Pattern)
+	default:
+		return hcl.SplitByType()
+	}
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const splitContent = `resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+
+resource "aws_iam_role" "app" {
+  name = "app"
+}
+`
+
+// TestRunCLI_Split tests splitting a file by resource prefix
+func TestRunCLI_Split(t *testing.T) {
+	tmpDir := t.TempDir()
+	mainFile := filepath.Join(tmpDir, "main.tf")
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(mainFile, []byte(splitContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dry run prints the planned moves without changing anything
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"split", "--by", "prefix", "--dry-run", mainFile}, &stdout, &stderr); exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "resource.aws_iam_role.app") || !strings.Contains(stdout.String(), "would be moved") {
+		t.Errorf("Expected planned moves in output, got: %s", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "iam.tf")); !os.IsNotExist(err) {
+		t.Error("iam.tf should not be created in dry-run mode")
+	}
+
+	// Apply the split
+	stdout.Reset()
+	stderr.Reset()
+	if exitCode := RunCLIWithWriters([]string{"split", "--by", "prefix", mainFile}, &stdout, &stderr); exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr.String())
+	}
+	for _, name := range []string{"iam.tf", "s3.tf"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, name)); err != nil {
+			t.Errorf("Expected %s to be created: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(mainFile); !os.IsNotExist(err) {
+		t.Error("Expected emptied main.tf to be removed")
+	}
+}
+
+// TestRunCLI_SplitRefusesOverwrite tests that existing files require --merge
+func TestRunCLI_SplitRefusesOverwrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	mainFile := filepath.Join(tmpDir, "main.tf")
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(mainFile, []byte(splitContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	//nolint:gosec // G306: Test files can use 0644
+	if err := os.WriteFile(filepath.Join(tmpDir, "s3.tf"), []byte("resource \"aws_s3_bucket\" \"data\" {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"split", "--by", "prefix", mainFile}, &stdout, &stderr); exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "--merge") {
+		t.Errorf("Expected hint about --merge, got: %s", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if exitCode := RunCLIWithWriters([]string{"split", "--by", "prefix", "--merge", mainFile}, &stdout, &stderr); exitCode != 0 {
+		t.Errorf("Expected exit code 0 with --merge, got %d. Stderr: %s", exitCode, stderr.String())
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	s3, err := os.ReadFile(filepath.Join(tmpDir, "s3.tf"))
+	if err != nil || !strings.Contains(string(s3), `"data"`) || !strings.Contains(string(s3), `"logs"`) {
+		t.Errorf("Expected merged s3.tf, got: %s (err %v)", s3, err)
+	}
+}
+
+// TestRunCLI_SplitUsageError tests invalid split arguments
+func TestRunCLI_SplitUsageError(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if exitCode := RunCLIWithWriters([]string{"split", "--by", "regex", "main.tf"}, &stdout, &stderr); exitCode != 2 {
+		t.Errorf("Expected exit code 2, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "--pattern") {
+		t.Errorf("Expected pattern error, got: %s", stderr.String())
+	}
+}
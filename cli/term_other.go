@@ -0,0 +1,9 @@
+//go:build !unix
+
+package cli
+
+import "io"
+
+// terminalWidth returns 0 on systems where the terminal size is not queried,
+// so the COLUMNS environment variable or the default width is used.
+func terminalWidth(io.Writer) int { return 0 }
@@ -0,0 +1,26 @@
+//go:build unix
+
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTerminalWidth tests that writers other than terminals have no width
+func TestTerminalWidth(t *testing.T) {
+	if got := terminalWidth(&bytes.Buffer{}); got != 0 {
+		t.Errorf("terminalWidth(buffer) = %d, want 0", got)
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	if got := terminalWidth(f); got != 0 {
+		t.Errorf("terminalWidth(file) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/obergerkatz/sortTF/api"
+	"github.com/obergerkatz/sortTF/config"
+	"github.com/obergerkatz/sortTF/hcl"
+	"github.com/obergerkatz/sortTF/internal/errors"
+	"github.com/obergerkatz/sortTF/vfs"
+
+	"github.com/fatih/color"
+	hcllib "github.com/hashicorp/hcl/v2"
+)
+
+// stdin is where confirmation prompts read their answer from.
+var stdin io.Reader = os.Stdin
+
+// checkUnused reports the unused variables and local values of every module
+// directory containing discovered files as warnings. With --fix-unused it
+// deletes them after confirmation; in dry-run and validate modes it only
+// reports what would be removed. Unused declarations do not affect the exit
+// code. Returns the number of modules that could not be checked or fixed.
+func checkUnused(filePaths []string, config *config.Config, stdout, stderr io.Writer) int {
+	opts := apiOptions(config, stdout)
+	errorCount := 0
+	var unused []hcl.UnusedDeclaration
+	for _, dir := range moduleDirs(filePaths) {
+		found, err := api.FindUnused(dir, opts)
+		if err != nil {
+			// Files that fail to parse are reported by processing
+			if !hcl.IsHCLParseError(err) {
+				errorCount++
+				errors.PrintError(errors.New("unused", fmt.Errorf("failed to check module %s: %w", dir, err)), stderr)
+			}
+			continue
+		}
+		unused = append(unused, found...)
+	}
+
+	if len(unused) == 0 {
+		return errorCount
+	}
+
+	_, _ = warningColor.Fprintf(stderr, "⚠️  Found %d unused declarations:\n", len(unused))
+	_, _ = io.WriteString(stderr, renderUnused(opts.FS, unused, config.DiagnosticWidth))
+
+	if !config.FixUnused {
+		return errorCount
+	}
+	if config.DryRun || config.Validate {
+		_, _ = infoColor.Fprintf(stdout, "📊 %d unused declarations would be removed\n", len(unused))
+		return errorCount
+	}
+	if !config.Yes && !confirm(fmt.Sprintf("Delete %d unused declarations?", len(unused)), stdout) {
+		_, _ = infoColor.Fprintf(stdout, "ℹ️  Kept unused declarations\n")
+		return errorCount
+	}
+
+	written, err := api.RemoveUnused(unused, opts)
+	for _, path := range written {
+		_, _ = successColor.Fprintf(stdout, "🗑️  Removed unused declarations from %s\n", fileColor.Sprint(path))
+	}
+	if err != nil {
+		errorCount++
+		errors.PrintError(errors.New("unused", fmt.Errorf("failed to remove unused declarations: %w", err)), stderr)
+	}
+	return errorCount
+}
+
+// renderUnused renders the unused declarations as warnings with the source
+// snippets of their declarations, in color if the CLI's color mode allows it.
+// The declaring files are read from fsys, or from the operating system's file
+// system if it is nil.
+func renderUnused(fsys fs.FS, unused []hcl.UnusedDeclaration, width int) string {
+	if fsys == nil {
+		fsys = vfs.OSFS{}
+	}
+	diags := make(hcllib.Diagnostics, 0, len(unused))
+	sources := make(map[string][]byte)
+	for _, decl := range unused {
+		diags = append(diags, decl.Diagnostic())
+		if _, ok := sources[decl.Range.Filename]; !ok {
+			// A file that cannot be read is rendered without snippets
+			src, _ := fs.ReadFile(fsys, decl.Range.Filename)
+			sources[decl.Range.Filename] = src
+		}
+	}
+
+	var out bytes.Buffer
+	_ = hcl.WriteDiagnostics(&out, diags, sources, hcl.DiagnosticOptions{Width: width, Color: !color.NoColor})
+	return out.String()
+}
+
+// confirm asks a yes/no question on stdout and reads the answer from stdin.
+// Anything other than y or yes, including no answer at all, means no.
+func confirm(question string, stdout io.Writer) bool {
+	_, _ = fmt.Fprintf(stdout, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
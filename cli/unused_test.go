@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/obergerkatz/sortTF/api"
+	"github.com/obergerkatz/sortTF/vfs"
+)
+
+// TestRunCLI_Unused tests reporting and removing unused variables and locals
+func TestRunCLI_Unused(t *testing.T) {
+	const variables = "variable \"name\" {\n  type = string\n}\n\nvariable \"region\" {}\n"
+
+	tests := []struct {
+		name        string
+		args        []string
+		input       string
+		wantRemoved bool
+		wantStdout  string
+	}{
+		{
+			name:       "report only",
+			args:       []string{"--unused"},
+			wantStdout: "Processed",
+		},
+		{
+			name:        "fix with confirmation",
+			args:        []string{"--fix-unused"},
+			input:       "y\n",
+			wantRemoved: true,
+			wantStdout:  "Delete 1 unused declarations? [y/N]",
+		},
+		{
+			name:       "fix declined",
+			args:       []string{"--fix-unused"},
+			input:      "\n",
+			wantStdout: "Kept unused declarations",
+		},
+		{
+			name:        "fix without prompt",
+			args:        []string{"--fix-unused", "--yes"},
+			wantRemoved: true,
+			wantStdout:  "Removed unused declarations from",
+		},
+		{
+			name:       "fix in dry run",
+			args:       []string{"--fix-unused", "--yes", "--dry-run"},
+			wantStdout: "1 unused declarations would be removed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			for name, content := range map[string]string{
+				"main.tf":      "output \"name\" {\n  value = var.name\n}\n",
+				"variables.tf": variables,
+			} {
+				//nolint:gosec // G306: Test files can use 0644
+				if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			oldStdin := stdin
+			stdin = strings.NewReader(tt.input)
+			defer func() { stdin = oldStdin }()
+
+			var stdout, stderr bytes.Buffer
+			if exitCode := RunCLIWithWriters(append(tt.args, tmpDir), &stdout, &stderr); exitCode != 0 {
+				t.Errorf("Expected exit code 0, got %d. Stderr: %s", exitCode, stderr.String())
+			}
+
+			for _, want := range []string{
+				"Found 1 unused declarations",
+				"Warning: Unused variable",
+				"--> " + filepath.Join(tmpDir, "variables.tf") + ":5:1",
+			} {
+				if !strings.Contains(stderr.String(), want) {
+					t.Errorf("Expected %q in stderr, got: %s", want, stderr.String())
+				}
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("Expected %q in stdout, got: %s", tt.wantStdout, stdout.String())
+			}
+
+			content, err := os.ReadFile(filepath.Join(tmpDir, "variables.tf")) //nolint:gosec // G304: Test file path from t.TempDir()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed := !strings.Contains(string(content), "region"); removed != tt.wantRemoved {
+				t.Errorf("Expected removed=%v, got file:\n%s", tt.wantRemoved, content)
+			}
+		})
+	}
+}
+
+// TestRenderUnused_FS tests that the snippets of unused declarations are read
+// from the file system they were found in
+func TestRenderUnused_FS(t *testing.T) {
+	fsys := vfs.NewMemFS(map[string][]byte{
+		"vpc/variables.tf": []byte("variable \"region\" {}\n"),
+	})
+	unused, err := api.FindUnused("vpc", api.Options{FS: fsys})
+	if err != nil {
+		t.Fatalf("FindUnused() error = %v", err)
+	}
+
+	out := renderUnused(fsys, unused, 80)
+	for _, want := range []string{"--> vpc/variables.tf:1:1", `1 | variable "region" {}`, "^^^"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
@@ -9,8 +9,37 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/obergerkatz/sortTF/diff"
+)
+
+// Preset names accepted by the --preset flag.
+const (
+	// PresetDefault sorts and formats only.
+	PresetDefault = "default"
+	// PresetStyleGuide additionally applies the conventions of the
+	// Terraform style guide, such as sorting depends_on lists.
+	PresetStyleGuide = "style-guide"
+)
+
+// Diff styles accepted by the --diff-style flag.
+const (
+	// DiffStyleUnified prints unified diffs that git apply accepts.
+	DiffStyleUnified = "unified"
+	// DiffStyleSideBySide prints the original and sorted lines in two
+	// columns sized to the terminal.
+	DiffStyleSideBySide = "side-by-side"
+	// DiffStyleWords prints changed lines once, marking the changed words.
+	DiffStyleWords = "words"
 )
 
+// styleGuideSortLists are the set-like attributes sorted by the style-guide preset.
+var styleGuideSortLists = []string{"depends_on"}
+
 // Config holds the configuration for a sortTF execution.
 type Config struct {
 	// Root is the file or directory path to process.
@@ -28,6 +57,101 @@ type Config struct {
 	// Validate checks if files are sorted without modifying them.
 	// Exits with code 1 if changes are needed.
 	Validate bool
+
+	// CheckIdempotence sorts each sorted result again and fails if that
+	// changes it. It is on by default in validate mode.
+	CheckIdempotence bool
+
+	// DiffContext is the number of unchanged lines shown around each change
+	// in the diffs of dry-run and validate mode.
+	DiffContext int
+
+	// DiffStyle selects how diffs are printed: DiffStyleUnified,
+	// DiffStyleSideBySide or DiffStyleWords.
+	DiffStyle string
+
+	// DiffWidth is the width of side-by-side diffs in columns. Zero uses
+	// the width of the terminal.
+	DiffWidth int
+
+	// Patch is a file to write the changes of every file that would change
+	// to, as one patch in git format, in dry-run or validate mode.
+	Patch string
+
+	// DetectMoves describes top-level blocks that moved without changing
+	// in one line each instead of showing them removed and added.
+	DetectMoves bool
+
+	// Normalize rewrites legacy expression syntax (e.g., "${var.name}" wrappers
+	// and quoted type constraints) and reports each rewrite.
+	Normalize bool
+
+	// MaxLineWidth wraps collection constructors on lines longer than this
+	// many columns. Zero disables wrapping.
+	MaxLineWidth int
+
+	// Preset selects a bundle of defaults for the other options.
+	Preset string
+
+	// SortLists names attributes whose list values are sorted because
+	// they are semantically sets (e.g., depends_on).
+	SortLists []string
+
+	// Layout moves each top-level block in a module directory to its
+	// canonical file (variables.tf, outputs.tf, ...) instead of sorting files
+	// one at a time.
+	Layout bool
+
+	// LayoutMapping overrides the canonical file for block types in layout
+	// mode. An empty file name keeps blocks of that type where they are.
+	LayoutMapping map[string]string
+
+	// Collapse joins short multi-line collections onto one line when they fit
+	// within MaxLineWidth.
+	Collapse bool
+
+	// NoSchema disables the structural checks of core block bodies, such as
+	// an output without value, that run before sorting.
+	NoSchema bool
+
+	// DiagnosticWidth wraps the detail text of parse and validation errors
+	// at this many columns. Zero disables wrapping.
+	DiagnosticWidth int
+
+	// Unused reports variables and local values that are never referenced
+	// in their module as warnings.
+	Unused bool
+
+	// FixUnused deletes the unused variables and local values after
+	// confirmation. It implies Unused.
+	FixUnused bool
+
+	// Yes answers confirmation prompts with yes.
+	Yes bool
+
+	// Workers bounds how many files are processed at once. Zero uses one
+	// worker per CPU.
+	Workers int
+
+	// FileTimeout bounds the time spent on each file. Zero means no limit.
+	FileTimeout time.Duration
+
+	// Backup saves the original of each file it overwrites next to it, as
+	// <file>.orig.
+	Backup bool
+
+	// BackupDir saves the original of each file it overwrites in a new
+	// timestamped directory inside BackupDir, with a manifest that the
+	// restore command reads.
+	BackupDir string
+
+	// Atomic writes all files or none: nothing is written if any file
+	// fails, and files already written are restored if a later write fails.
+	Atomic bool
+
+	// FS is the file system files are sorted in. Nil means the operating
+	// system's. It has no flag; it is meant for tests and embedders.
+	FS fs.FS
 }
 
 // ParseFlags parses command line arguments and returns a Config.
@@ -46,10 +170,26 @@ func ParseFlags(args []string, stderr io.Writer) (*Config, error) {
 	fs.BoolVar(&config.DryRun, "dry-run", false, "Show what would be changed without writing (shows a unified diff)")
 	fs.BoolVar(&config.Verbose, "verbose", false, "Print detailed logs about which files were parsed, sorted, and formatted")
 	fs.BoolVar(&config.Validate, "validate", false, "Exit with a non-zero code if any files are not sorted/formatted")
+	finishDiffFlags := addDiffFlags(fs, &config)
+	fs.BoolVar(&config.CheckIdempotence, "check-idempotence", false, "Fail if sorting a sorted file again changes it (default true with --validate)")
+	finishSortFlags := addSortFlags(fs, &config)
+	addDiagnosticFlags(fs, &config)
+	fs.BoolVar(&config.Layout, "layout", false, "Move blocks to their canonical files (variables.tf, outputs.tf, ...) within each module directory")
+	layoutMap := fs.String("layout-map", "", "Comma-separated block=file overrides for --layout (e.g. resource=,output=outputs.tf)")
+	fs.BoolVar(&config.Unused, "unused", false, "Warn about variables and locals that are never referenced in their module")
+	fs.BoolVar(&config.FixUnused, "fix-unused", false, "Delete unused variables and locals after confirmation (implies --unused)")
+	fs.BoolVar(&config.Yes, "yes", false, "Do not ask for confirmation")
+	fs.IntVar(&config.Workers, "workers", 0, "Number of files to process at once (0 uses one per CPU)")
+	fs.DurationVar(&config.FileTimeout, "file-timeout", 0, "Give up on a file after this long, e.g. 30s (0 disables the limit)")
+	fs.BoolVar(&config.Backup, "backup", false, "Save the original of each file as <file>.orig before overwriting it")
+	fs.StringVar(&config.BackupDir, "backup-dir", "", "Save the originals in a timestamped directory inside this directory, for 'sorttf restore'")
+	fs.BoolVar(&config.Atomic, "atomic", false, "Write all files or none: write nothing if any file fails, and roll back if a write fails")
 
 	// Custom usage function
 	fs.Usage = func() {
 		_, _ = fmt.Fprintf(stderr, "Usage: sorttf [flags] [path]\n")
+		_, _ = fmt.Fprintf(stderr, "       sorttf split [flags] <file>\n")
+		_, _ = fmt.Fprintf(stderr, "       sorttf restore [flags] <backup>\n")
 		_, _ = fmt.Fprintf(stderr, "\nSort and format Terraform (.tf) and Terragrunt (.hcl) files for consistency and readability.\n")
 		_, _ = fmt.Fprintf(stderr, "\nPath can be a file or directory. If no path is provided, the current directory is used.\n")
 		_, _ = fmt.Fprintf(stderr, "\nFlags:\n")
@@ -67,6 +207,16 @@ func ParseFlags(args []string, stderr io.Writer) (*Config, error) {
 		_, _ = fmt.Fprintf(stderr, "  sorttf --recursive .        # Recursively process subdirectories\n")
 		_, _ = fmt.Fprintf(stderr, "  sorttf --validate .         # Check if files are properly sorted/formatted\n")
 		_, _ = fmt.Fprintf(stderr, "  sorttf --dry-run .          # Show what would change, with a unified diff\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf --dry-run --diff-style words --detect-moves . # Review changes word by word\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf --dry-run --patch sort.patch --recursive . # Write all changes to one patch for git apply\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf --normalize .        # Also rewrite legacy interpolation and type syntax\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf --max-line-width 100 . # Wrap long lists and objects at 100 columns\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf --preset style-guide . # Apply style guide conventions such as sorted depends_on\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf --layout --dry-run .   # Show which blocks would move to their canonical files\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf --unused .             # Warn about variables and locals that are never used\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf --backup-dir .sorttf-backups . # Keep the originals so the run can be undone\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf --atomic --recursive .  # Sort every file or, if one fails, none of them\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf split --by prefix main.tf # Split main.tf into iam.tf, s3.tf, ...\n")
 	}
 
 	if err := fs.Parse(args); err != nil {
@@ -77,6 +227,45 @@ func ParseFlags(args []string, stderr io.Writer) (*Config, error) {
 		return nil, fmt.Errorf("parseFlags: %w", err)
 	}
 
+	if err := finishSortFlags(); err != nil {
+		return nil, fmt.Errorf("parseFlags: %w", err)
+	}
+
+	if config.DiagnosticWidth < 0 {
+		return nil, fmt.Errorf("parseFlags: --diagnostic-width must not be negative")
+	}
+	if err := finishDiffFlags(); err != nil {
+		return nil, fmt.Errorf("parseFlags: %w", err)
+	}
+
+	if config.Workers < 0 {
+		return nil, fmt.Errorf("parseFlags: --workers must not be negative")
+	}
+	if config.FileTimeout < 0 {
+		return nil, fmt.Errorf("parseFlags: --file-timeout must not be negative")
+	}
+
+	if config.Backup && config.BackupDir != "" {
+		return nil, fmt.Errorf("parseFlags: --backup and --backup-dir cannot be combined")
+	}
+
+	if config.FixUnused {
+		config.Unused = true
+	}
+
+	// Validate mode checks idempotence unless told otherwise
+	if config.Validate && !isSet(fs, "check-idempotence") {
+		config.CheckIdempotence = true
+	}
+
+	if *layoutMap != "" {
+		mapping, err := parseMapping(*layoutMap)
+		if err != nil {
+			return nil, fmt.Errorf("parseFlags: --layout-map: %w", err)
+		}
+		config.LayoutMapping = mapping
+	}
+
 	// Get positional arguments
 	positionalArgs := fs.Args()
 	if len(positionalArgs) > 1 {
@@ -92,3 +281,110 @@ func ParseFlags(args []string, stderr io.Writer) (*Config, error) {
 
 	return &config, nil
 }
+
+// addSortFlags registers the flags that control the sorting pipeline on fs.
+// The returned function applies the preset and validates the values; call it
+// after fs.Parse.
+func addSortFlags(fs *flag.FlagSet, config *Config) func() error {
+	fs.BoolVar(&config.Normalize, "normalize", false, "Rewrite legacy syntax such as \"${var.name}\" wrappers and quoted type constraints")
+	fs.IntVar(&config.MaxLineWidth, "max-line-width", 0, "Wrap lists and objects on lines longer than this many columns (0 disables wrapping)")
+	fs.BoolVar(&config.Collapse, "collapse", false, "Join short multi-line lists and objects onto one line (requires --max-line-width)")
+	fs.StringVar(&config.Preset, "preset", PresetDefault, "Defaults to apply: \"default\" or \"style-guide\" (sorts depends_on lists)")
+	fs.BoolVar(&config.NoSchema, "no-schema", false, "Skip structural checks of block bodies (e.g. output without value, count with for_each)")
+	sortLists := fs.String("sort-lists", "", "Comma-separated attribute names whose list values are sorted (e.g. depends_on,security_group_ids)")
+
+	return func() error {
+		switch config.Preset {
+		case PresetDefault:
+		case PresetStyleGuide:
+			config.SortLists = slices.Clone(styleGuideSortLists)
+		default:
+			return fmt.Errorf("unknown preset %q (want %q or %q)", config.Preset, PresetDefault, PresetStyleGuide)
+		}
+
+		// An explicit --sort-lists overrides the preset, including --sort-lists=""
+		if isSet(fs, "sort-lists") {
+			config.SortLists = splitList(*sortLists)
+		}
+
+		if config.MaxLineWidth < 0 {
+			return fmt.Errorf("--max-line-width must not be negative")
+		}
+		return nil
+	}
+}
+
+// addDiffFlags registers the flags that control how the diffs of dry-run and
+// validate mode are printed on fs. The returned function validates the
+// values; call it after fs.Parse.
+func addDiffFlags(fs *flag.FlagSet, config *Config) func() error {
+	fs.IntVar(&config.DiffContext, "diff-context", diff.DefaultContext, "Number of unchanged lines shown around each change in diffs")
+	fs.StringVar(&config.DiffStyle, "diff-style", DiffStyleUnified, "How to print diffs: \"unified\", \"side-by-side\" or \"words\"")
+	fs.IntVar(&config.DiffWidth, "diff-width", 0, "Width of side-by-side diffs in columns (0 uses the terminal width)")
+	fs.BoolVar(&config.DetectMoves, "detect-moves", false, "Describe blocks that only moved in one line instead of showing them removed and added")
+	fs.StringVar(&config.Patch, "patch", "", "Write the changes of all files to this file as one patch for git apply (with --dry-run or --validate)")
+
+	return func() error {
+		switch config.DiffStyle {
+		case DiffStyleUnified, DiffStyleSideBySide, DiffStyleWords:
+		default:
+			return fmt.Errorf("unknown diff style %q (want %q, %q or %q)", config.DiffStyle, DiffStyleUnified, DiffStyleSideBySide, DiffStyleWords)
+		}
+		if config.DiffContext < 0 {
+			return fmt.Errorf("--diff-context must not be negative")
+		}
+		if config.DiffWidth < 0 {
+			return fmt.Errorf("--diff-width must not be negative")
+		}
+		if config.Patch != "" && !config.DryRun && !config.Validate {
+			return fmt.Errorf("--patch requires --dry-run or --validate")
+		}
+		if config.Patch != "" && config.Layout {
+			return fmt.Errorf("--patch cannot be combined with --layout")
+		}
+		return nil
+	}
+}
+
+// addDiagnosticFlags registers the flags that control how parse and
+// validation errors are rendered on fs.
+func addDiagnosticFlags(fs *flag.FlagSet, config *Config) {
+	fs.IntVar(&config.DiagnosticWidth, "diagnostic-width", 0, "Wrap the detail text of parse and validation errors at this many columns (0 disables wrapping)")
+}
+
+// isSet reports whether the named flag was given on the command line.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// splitList splits a comma-separated flag value into its non-empty, trimmed items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseMapping parses a comma-separated list of key=value pairs.
+// Values may be empty; keys may not.
+func parseMapping(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, item := range splitList(value) {
+		key, val, ok := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected block=file", item)
+		}
+		mapping[key] = strings.TrimSpace(val)
+	}
+	return mapping, nil
+}
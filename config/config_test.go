@@ -2,8 +2,10 @@ package config
 
 import (
 	"bytes"
+	"io"
 	"strings"
 	"testing"
+	"time"
 )
 
 func assertConfigEqual(t *testing.T, got, want *Config) {
@@ -18,9 +20,31 @@ func assertConfigEqual(t *testing.T, got, want *Config) {
 		got.Recursive != want.Recursive ||
 		got.DryRun != want.DryRun ||
 		got.Verbose != want.Verbose ||
-		got.Validate != want.Validate {
+		got.Validate != want.Validate ||
+		got.CheckIdempotence != want.CheckIdempotence ||
+		got.Normalize != want.Normalize ||
+		got.MaxLineWidth != want.MaxLineWidth ||
+		got.Collapse != want.Collapse ||
+		got.DiagnosticWidth != want.DiagnosticWidth ||
+		got.NoSchema != want.NoSchema ||
+		got.Unused != want.Unused ||
+		got.FixUnused != want.FixUnused ||
+		got.Yes != want.Yes ||
+		got.Workers != want.Workers ||
+		got.FileTimeout != want.FileTimeout ||
+		got.Backup != want.Backup ||
+		got.BackupDir != want.BackupDir ||
+		got.Atomic != want.Atomic ||
+		strings.Join(got.SortLists, ",") != strings.Join(want.SortLists, ",") ||
+		got.Layout != want.Layout ||
+		len(got.LayoutMapping) != len(want.LayoutMapping) {
 		t.Errorf("Config: got %+v, want %+v", got, want)
 	}
+	for key, value := range want.LayoutMapping {
+		if got.LayoutMapping[key] != value {
+			t.Errorf("LayoutMapping[%q]: got %q, want %q", key, got.LayoutMapping[key], value)
+		}
+	}
 }
 
 func TestParseFlags_TableDriven(t *testing.T) {
@@ -44,7 +68,7 @@ func TestParseFlags_TableDriven(t *testing.T) {
 		{
 			name: "with flags",
 			args: []string{"--recursive", "--dry-run", "--verbose", "--validate", "/test/dir"},
-			want: &Config{Root: "/test/dir", Recursive: true, DryRun: true, Verbose: true, Validate: true},
+			want: &Config{Root: "/test/dir", Recursive: true, DryRun: true, Verbose: true, Validate: true, CheckIdempotence: true},
 		},
 		{
 			name:    "too many args",
@@ -69,6 +93,158 @@ func TestParseFlags_TableDriven(t *testing.T) {
 			args: []string{"--dry-run", "--verbose", "/foo"},
 			want: &Config{Root: "/foo", Recursive: false, DryRun: true, Verbose: true, Validate: false},
 		},
+		{
+			name: "normalize flag",
+			args: []string{"--normalize", "."},
+			want: &Config{Root: ".", Normalize: true},
+		},
+		{
+			name: "line width flags",
+			args: []string{"--max-line-width", "100", "--collapse", "."},
+			want: &Config{Root: ".", MaxLineWidth: 100, Collapse: true},
+		},
+		{
+			name: "no schema",
+			args: []string{"--no-schema", "."},
+			want: &Config{Root: ".", NoSchema: true},
+		},
+		{
+			name: "diagnostic width",
+			args: []string{"--diagnostic-width", "80", "."},
+			want: &Config{Root: ".", DiagnosticWidth: 80},
+		},
+		{
+			name:    "negative diff context",
+			args:    []string{"--diff-context=-1", "."},
+			wantErr: true,
+			errMsg:  "--diff-context must not be negative",
+		},
+		{
+			name:    "unknown diff style",
+			args:    []string{"--diff-style", "fancy", "."},
+			wantErr: true,
+			errMsg:  "unknown diff style",
+		},
+		{
+			name:    "patch without dry run",
+			args:    []string{"--patch", "out.patch", "."},
+			wantErr: true,
+			errMsg:  "--patch requires --dry-run or --validate",
+		},
+		{
+			name:    "patch with layout",
+			args:    []string{"--dry-run", "--layout", "--patch", "out.patch", "."},
+			wantErr: true,
+			errMsg:  "--patch cannot be combined with --layout",
+		},
+		{
+			name:    "negative diff width",
+			args:    []string{"--diff-width=-1", "."},
+			wantErr: true,
+			errMsg:  "--diff-width must not be negative",
+		},
+		{
+			name:    "negative diagnostic width",
+			args:    []string{"--diagnostic-width=-1", "."},
+			wantErr: true,
+			errMsg:  "--diagnostic-width must not be negative",
+		},
+		{
+			name: "unused",
+			args: []string{"--unused", "."},
+			want: &Config{Root: ".", Unused: true},
+		},
+		{
+			name: "fix unused implies unused",
+			args: []string{"--fix-unused", "--yes", "."},
+			want: &Config{Root: ".", Unused: true, FixUnused: true, Yes: true},
+		},
+		{
+			name: "workers and file timeout",
+			args: []string{"--workers", "4", "--file-timeout", "30s", "."},
+			want: &Config{Root: ".", Workers: 4, FileTimeout: 30 * time.Second},
+		},
+		{
+			name: "backup",
+			args: []string{"--backup", "."},
+			want: &Config{Root: ".", Backup: true},
+		},
+		{
+			name: "backup directory",
+			args: []string{"--backup-dir", ".sorttf-backups", "."},
+			want: &Config{Root: ".", BackupDir: ".sorttf-backups"},
+		},
+		{
+			name: "check idempotence",
+			args: []string{"--check-idempotence", "."},
+			want: &Config{Root: ".", CheckIdempotence: true},
+		},
+		{
+			name: "validate without idempotence check",
+			args: []string{"--validate", "--check-idempotence=false", "."},
+			want: &Config{Root: ".", Validate: true},
+		},
+		{
+			name: "atomic",
+			args: []string{"--atomic", "."},
+			want: &Config{Root: ".", Atomic: true},
+		},
+		{
+			name:    "backup and backup directory",
+			args:    []string{"--backup", "--backup-dir", ".sorttf-backups", "."},
+			wantErr: true,
+			errMsg:  "--backup and --backup-dir cannot be combined",
+		},
+		{
+			name:    "negative workers",
+			args:    []string{"--workers=-1", "."},
+			wantErr: true,
+			errMsg:  "--workers must not be negative",
+		},
+		{
+			name:    "negative file timeout",
+			args:    []string{"--file-timeout=-1s", "."},
+			wantErr: true,
+			errMsg:  "--file-timeout must not be negative",
+		},
+		{
+			name:    "negative line width",
+			args:    []string{"--max-line-width=-1", "."},
+			wantErr: true,
+			errMsg:  "must not be negative",
+		},
+		{
+			name: "style-guide preset sorts depends_on",
+			args: []string{"--preset", "style-guide", "."},
+			want: &Config{Root: ".", SortLists: []string{"depends_on"}},
+		},
+		{
+			name: "sort-lists overrides preset",
+			args: []string{"--preset", "style-guide", "--sort-lists", "security_group_ids, cidr_blocks", "."},
+			want: &Config{Root: ".", SortLists: []string{"security_group_ids", "cidr_blocks"}},
+		},
+		{
+			name: "empty sort-lists disables preset default",
+			args: []string{"--sort-lists=", "--preset=style-guide", "."},
+			want: &Config{Root: "."},
+		},
+		{
+			name:    "unknown preset",
+			args:    []string{"--preset", "fancy", "."},
+			wantErr: true,
+			errMsg:  "unknown preset",
+		},
+		{
+			name: "layout with mapping",
+			args: []string{"--layout", "--layout-map", "resource=main.tf, output=", "."},
+			want: &Config{Root: ".", Layout: true, LayoutMapping: map[string]string{"resource": "main.tf", "output": ""}},
+		},
+		{
+			name:    "invalid layout mapping",
+			args:    []string{"--layout-map", "resource", "."},
+			wantErr: true,
+			errMsg:  "expected block=file",
+		},
 		{
 			name: "path with spaces",
 			args: []string{"my dir/file.tf"},
@@ -103,6 +279,59 @@ func TestParseFlags_TableDriven(t *testing.T) {
 	}
 }
 
+// TestParseFlags_DiffFlags tests the flags that control how diffs are printed
+func TestParseFlags_DiffFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		context     int
+		style       string
+		width       int
+		detectMoves bool
+		patch       string
+	}{
+		{"defaults", []string{"."}, 3, DiffStyleUnified, 0, false, ""},
+		{"no context", []string{"--diff-context", "0", "."}, 0, DiffStyleUnified, 0, false, ""},
+		{"side by side", []string{"--diff-style", "side-by-side", "--diff-width=120", "."}, 3, DiffStyleSideBySide, 120, false, ""},
+		{"words with moves", []string{"--diff-style=words", "--detect-moves", "--diff-context=10", "."}, 10, DiffStyleWords, 0, true, ""},
+		{"patch", []string{"--dry-run", "--patch", "out.patch", "."}, 3, DiffStyleUnified, 0, false, "out.patch"},
+		{"patch in validate mode", []string{"--validate", "--patch=fix.patch", "."}, 3, DiffStyleUnified, 0, false, "fix.patch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseFlags(tt.args, io.Discard)
+			if err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+			if config.DiffContext != tt.context || config.DiffStyle != tt.style ||
+				config.DiffWidth != tt.width || config.DetectMoves != tt.detectMoves || config.Patch != tt.patch {
+				t.Errorf("Got context %d, style %q, width %d, detect moves %v, patch %q; want %d, %q, %d, %v, %q",
+					config.DiffContext, config.DiffStyle, config.DiffWidth, config.DetectMoves, config.Patch,
+					tt.context, tt.style, tt.width, tt.detectMoves, tt.patch)
+			}
+		})
+	}
+}
+
+// TestParseFlags_StyleGuidePresetCopy tests that changing the SortLists of one
+// configuration does not change the preset for the next one
+func TestParseFlags_StyleGuidePresetCopy(t *testing.T) {
+	first, err := ParseFlags([]string{"--preset", "style-guide", "."}, io.Discard)
+	if err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	first.SortLists[0] = "changed"
+
+	second, err := ParseFlags([]string{"--preset", "style-guide", "."}, io.Discard)
+	if err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	if len(second.SortLists) != 1 || second.SortLists[0] != "depends_on" {
+		t.Errorf("Expected SortLists [depends_on], got %v", second.SortLists)
+	}
+}
+
 func TestParseFlags_StderrUsage(t *testing.T) {
 	var stderr bytes.Buffer
 	_, err := ParseFlags([]string{"--help"}, &stderr)
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// RestoreConfig holds the configuration for the restore command.
+type RestoreConfig struct {
+	// Dir is the backup directory created by --backup-dir to restore from.
+	Dir string
+
+	// DryRun checks which files would be restored without writing them.
+	DryRun bool
+
+	// Force restores files that were modified after the backup was taken,
+	// discarding those modifications.
+	Force bool
+}
+
+// ParseRestoreFlags parses the arguments of the restore command, without the
+// command name itself, and returns a RestoreConfig.
+//
+// Like ParseFlags, it returns an error with message "help" if the -help flag
+// was requested. The stderr writer is used to display help text.
+func ParseRestoreFlags(args []string, stderr io.Writer) (*RestoreConfig, error) {
+	fs := flag.NewFlagSet("sorttf restore", flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // Suppress default error output
+
+	var config RestoreConfig
+
+	fs.BoolVar(&config.DryRun, "dry-run", false, "Show which files would be restored without writing")
+	fs.BoolVar(&config.Force, "force", false, "Also restore files that were modified after the backup, discarding those changes")
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "Usage: sorttf restore [flags] <backup>\n")
+		_, _ = fmt.Fprintf(stderr, "\nPut back the original files saved by a run with --backup-dir.\n")
+		_, _ = fmt.Fprintf(stderr, "\nFiles that were edited since that run are left alone unless --force is given.\n")
+		_, _ = fmt.Fprintf(stderr, "\nFlags:\n")
+
+		var flagOutput bytes.Buffer
+		fs.SetOutput(&flagOutput)
+		fs.PrintDefaults()
+		fs.SetOutput(io.Discard)
+
+		_, _ = fmt.Fprintf(stderr, "%s", flagOutput.String())
+		_, _ = fmt.Fprintf(stderr, "\nExamples:\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf restore .sorttf-backups/20240102-150405            # Undo a run\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf restore --dry-run .sorttf-backups/20240102-150405  # Check what would be restored\n")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if err.Error() == "flag: help requested" {
+			return nil, fmt.Errorf("help")
+		}
+		return nil, fmt.Errorf("parseRestoreFlags: %w", err)
+	}
+
+	positionalArgs := fs.Args()
+	switch len(positionalArgs) {
+	case 0:
+		return nil, fmt.Errorf("parseRestoreFlags: a backup directory is required")
+	case 1:
+		config.Dir = positionalArgs[0]
+	default:
+		return nil, fmt.Errorf("parseRestoreFlags: too many arguments provided")
+	}
+
+	return &config, nil
+}
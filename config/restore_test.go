@@ -0,0 +1,79 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestParseRestoreFlags tests parsing of the restore command's arguments
+func TestParseRestoreFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    RestoreConfig
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			args: []string{"backups/20240102-150405"},
+			want: RestoreConfig{Dir: "backups/20240102-150405"},
+		},
+		{
+			name: "dry run and force",
+			args: []string{"--dry-run", "--force", "backups/20240102-150405"},
+			want: RestoreConfig{Dir: "backups/20240102-150405", DryRun: true, Force: true},
+		},
+		{
+			name:    "missing directory",
+			args:    []string{"--force"},
+			wantErr: "a backup directory is required",
+		},
+		{
+			name:    "too many directories",
+			args:    []string{"a", "b"},
+			wantErr: "too many arguments",
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"--recursive", "a"},
+			wantErr: "flag provided but not defined",
+		},
+		{
+			name:    "help",
+			args:    []string{"--help"},
+			wantErr: "help",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			got, err := ParseRestoreFlags(tt.args, &stderr)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseRestoreFlags() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRestoreFlags() unexpected error: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("RestoreConfig: got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseRestoreFlags_StderrUsage tests the restore command's usage message
+func TestParseRestoreFlags_StderrUsage(t *testing.T) {
+	var stderr bytes.Buffer
+	if _, err := ParseRestoreFlags([]string{"--help"}, &stderr); err == nil || err.Error() != "help" {
+		t.Errorf("Expected help error, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "Usage: sorttf restore") {
+		t.Errorf("Expected usage message in stderr, got: %s", stderr.String())
+	}
+}
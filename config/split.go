@@ -0,0 +1,120 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// Grouping rules accepted by the split command's --by flag.
+const (
+	// SplitByType groups blocks by block type (variables.tf, resources.tf, ...).
+	SplitByType = "type"
+	// SplitByPrefix groups resource and data blocks by resource type prefix
+	// (aws_iam_* → iam.tf).
+	SplitByPrefix = "prefix"
+	// SplitByRegex groups blocks by a label regular expression given with --pattern.
+	SplitByRegex = "regex"
+)
+
+// SplitConfig holds the configuration for the split command.
+type SplitConfig struct {
+	// Config holds the sorting options applied to every resulting file.
+	// Root is the file to split.
+	Config
+
+	// By is the grouping rule: SplitByType, SplitByPrefix, or SplitByRegex.
+	By string
+
+	// Pattern is the label regular expression for SplitByRegex. The first
+	// capture group, or the whole match, names the destination file.
+	Pattern *regexp.Regexp
+
+	// Merge allows blocks to be added to destination files that already exist.
+	Merge bool
+}
+
+// ParseSplitFlags parses the arguments of the split command, without the
+// command name itself, and returns a SplitConfig.
+//
+// Like ParseFlags, it returns an error with message "help" if the -help flag
+// was requested. The stderr writer is used to display help text.
+func ParseSplitFlags(args []string, stderr io.Writer) (*SplitConfig, error) {
+	fs := flag.NewFlagSet("sorttf split", flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // Suppress default error output
+
+	var config SplitConfig
+
+	fs.StringVar(&config.By, "by", SplitByType, "Grouping rule: \"type\", \"prefix\" (aws_iam_* → iam.tf), or \"regex\" (requires --pattern)")
+	pattern := fs.String("pattern", "", "Regular expression matched against block labels joined with \".\"; the first capture group names the file")
+	fs.BoolVar(&config.Merge, "merge", false, "Add blocks to destination files that already exist instead of refusing")
+	fs.BoolVar(&config.DryRun, "dry-run", false, "Show which blocks would move without writing")
+	fs.BoolVar(&config.Validate, "validate", false, "Exit with a non-zero code if the file would be split")
+	finishSortFlags := addSortFlags(fs, &config.Config)
+	addDiagnosticFlags(fs, &config.Config)
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "Usage: sorttf split [flags] <file>\n")
+		_, _ = fmt.Fprintf(stderr, "\nSplit a Terraform file into several sorted files in the same directory.\n")
+		_, _ = fmt.Fprintf(stderr, "\nFlags:\n")
+
+		var flagOutput bytes.Buffer
+		fs.SetOutput(&flagOutput)
+		fs.PrintDefaults()
+		fs.SetOutput(io.Discard)
+
+		_, _ = fmt.Fprintf(stderr, "%s", flagOutput.String())
+		_, _ = fmt.Fprintf(stderr, "\nExamples:\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf split main.tf                    # One file per block type\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf split --by prefix main.tf        # aws_iam_* → iam.tf, aws_s3_* → s3.tf\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf split --by regex --pattern '^aws_(\\w+?)_' main.tf\n")
+		_, _ = fmt.Fprintf(stderr, "  sorttf split --merge --dry-run main.tf  # Preview merging into existing files\n")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if err.Error() == "flag: help requested" {
+			return nil, fmt.Errorf("help")
+		}
+		return nil, fmt.Errorf("parseSplitFlags: %w", err)
+	}
+
+	if err := finishSortFlags(); err != nil {
+		return nil, fmt.Errorf("parseSplitFlags: %w", err)
+	}
+
+	if config.DiagnosticWidth < 0 {
+		return nil, fmt.Errorf("parseSplitFlags: --diagnostic-width must not be negative")
+	}
+
+	switch config.By {
+	case SplitByType, SplitByPrefix:
+		if *pattern != "" {
+			return nil, fmt.Errorf("parseSplitFlags: --pattern requires --by %s", SplitByRegex)
+		}
+	case SplitByRegex:
+		if *pattern == "" {
+			return nil, fmt.Errorf("parseSplitFlags: --by %s requires --pattern", SplitByRegex)
+		}
+		re, err := regexp.Compile(*pattern)
+		if err != nil {
+			return nil, fmt.Errorf("parseSplitFlags: --pattern: %w", err)
+		}
+		config.Pattern = re
+	default:
+		return nil, fmt.Errorf("parseSplitFlags: unknown rule %q (want %q, %q or %q)", config.By, SplitByType, SplitByPrefix, SplitByRegex)
+	}
+
+	positionalArgs := fs.Args()
+	switch len(positionalArgs) {
+	case 0:
+		return nil, fmt.Errorf("parseSplitFlags: a file to split is required")
+	case 1:
+		config.Root = positionalArgs[0]
+	default:
+		return nil, fmt.Errorf("parseSplitFlags: too many arguments provided")
+	}
+
+	return &config, nil
+}
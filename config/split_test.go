@@ -0,0 +1,128 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestParseSplitFlags tests parsing of the split command's arguments
+func TestParseSplitFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantBy      string
+		wantPattern string
+		wantMerge   bool
+		wantDryRun  bool
+		wantErr     string
+	}{
+		{
+			name:   "defaults",
+			args:   []string{"main.tf"},
+			wantBy: SplitByType,
+		},
+		{
+			name:       "prefix with merge and dry run",
+			args:       []string{"--by", "prefix", "--merge", "--dry-run", "main.tf"},
+			wantBy:     SplitByPrefix,
+			wantMerge:  true,
+			wantDryRun: true,
+		},
+		{
+			name:        "regex",
+			args:        []string{"--by", "regex", "--pattern", `^aws_(\w+?)_`, "main.tf"},
+			wantBy:      SplitByRegex,
+			wantPattern: `^aws_(\w+?)_`,
+		},
+		{
+			name:    "regex without pattern",
+			args:    []string{"--by", "regex", "main.tf"},
+			wantErr: "requires --pattern",
+		},
+		{
+			name:    "pattern without regex",
+			args:    []string{"--pattern", "x", "main.tf"},
+			wantErr: "--pattern requires --by regex",
+		},
+		{
+			name:    "invalid pattern",
+			args:    []string{"--by", "regex", "--pattern", "(", "main.tf"},
+			wantErr: "--pattern",
+		},
+		{
+			name:    "unknown rule",
+			args:    []string{"--by", "size", "main.tf"},
+			wantErr: "unknown rule",
+		},
+		{
+			name:    "missing file",
+			args:    []string{"--by", "type"},
+			wantErr: "file to split is required",
+		},
+		{
+			name:    "too many files",
+			args:    []string{"a.tf", "b.tf"},
+			wantErr: "too many arguments",
+		},
+		{
+			name:    "help",
+			args:    []string{"--help"},
+			wantErr: "help",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			got, err := ParseSplitFlags(tt.args, &stderr)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseSplitFlags() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSplitFlags() unexpected error: %v", err)
+			}
+
+			if got.By != tt.wantBy || got.Merge != tt.wantMerge || got.DryRun != tt.wantDryRun {
+				t.Errorf("SplitConfig: got %+v", got)
+			}
+			if got.Root != "main.tf" {
+				t.Errorf("Root: got %q, want %q", got.Root, "main.tf")
+			}
+			gotPattern := ""
+			if got.Pattern != nil {
+				gotPattern = got.Pattern.String()
+			}
+			if gotPattern != tt.wantPattern {
+				t.Errorf("Pattern: got %q, want %q", gotPattern, tt.wantPattern)
+			}
+		})
+	}
+}
+
+// TestParseSplitFlags_SortFlags tests that sorting flags apply to split too
+func TestParseSplitFlags_SortFlags(t *testing.T) {
+	var stderr bytes.Buffer
+	got, err := ParseSplitFlags([]string{"--preset", "style-guide", "--normalize", "main.tf"}, &stderr)
+	if err != nil {
+		t.Fatalf("ParseSplitFlags() unexpected error: %v", err)
+	}
+	if !got.Normalize || strings.Join(got.SortLists, ",") != "depends_on" {
+		t.Errorf("expected style-guide preset and normalize, got %+v", got.Config)
+	}
+}
+
+// TestParseSplitFlags_StderrUsage tests the split command's usage message
+func TestParseSplitFlags_StderrUsage(t *testing.T) {
+	var stderr bytes.Buffer
+	if _, err := ParseSplitFlags([]string{"--help"}, &stderr); err == nil || err.Error() != "help" {
+		t.Errorf("Expected help error, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "Usage: sorttf split") {
+		t.Errorf("Expected usage message in stderr, got: %s", stderr.String())
+	}
+}
@@ -0,0 +1,270 @@
+// Package diff computes line-level differences between two texts and renders
+// them as unified diffs with hunks, like diff -u and git diff.
+//
+// It is used by the api package to describe what sorting changed in a file
+// and by the CLI to render those changes.
+package diff
+
+import "strings"
+
+// Op is the kind of a line in a diff.
+type Op int
+
+// Line operations.
+const (
+	// Equal marks a line present in both texts.
+	Equal Op = iota
+	// Delete marks a line present only in the original text.
+	Delete
+	// Insert marks a line present only in the new text.
+	Insert
+)
+
+// String returns the unified diff prefix of the operation: " ", "-" or "+".
+func (op Op) String() string {
+	switch op {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// Line is one line of a diff, without its line break.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines returns the line-level differences that turn a into b, as a shortest
+// sequence of deletions and insertions interleaved with the unchanged lines.
+// A trailing line break does not produce an empty last line.
+func Lines(a, b string) []Line {
+	return diffLines(SplitLines(a), SplitLines(b))
+}
+
+// diffLines returns the differences that turn the lines a into b, listing
+// deletions before insertions between unchanged lines. The common prefix and
+// suffix are split off first, the rest is diffed by number, see intern.
+func diffLines(linesA, linesB []string) []Line {
+	prefix := 0
+	for prefix < len(linesA) && prefix < len(linesB) && linesA[prefix] == linesB[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(linesA)-prefix && suffix < len(linesB)-prefix &&
+		linesA[len(linesA)-1-suffix] == linesB[len(linesB)-1-suffix] {
+		suffix++
+	}
+
+	result := make([]Line, 0, max(len(linesA), len(linesB)))
+	for _, text := range linesA[:prefix] {
+		result = append(result, Line{Op: Equal, Text: text})
+	}
+
+	textA, textB := linesA[prefix:len(linesA)-suffix], linesB[prefix:len(linesB)-suffix]
+	idsA, idsB := intern(textA, textB)
+	d := differ{a: idsA, b: idsB, textA: textA, textB: textB, result: result}
+	d.compare(0, len(idsA), 0, len(idsB))
+	result = d.result
+	deletionsFirst(result[prefix:])
+
+	for _, text := range linesA[len(linesA)-suffix:] {
+		result = append(result, Line{Op: Equal, Text: text})
+	}
+	return result
+}
+
+// intern numbers the distinct lines of a and b, so that equal lines get the
+// same number and compare faster, and returns both as numbers.
+func intern(a, b []string) ([]int, []int) {
+	ids := make(map[string]int)
+	number := func(lines []string) []int {
+		numbers := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			numbers[i] = id
+		}
+		return numbers
+	}
+	return number(a), number(b)
+}
+
+// deletionsFirst moves the deletions of each run of changes in lines before
+// its insertions, keeping their order otherwise. The halves of a split may
+// leave an insertion before a deletion.
+func deletionsFirst(lines []Line) {
+	var inserts []Line
+	for start := 0; start < len(lines); {
+		if lines[start].Op == Equal {
+			start++
+			continue
+		}
+		end, deletes := start, start
+		inserts = inserts[:0]
+		for ; end < len(lines) && lines[end].Op != Equal; end++ {
+			if lines[end].Op == Delete {
+				lines[deletes] = lines[end]
+				deletes++
+			} else {
+				inserts = append(inserts, lines[end])
+			}
+		}
+		copy(lines[deletes:end], inserts)
+		start = end
+	}
+}
+
+// differ diffs two sequences of line numbers with the linear space variant
+// of Myers' O(ND) algorithm, appending the lines of the diff to result.
+type differ struct {
+	a, b         []int    // Line numbers of both texts
+	textA, textB []string // The lines themselves
+	result       []Line
+}
+
+// compare appends the diff of a[aLo:aHi] and b[bLo:bHi] to the result. The
+// common prefix and suffix are split off, the rest is split at the middle of
+// a shortest path and each side is compared on its own.
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.result = append(d.result, Line{Op: Equal, Text: d.textA[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-1-suffix] == d.b[bHi-1-suffix] {
+		suffix++
+	}
+
+	switch {
+	case aLo == aHi-suffix:
+		for _, text := range d.textB[bLo : bHi-suffix] {
+			d.result = append(d.result, Line{Op: Insert, Text: text})
+		}
+	case bLo == bHi-suffix:
+		for _, text := range d.textA[aLo : aHi-suffix] {
+			d.result = append(d.result, Line{Op: Delete, Text: text})
+		}
+	default:
+		x, y := d.middle(aLo, aHi-suffix, bLo, bHi-suffix)
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi-suffix, y, bHi-suffix)
+	}
+
+	for _, text := range d.textA[aHi-suffix : aHi] {
+		d.result = append(d.result, Line{Op: Equal, Text: text})
+	}
+}
+
+// middle returns a point on a shortest path from the start of a[aLo:aHi]
+// and b[bLo:bHi] to their end, at about half of its length, where the
+// searches from both ends meet. Both ranges must be non-empty and differ in
+// their first and last lines, so that both halves are smaller than the whole.
+//
+// forward[k] is the furthest x reached from the start on diagonal k = x - y,
+// backward[k] the furthest distance from the end reached on diagonal k of
+// the reversed ranges. Only the current round is kept, so memory grows with
+// the lengths rather than with the number of differences.
+func (d *differ) middle(aLo, aHi, bLo, bHi int) (int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	// Diagonal k forward meets diagonal delta-k backward. Which search can
+	// reach the meeting point first depends on the parity of delta
+	delta := n - m
+	odd := delta%2 != 0
+
+	// Diagonals that left the ranges are not searched again
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for step := 0; step < maxD; step++ {
+		for k := -step + fStart; k <= step-fEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				if i := offset + delta - k; i >= 0 && i < len(backward) && backward[i] != -1 && x >= n-backward[i] {
+					return aLo + x, bLo + y
+				}
+			}
+		}
+
+		for k := -step + bStart; k <= step-bEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				if i := offset + delta - k; i >= 0 && i < len(forward) && forward[i] != -1 && forward[i] >= n-x {
+					fx := forward[i]
+					return aLo + fx, bLo + fx - (delta - k)
+				}
+			}
+		}
+	}
+
+	// The ranges have nothing in common: delete all of a, then insert all of b
+	return aLo + n, bLo
+}
+
+// SplitLines splits text into lines without their line breaks. A trailing
+// line break does not produce an empty last line, and an empty text has no
+// lines.
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Changed reports whether the diff contains any insertion or deletion.
+func Changed(lines []Line) bool {
+	for _, line := range lines {
+		if line.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
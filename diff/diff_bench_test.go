@@ -0,0 +1,49 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// variableBlocks returns n variable blocks, sorted by name
+func variableBlocks(n int) []string {
+	blocks := make([]string, n)
+	for i := range blocks {
+		blocks[i] = fmt.Sprintf("variable \"v%05d\" {\n  type    = string\n  default = \"%d\"\n}\n", i, i)
+	}
+	return blocks
+}
+
+// BenchmarkLines_Reordered measures diffing a 12.5k-line file whose blocks
+// all moved against the sorted file, where the number of differences is
+// close to the number of lines
+func BenchmarkLines_Reordered(b *testing.B) {
+	blocks := variableBlocks(2500)
+	sorted := strings.Join(blocks, "\n")
+	rng := rand.New(rand.NewPCG(1, 2))
+	rng.Shuffle(len(blocks), func(i, j int) { blocks[i], blocks[j] = blocks[j], blocks[i] })
+	original := strings.Join(blocks, "\n")
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Lines(original, sorted)
+	}
+}
+
+// BenchmarkLines_FewChanges measures diffing a 12.5k-line file in which two
+// blocks swapped places against the sorted file
+func BenchmarkLines_FewChanges(b *testing.B) {
+	blocks := variableBlocks(2500)
+	sorted := strings.Join(blocks, "\n")
+	blocks[100], blocks[2000] = blocks[2000], blocks[100]
+	original := strings.Join(blocks, "\n")
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Lines(original, sorted)
+	}
+}
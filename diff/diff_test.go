@@ -0,0 +1,158 @@
+package diff
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// format renders a diff with one prefixed line per entry, for comparisons.
+func format(lines []Line) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line.Op.String() + line.Text + "\n")
+	}
+	return b.String()
+}
+
+// TestLines tests line-level diffs
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected string
+	}{
+		{
+			name:     "identical",
+			a:        "a\nb\n",
+			b:        "a\nb\n",
+			expected: " a\n b\n",
+		},
+		{
+			name:     "both empty",
+			expected: "",
+		},
+		{
+			name:     "insert into empty",
+			b:        "a\n",
+			expected: "+a\n",
+		},
+		{
+			name:     "delete everything",
+			a:        "a\nb\n",
+			expected: "-a\n-b\n",
+		},
+		{
+			name:     "changed middle line",
+			a:        "a\nb\nc\n",
+			b:        "a\nx\nc\n",
+			expected: " a\n-b\n+x\n c\n",
+		},
+		{
+			name:     "moved line keeps the rest equal",
+			a:        "b\nc\nd\na\n",
+			b:        "a\nb\nc\nd\n",
+			expected: "+a\n b\n c\n d\n-a\n",
+		},
+		{
+			name:     "missing trailing newline",
+			a:        "a\nb",
+			b:        "a\nb\n",
+			expected: " a\n b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := Lines(tt.a, tt.b)
+			if got := format(lines); got != tt.expected {
+				t.Errorf("Lines() =\n%s\nwant:\n%s", got, tt.expected)
+			}
+			wantChanged := strings.Contains("\n"+tt.expected, "\n-") || strings.Contains("\n"+tt.expected, "\n+")
+			if Changed(lines) != wantChanged {
+				t.Errorf("Changed() = %v, want %v", Changed(lines), wantChanged)
+			}
+		})
+	}
+}
+
+// TestSplitLines tests splitting text into lines
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a"}},
+		{"a\n\nb\n", []string{"a", "", "b"}},
+	}
+
+	for _, tt := range tests {
+		got := SplitLines(tt.text)
+		if strings.Join(got, "|") != strings.Join(tt.expected, "|") || len(got) != len(tt.expected) {
+			t.Errorf("SplitLines(%q) = %q, want %q", tt.text, got, tt.expected)
+		}
+	}
+}
+
+// TestLines_Shortest tests on random texts that the diff turns one text into
+// the other with the fewest possible deletions and insertions, with the
+// deletions of each change before its insertions
+func TestLines_Shortest(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	randomLines := func() []string {
+		lines := make([]string, rng.IntN(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.IntN(4)))
+		}
+		return lines
+	}
+
+	for range 5000 {
+		a, b := randomLines(), randomLines()
+		lines := diffLines(a, b)
+
+		var gotA, gotB []string
+		changes := 0
+		for i, line := range lines {
+			if i > 0 && lines[i-1].Op == Insert && line.Op == Delete {
+				t.Fatalf("diffLines(%q, %q) = %q, inserts before it deletes", a, b, format(lines))
+			}
+			if line.Op != Insert {
+				gotA = append(gotA, line.Text)
+			}
+			if line.Op != Delete {
+				gotB = append(gotB, line.Text)
+			}
+			if line.Op != Equal {
+				changes++
+			}
+		}
+		if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+			t.Fatalf("diffLines(%q, %q) = %q, does not turn one into the other", a, b, format(lines))
+		}
+		if want := len(a) + len(b) - 2*commonLength(a, b); changes != want {
+			t.Fatalf("diffLines(%q, %q) has %d changes, want %d", a, b, changes, want)
+		}
+	}
+}
+
+// commonLength returns the length of the longest common subsequence of a and b.
+func commonLength(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	return table[0][0]
+}
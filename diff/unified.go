@@ -0,0 +1,140 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change
+// in a unified diff, as with diff -u and git diff.
+const DefaultContext = 3
+
+// noNewline marks a line that does not end with a line break in a unified
+// diff, as written by diff and git.
+const noNewline = "\\ No newline at end of file\n"
+
+// Hunk is a group of nearby changes together with the unchanged lines around
+// them, as in a unified diff.
+type Hunk struct {
+	OldStart int    // Line number (1-based) of the first line in the original text
+	OldLines int    // Number of lines of the original text in the hunk
+	NewStart int    // Line number (1-based) of the first line in the new text
+	NewLines int    // Number of lines of the new text in the hunk
+	Lines    []Line // The lines of the hunk, with context first and last
+}
+
+// Header returns the hunk header, such as "@@ -3,7 +3,6 @@". As in diff -u, a
+// count of 1 is omitted and an empty range names the line before it.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+// hunkRange formats the start and count of one side of a hunk header.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	default:
+		return fmt.Sprintf("%d,%d", start, count)
+	}
+}
+
+// Hunks groups the changes of a diff into hunks, each with up to context
+// unchanged lines before and after its changes. Changes separated by at most
+// twice context unchanged lines share a hunk. Returns nil if nothing changed.
+func Hunks(lines []Line, context int) []Hunk {
+	context = max(context, 0)
+
+	// oldLine[i] and newLine[i] count the lines of each text before lines[i]
+	oldLine := make([]int, len(lines)+1)
+	newLine := make([]int, len(lines)+1)
+	for i, line := range lines {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if line.Op != Insert {
+			oldLine[i+1]++
+		}
+		if line.Op != Delete {
+			newLine[i+1]++
+		}
+	}
+
+	var hunks []Hunk
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			i++
+			continue
+		}
+
+		// Extend over changes until the unchanged run after them is too long
+		end := i
+		for {
+			for end < len(lines) && lines[end].Op != Equal {
+				end++
+			}
+			next := end
+			for next < len(lines) && lines[next].Op == Equal {
+				next++
+			}
+			if next == len(lines) || next-end > 2*context {
+				break
+			}
+			end = next
+		}
+
+		start := max(i-context, 0)
+		end = min(end+context, len(lines))
+		hunks = append(hunks, Hunk{
+			OldStart: oldLine[start] + 1,
+			OldLines: oldLine[end] - oldLine[start],
+			NewStart: newLine[start] + 1,
+			NewLines: newLine[end] - newLine[start],
+			Lines:    lines[start:end],
+		})
+		i = end
+	}
+	return hunks
+}
+
+// Unified returns a unified diff that turns a into b, with context unchanged
+// lines around each change, or an empty string if they are equal. The file
+// names go in the "---" and "+++" header lines; with "a/" and "b/" prefixes
+// the output can be applied with patch -p1 or git apply.
+//
+// Unlike Lines, a missing line break at the end of either text is a change,
+// and is marked with "\ No newline at end of file".
+func Unified(oldName, newName, a, b string, context int) string {
+	// Lines keep their line breaks, so a last line without one differs
+	hunks := Hunks(diffLines(splitAfterLines(a), splitAfterLines(b)), context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	out.WriteString("--- " + oldName + "\n")
+	out.WriteString("+++ " + newName + "\n")
+	for _, hunk := range hunks {
+		out.WriteString(hunk.Header() + "\n")
+		for _, line := range hunk.Lines {
+			out.WriteString(line.Op.String() + line.Text)
+			if !strings.HasSuffix(line.Text, "\n") {
+				out.WriteString("\n" + noNewline)
+			}
+		}
+	}
+	return out.String()
+}
+
+// splitAfterLines splits text into lines that keep their line breaks. Only
+// the last line can lack one.
+func splitAfterLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,166 @@
+package diff
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHunks tests grouping changes into hunks with context
+func TestHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	tests := []struct {
+		name    string
+		b       string
+		context int
+		headers []string
+	}{
+		{
+			name:    "no changes",
+			b:       a,
+			context: 3,
+		},
+		{
+			name:    "one change",
+			b:       "1\n2\n3\n4\n5\nsix\n7\n8\n9\n10\n11\n12\n",
+			context: 3,
+			headers: []string{"@@ -3,7 +3,7 @@"},
+		},
+		{
+			name:    "changes far apart",
+			b:       "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			context: 3,
+			headers: []string{"@@ -1,4 +1,4 @@", "@@ -9,4 +9,4 @@"},
+		},
+		{
+			name:    "changes close together share a hunk",
+			b:       "1\n2\nthree\n4\n5\n6\n7\n8\nnine\n10\n11\n12\n",
+			context: 3,
+			headers: []string{"@@ -1,12 +1,12 @@"},
+		},
+		{
+			name:    "insertion without context",
+			b:       "1\n2\n3\n4\n5\n5.5\n6\n7\n8\n9\n10\n11\n12\n",
+			context: 0,
+			headers: []string{"@@ -5,0 +6 @@"},
+		},
+		{
+			name:    "deletion without context",
+			b:       "1\n2\n3\n4\n5\n7\n8\n9\n10\n11\n12\n",
+			context: 0,
+			headers: []string{"@@ -6 +5,0 @@"},
+		},
+		{
+			name:    "everything deleted",
+			b:       "",
+			context: 3,
+			headers: []string{"@@ -1,12 +0,0 @@"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks := Hunks(Lines(a, tt.b), tt.context)
+			var headers []string
+			for _, hunk := range hunks {
+				headers = append(headers, hunk.Header())
+			}
+			if strings.Join(headers, " | ") != strings.Join(tt.headers, " | ") {
+				t.Errorf("Hunks() headers = %q, want %q", headers, tt.headers)
+			}
+		})
+	}
+}
+
+// TestUnified tests rendering unified diffs
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+		},
+		{
+			name: "moved block",
+			a:    "b\nc\nd\ne\nf\ng\nh\na\n",
+			b:    "a\nb\nc\nd\ne\nf\ng\nh\n",
+			expected: "--- a/main.tf\n+++ b/main.tf\n" +
+				"@@ -1,3 +1,4 @@\n+a\n b\n c\n d\n" +
+				"@@ -5,4 +6,3 @@\n f\n g\n h\n-a\n",
+		},
+		{
+			name: "missing newline added",
+			a:    "a\nb",
+			b:    "a\nb\n",
+			expected: "--- a/main.tf\n+++ b/main.tf\n" +
+				"@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name: "new file",
+			b:    "a\n",
+			expected: "--- a/main.tf\n+++ b/main.tf\n" +
+				"@@ -0,0 +1 @@\n+a\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a/main.tf", "b/main.tf", tt.a, tt.b, 3); got != tt.expected {
+				t.Errorf("Unified() =\n%s\nwant:\n%s", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestUnified_GitApply tests that git apply accepts the diffs and produces
+// the new text
+func TestUnified_GitApply(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+	}{
+		{"reordered", "c = 3\nb = 2\na = 1\n\nx = 1\n", "a = 1\nb = 2\nc = 3\n\nx = 1\n", 3},
+		{"one line of context", "1\n2\n3\n4\n5\n6\n7\n8\n9\n", "1\ntwo\n3\n4\n5\n6\n7\neight\n9\n", 1},
+		{"no final newline", "a = 1\nb = 2", "b = 2\na = 1\n", 3},
+		{"final newline removed", "a = 1\nb = 2\n", "a = 1\nb = 3", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "main.tf")
+			//nolint:gosec // G306: Test file permissions are acceptable
+			if err := os.WriteFile(path, []byte(tt.a), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			patch := Unified("a/main.tf", "b/main.tf", tt.a, tt.b, tt.context)
+
+			cmd := exec.Command("git", "apply", "-")
+			cmd.Dir = dir
+			cmd.Stdin = strings.NewReader(patch)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git apply failed: %v\n%s\npatch:\n%s", err, output, patch)
+			}
+
+			//nolint:gosec // G304: Reading test file is safe
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read file: %v", err)
+			}
+			if string(got) != tt.b {
+				t.Errorf("After git apply: %q, want %q", got, tt.b)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package diff
+
+import "unicode"
+
+// Words returns the word-level differences that turn the line a into b. Each
+// Line holds one token: a word of letters, digits and underscores, a run of
+// whitespace, or any other single character. Joining the Text of the Equal
+// and Delete tokens gives a, of the Equal and Insert tokens gives b.
+func Words(a, b string) []Line {
+	return diffLines(splitWords(a), splitWords(b))
+}
+
+// splitWords splits text into the tokens described for Words.
+func splitWords(text string) []string {
+	var tokens []string
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		end := start + 1
+		switch {
+		case isWordRune(runes[start]):
+			for end < len(runes) && isWordRune(runes[end]) {
+				end++
+			}
+		case unicode.IsSpace(runes[start]):
+			for end < len(runes) && unicode.IsSpace(runes[end]) {
+				end++
+			}
+		}
+		tokens = append(tokens, string(runes[start:end]))
+		start = end
+	}
+	return tokens
+}
+
+// isWordRune reports whether r is part of a word.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
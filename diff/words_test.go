@@ -0,0 +1,70 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestWords tests word-level diffs of single lines
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected string
+	}{
+		{
+			name:     "identical",
+			a:        "ami = var.ami",
+			b:        "ami = var.ami",
+			expected: "ami = var.ami",
+		},
+		{
+			name:     "changed value",
+			a:        `  instance_type = "t3.micro"`,
+			b:        `  instance_type = "t3.large"`,
+			expected: `  instance_type = "t3.[-micro-]{+large+}"`,
+		},
+		{
+			name:     "realigned",
+			a:        "  ami = 1",
+			b:        "  ami   = 1",
+			expected: "  ami[- -]{+   +}= 1",
+		},
+		{
+			name:     "word added",
+			a:        "depends_on = [aws_vpc.a]",
+			b:        "depends_on = [aws_vpc.a, aws_vpc.b]",
+			expected: "depends_on = [aws_vpc.a{+, aws_vpc.b+}]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strings.Builder
+			for _, word := range Words(tt.a, tt.b) {
+				switch word.Op {
+				case Delete:
+					got.WriteString("[-" + word.Text + "-]")
+				case Insert:
+					got.WriteString("{+" + word.Text + "+}")
+				default:
+					got.WriteString(word.Text)
+				}
+			}
+			// Adjacent changes of the same kind read as one
+			merged := strings.NewReplacer("-][-", "", "+}{+", "").Replace(got.String())
+			if merged != tt.expected {
+				t.Errorf("Words() = %q, want %q", merged, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSplitWords tests splitting a line into words, whitespace and punctuation
+func TestSplitWords(t *testing.T) {
+	got := splitWords(`  tags = { Name = "web_1" }`)
+	want := []string{"  ", "tags", " ", "=", " ", "{", " ", "Name", " ", "=", " ", `"`, "web_1", `"`, " ", "}"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitWords() = %q, want %q", got, want)
+	}
+}
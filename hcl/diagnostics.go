@@ -0,0 +1,149 @@
+package hcl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// ANSI escape codes used when rendering diagnostics in color.
+const (
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiBold   = "\x1b[1m"
+	ansiReset  = "\x1b[0m"
+)
+
+// DiagnosticOptions controls how WriteDiagnostics renders diagnostics.
+type DiagnosticOptions struct {
+	// Width wraps the detail text at this many columns. Zero disables wrapping.
+	Width int
+
+	// Color highlights the severity and the caret underline with ANSI colors.
+	Color bool
+}
+
+// WriteDiagnostics renders diagnostics like compiler output, following the
+// layout of hcl.NewDiagnosticTextWriter: the severity and summary, the
+// position in file:line:col form, the offending source lines with a caret
+// underline below the subject range, and the detail text.
+//
+// sources holds the contents of the files the diagnostics refer to, keyed by
+// file name. If a file is missing, or a diagnostic has no range, the source
+// snippet is omitted.
+func WriteDiagnostics(w io.Writer, diags hcl.Diagnostics, sources map[string][]byte, opts DiagnosticOptions) error {
+	for _, diag := range diags {
+		var src []byte
+		if diag.Subject != nil {
+			src = sources[diag.Subject.Filename]
+		}
+		if err := writeDiagnostic(w, diag, src, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDiagnostic renders a single diagnostic for WriteDiagnostics.
+func writeDiagnostic(w io.Writer, diag *hcl.Diagnostic, src []byte, opts DiagnosticOptions) error {
+	severity, color := "Error", ansiRed
+	if diag.Severity == hcl.DiagWarning {
+		severity, color = "Warning", ansiYellow
+	}
+	reset, bold := ansiReset, ansiBold
+	if !opts.Color {
+		color, reset, bold = "", "", ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s%s%s: %s%s%s\n", color, severity, reset, bold, diag.Summary, reset)
+
+	if diag.Subject != nil {
+		subject := *diag.Subject
+		fmt.Fprintf(&b, "  --> %s:%d:%d\n", subject.Filename, subject.Start.Line, subject.Start.Column)
+		if src != nil {
+			writeSnippet(&b, subject, src, color, reset)
+		}
+	}
+
+	if diag.Detail != "" {
+		for _, line := range strings.Split(wrapWords(diag.Detail, opts.Width-2), "\n") {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeSnippet writes the source lines covered by subject, each followed by
+// a caret underline of the part of the line inside the range.
+func writeSnippet(b *strings.Builder, subject hcl.Range, src []byte, color, reset string) {
+	// An empty range still deserves a caret
+	if subject.Empty() {
+		subject.End.Byte++
+		subject.End.Column++
+	}
+
+	gutter := len(fmt.Sprint(subject.End.Line))
+	sc := hcl.NewRangeScanner(src, subject.Filename, bufio.ScanLines)
+	for sc.Scan() {
+		lineRange := sc.Range()
+		if !lineRange.Overlaps(subject) {
+			continue
+		}
+
+		line := string(sc.Bytes())
+		from := max(subject.Start.Byte-lineRange.Start.Byte, 0)
+		to := min(subject.End.Byte-lineRange.Start.Byte, len(line))
+		if lineRange.Start.Line != subject.Start.Line && to <= from {
+			continue // Range only reaches the line break before this line
+		}
+		from = min(from, len(line)) // Point just past the end of the line
+		width := max(utf8.RuneCountInString(line[min(from, to):to]), 1)
+
+		// Keep tabs in the padding so the carets line up with the source
+		var pad strings.Builder
+		for _, c := range line[:from] {
+			if c == '\t' {
+				pad.WriteRune('\t')
+			} else {
+				pad.WriteRune(' ')
+			}
+		}
+
+		fmt.Fprintf(b, "  %*d | %s\n", gutter, lineRange.Start.Line, line)
+		fmt.Fprintf(b, "  %*s | %s%s%s%s\n", gutter, "", pad.String(), color, strings.Repeat("^", width), reset)
+	}
+}
+
+// wrapWords wraps text at width columns, breaking only between words.
+// Existing line breaks are kept. A width below 1 disables wrapping.
+func wrapWords(text string, width int) string {
+	if width < 1 {
+		return text
+	}
+
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			switch {
+			case line == "":
+				line = word
+			case len(line)+1+len(word) > width:
+				lines = append(lines, line)
+				line = word
+			default:
+				line += " " + word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,190 @@
+package hcl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// TestWriteDiagnostics tests rendering diagnostics with source snippets
+func TestWriteDiagnostics(t *testing.T) {
+	src := []byte("resource \"aws_instance\" {\n\tami = \"ami-12345\"\n}\n")
+	subject := func(startLine, startCol, startByte, endLine, endCol, endByte int) *hcl.Range {
+		return &hcl.Range{
+			Filename: "main.tf",
+			Start:    hcl.Pos{Line: startLine, Column: startCol, Byte: startByte},
+			End:      hcl.Pos{Line: endLine, Column: endCol, Byte: endByte},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		diag     *hcl.Diagnostic
+		src      []byte
+		opts     DiagnosticOptions
+		expected string
+	}{
+		{
+			name: "single line",
+			diag: &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid block labels",
+				Detail:   "resource block must have exactly 2 labels, got 1",
+				Subject:  subject(1, 1, 0, 1, 24, 23),
+			},
+			src: src,
+			expected: `Error: Invalid block labels
+  --> main.tf:1:1
+  1 | resource "aws_instance" {
+    | ^^^^^^^^^^^^^^^^^^^^^^^
+  resource block must have exactly 2 labels, got 1
+
+`,
+		},
+		{
+			name: "tab indented with wrapped detail",
+			diag: &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Deprecated attribute",
+				Detail:   "the ami attribute is deprecated in favor of image",
+				Subject:  subject(2, 2, 27, 2, 5, 30),
+			},
+			src:  src,
+			opts: DiagnosticOptions{Width: 24},
+			expected: "Warning: Deprecated attribute\n" +
+				"  --> main.tf:2:2\n" +
+				"  2 | \tami = \"ami-12345\"\n" +
+				"    | \t^^^\n" +
+				"  the ami attribute is\n" +
+				"  deprecated in favor of\n" +
+				"  image\n\n",
+		},
+		{
+			name: "empty range at end of line",
+			diag: &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing value",
+				Subject:  subject(1, 26, 25, 1, 26, 25),
+			},
+			src: src,
+			expected: `Error: Missing value
+  --> main.tf:1:26
+  1 | resource "aws_instance" {
+    |                          ^
+
+`,
+		},
+		{
+			name: "no source",
+			diag: &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid block labels",
+				Detail:   "output block must have exactly 1 label, got 0",
+				Subject:  subject(3, 1, 0, 3, 7, 6),
+			},
+			expected: `Error: Invalid block labels
+  --> main.tf:3:1
+  output block must have exactly 1 label, got 0
+
+`,
+		},
+		{
+			name: "color",
+			diag: &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Bad",
+				Subject:  subject(3, 1, 45, 3, 2, 46),
+			},
+			src:  src,
+			opts: DiagnosticOptions{Color: true},
+			expected: "\x1b[1;31mError\x1b[0m: \x1b[1mBad\x1b[0m\n" +
+				"  --> main.tf:3:1\n" +
+				"  3 | }\n" +
+				"    | \x1b[1;31m^\x1b[0m\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteDiagnostics(&buf, hcl.Diagnostics{tt.diag}, map[string][]byte{"main.tf": tt.src}, tt.opts); err != nil {
+				t.Fatalf("WriteDiagnostics failed: %v", err)
+			}
+			if got := buf.String(); got != tt.expected {
+				t.Errorf("unexpected output:\ngot:\n%q\nwant:\n%q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestErrorDiagnostics tests extracting diagnostics and source from errors
+func TestErrorDiagnostics(t *testing.T) {
+	diags := hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "Bad"}}
+	src := []byte("locals {}\n")
+
+	tests := []struct {
+		name      string
+		err       error
+		wantDiags bool
+	}{
+		{name: "parse error", err: &HCLParseError{Path: "main.tf", Diags: diags, Source: src}, wantDiags: true},
+		{name: "validation error", err: &HCLError{Op: "Validate", Kind: KindValidation, Err: &DiagnosticsError{Diags: diags, Sources: map[string][]byte{"main.tf": src}}}, wantDiags: true},
+		{name: "validation error without diagnostics", err: &HCLError{Op: "Validate", Kind: KindValidation}},
+		{name: "other error", err: &HCLError{Op: "Sort", Kind: KindSorting, Err: &DiagnosticsError{Diags: diags}}},
+		{name: "nil", err: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotDiags, gotSources := ErrorDiagnostics(tt.err)
+			if !tt.wantDiags {
+				if gotDiags != nil || gotSources != nil {
+					t.Errorf("expected no diagnostics, got %v", gotDiags)
+				}
+				return
+			}
+			if len(gotDiags) != 1 || string(gotSources["main.tf"]) != string(src) {
+				t.Errorf("got %v and %q, want diagnostics with source", gotDiags, gotSources)
+			}
+		})
+	}
+}
+
+// TestWrapWords tests word wrapping of diagnostic details
+func TestWrapWords(t *testing.T) {
+	tests := []struct {
+		text     string
+		width    int
+		expected string
+	}{
+		{"one two three", 0, "one two three"},
+		{"one two three", 7, "one two\nthree"},
+		{"averyveryverylongword x", 5, "averyveryverylongword\nx"},
+		{"first\nsecond line", 6, "first\nsecond\nline"},
+	}
+
+	for _, tt := range tests {
+		if got := wrapWords(tt.text, tt.width); got != tt.expected {
+			t.Errorf("wrapWords(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.expected)
+		}
+	}
+}
+
+// TestParseHCLFile_ErrorSource tests that parse errors keep the file source
+func TestParseHCLFile_ErrorSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.tf")
+	//nolint:gosec // G306: Test files can use 0644 permissions
+	if err := os.WriteFile(path, []byte("locals {\n  name = \n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ParseHCLFile(path)
+	diags, sources := ErrorDiagnostics(err)
+	if len(diags) == 0 || !strings.Contains(string(sources[path]), "name =") {
+		t.Errorf("expected diagnostics with source, got %v and %q", diags, sources)
+	}
+}
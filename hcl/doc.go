@@ -8,7 +8,10 @@
 //
 // The main entry points are:
 //   - ParseHCLFile: Parse and validate an HCL file
+//   - ParseHCLFileFS: Parse and validate an HCL file read from an io/fs.FS
+//   - ParseHCL: Parse HCL source that is already in memory
 //   - SortHCLFile: Sort blocks and attributes in an HCL file
 //   - FormatHCLFile: Apply canonical HCL formatting using hclwrite
 //   - SortAndFormatHCLFile: Combined sort and format operation
+//   - CheckEquivalent: Verify that sorted content means the same as the original
 package hcl
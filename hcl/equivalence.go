@@ -0,0 +1,232 @@
+package hcl
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// EquivalenceError reports how sorted content differs in meaning from the
+// content it was sorted from. It is the underlying error of the KindSorting
+// error returned when sorting would lose or change something.
+type EquivalenceError struct {
+	Differences []string // One description per difference, e.g. `attribute "ami" was lost in resource "aws_instance" "web"`
+}
+
+// Error implements the error interface, listing every difference.
+func (e *EquivalenceError) Error() string {
+	return "sorting would change the meaning of the file: " + strings.Join(e.Differences, "; ")
+}
+
+// CheckEquivalent reports whether sorted has the same meaning as original.
+//
+// Both are parsed with hclsyntax and compared structurally: the bodies must
+// hold the same blocks, by type and labels, and the same attributes, whose
+// expressions must consist of the same tokens. Order, whitespace, comments,
+// and the choice between commas and newlines as separators are ignored.
+// Nested blocks are compared recursively; blocks with the same type and
+// labels are matched in the order they appear.
+//
+// Returns nil if they are equivalent, an *EquivalenceError describing each
+// difference if not, or an error if either fails to parse.
+func CheckEquivalent(original, sorted []byte) error {
+	origFile, diags := hclsyntax.ParseConfig(original, "original", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return fmt.Errorf("parse original: %w", diags)
+	}
+	sortedFile, diags := hclsyntax.ParseConfig(sorted, "sorted", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return &EquivalenceError{Differences: []string{"the sorted content is not valid HCL: " + diags.Error()}}
+	}
+
+	origBody, ok := origFile.Body.(*hclsyntax.Body)
+	if !ok {
+		return fmt.Errorf("file body is not hclsyntax.Body")
+	}
+	sortedBody, ok := sortedFile.Body.(*hclsyntax.Body)
+	if !ok {
+		return fmt.Errorf("file body is not hclsyntax.Body")
+	}
+
+	c := comparison{original: original, sorted: sorted}
+	c.bodies(origBody, sortedBody, "")
+	if len(c.differences) > 0 {
+		return &EquivalenceError{Differences: c.differences}
+	}
+	return nil
+}
+
+// checkSorted verifies that formatted, the result of sorting file with opts,
+// means the same as file. The expression rewrites enabled in opts are
+// applied to a copy of file first, since they change expressions on purpose.
+func checkSorted(file *hclwrite.File, formatted string, opts SortOptions) error {
+	reference := file.Bytes()
+	if opts.Normalize || len(opts.SortListAttributes) > 0 {
+		rewritten, diags := hclwrite.ParseConfig(reference, "", hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return fmt.Errorf("parse original: %w", diags)
+		}
+		if opts.Normalize {
+			NormalizeHCLFile(rewritten)
+		}
+		SortListValues(rewritten, opts.SortListAttributes)
+		reference = rewritten.Bytes()
+	}
+	return CheckEquivalent(reference, []byte(formatted))
+}
+
+// comparison collects the differences between an original and a sorted file.
+type comparison struct {
+	original    []byte
+	sorted      []byte
+	differences []string
+}
+
+// addf records a difference found in the body at location.
+func (c *comparison) addf(location, format string, args ...any) {
+	difference := fmt.Sprintf(format, args...)
+	if location != "" {
+		difference += " in " + location
+	}
+	c.differences = append(c.differences, difference)
+}
+
+// bodies compares the attributes and nested blocks of two bodies. Location
+// describes where the bodies are, or is empty for the top level.
+func (c *comparison) bodies(orig, sorted *hclsyntax.Body, location string) {
+	for _, name := range sortedNames(orig.Attributes) {
+		sortedAttr, ok := sorted.Attributes[name]
+		if !ok {
+			c.addf(location, "attribute %q was lost", name)
+			continue
+		}
+		origRange, sortedRange := orig.Attributes[name].Expr.Range(), sortedAttr.Expr.Range()
+		if !slices.Equal(expressionTokens(c.original, origRange), expressionTokens(c.sorted, sortedRange)) {
+			c.addf(location, "attribute %q changed from %s to %s", name, abbreviate(c.original, origRange), abbreviate(c.sorted, sortedRange))
+		}
+	}
+	for _, name := range sortedNames(sorted.Attributes) {
+		if _, ok := orig.Attributes[name]; !ok {
+			c.addf(location, "attribute %q was added", name)
+		}
+	}
+
+	origGroups, keys := groupBlocks(orig.Blocks)
+	sortedGroups, sortedKeys := groupBlocks(sorted.Blocks)
+	for _, key := range keys {
+		origBlocks, sortedBlocks := origGroups[key], sortedGroups[key]
+		for i := range min(len(origBlocks), len(sortedBlocks)) {
+			inner := key
+			if len(origBlocks) > 1 {
+				inner += " #" + strconv.Itoa(i+1)
+			}
+			if location != "" {
+				inner = location + " > " + inner
+			}
+			c.bodies(origBlocks[i].Body, sortedBlocks[i].Body, inner)
+		}
+		if lost := len(origBlocks) - len(sortedBlocks); lost > 0 {
+			c.addf(location, "%s was lost", countBlocks(lost, key))
+		}
+	}
+	for _, key := range sortedKeys {
+		if added := len(sortedGroups[key]) - len(origGroups[key]); added > 0 {
+			c.addf(location, "%s was added", countBlocks(added, key))
+		}
+	}
+}
+
+// groupBlocks groups blocks by their type and labels, keeping their order,
+// and returns the keys in order of first appearance.
+func groupBlocks(blocks hclsyntax.Blocks) (map[string][]*hclsyntax.Block, []string) {
+	groups := make(map[string][]*hclsyntax.Block)
+	var keys []string
+	for _, block := range blocks {
+		key := block.Type
+		for _, label := range block.Labels {
+			key += " " + strconv.Quote(label)
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], block)
+	}
+	return groups, keys
+}
+
+// countBlocks describes n blocks with the given key, such as `block output "a"`
+// or `2 blocks ingress`.
+func countBlocks(n int, key string) string {
+	if n == 1 {
+		return "block " + key
+	}
+	return strconv.Itoa(n) + " blocks " + key
+}
+
+// sortedNames returns the attribute names of a body in alphabetical order,
+// so differences are reported in a stable order.
+func sortedNames(attributes hclsyntax.Attributes) []string {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// expressionTokens returns the tokens of the expression at rng in src,
+// ignoring comments and whitespace. Newlines and commas are both separators:
+// a run of them becomes a single ",", and separators right after an opening
+// or right before a closing bracket are dropped.
+func expressionTokens(src []byte, rng hcl.Range) []string {
+	tokens, _ := hclsyntax.LexExpression(rng.SliceBytes(src), "", rng.Start)
+
+	var out []string
+	separated := false
+	for _, token := range tokens {
+		switch token.Type {
+		case hclsyntax.TokenNewline, hclsyntax.TokenComma, hclsyntax.TokenComment:
+			separated = true
+			continue
+		case hclsyntax.TokenEOF:
+			continue
+		}
+
+		if separated && len(out) > 0 && !isOpening(out[len(out)-1]) && !isClosing(token.Type) {
+			out = append(out, ",")
+		}
+		separated = false
+		out = append(out, string(token.Bytes))
+	}
+	return out
+}
+
+// isOpening reports whether a token opens a bracketed sequence.
+func isOpening(token string) bool {
+	return token == "[" || token == "{" || token == "("
+}
+
+// isClosing reports whether a token type closes a bracketed sequence.
+func isClosing(tokenType hclsyntax.TokenType) bool {
+	return tokenType == hclsyntax.TokenCBrack || tokenType == hclsyntax.TokenCBrace || tokenType == hclsyntax.TokenCParen
+}
+
+// maxAbbreviated is the length beyond which expressions are shortened in
+// difference descriptions.
+const maxAbbreviated = 40
+
+// abbreviate renders the expression at rng in src on one line for a
+// difference description, shortening long expressions.
+func abbreviate(src []byte, rng hcl.Range) string {
+	text := []rune(strings.Join(strings.Fields(string(rng.SliceBytes(src))), " "))
+	if len(text) > maxAbbreviated {
+		text = append(text[:maxAbbreviated-3], []rune("...")...)
+	}
+	return "`" + string(text) + "`"
+}
@@ -0,0 +1,201 @@
+package hcl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// TestCheckEquivalent tests the structural comparison of original and sorted content
+func TestCheckEquivalent(t *testing.T) {
+	original := `resource "aws_instance" "web" {
+  ami  = "ami-1"
+  tags = { Name = "web", Env = "prod" }
+
+  ebs_block_device {
+    device_name = "/dev/sda"
+  }
+  ebs_block_device {
+    device_name = "/dev/sdb"
+  }
+}
+
+variable "region" {
+  type = string
+}
+`
+
+	tests := []struct {
+		name   string
+		sorted string
+		want   []string
+	}{
+		{
+			name: "reordered and reformatted",
+			sorted: `variable "region" {
+  type = string # The region
+}
+
+resource "aws_instance" "web" {
+  ami = "ami-1"
+  tags = {
+    Name = "web"
+    Env  = "prod"
+  }
+
+  ebs_block_device {
+    device_name = "/dev/sda"
+  }
+  ebs_block_device {
+    device_name = "/dev/sdb"
+  }
+}
+`,
+		},
+		{
+			name: "lost and added",
+			sorted: `resource "aws_instance" "web" {
+  tags = { Name = "web", Env = "prod" }
+  user_data = ""
+
+  ebs_block_device {
+    device_name = "/dev/sda"
+  }
+  ebs_block_device {
+    device_name = "/dev/sdb"
+  }
+}
+
+variable "zone" {
+  type = string
+}
+`,
+			want: []string{
+				`attribute "ami" was lost in resource "aws_instance" "web"`,
+				`attribute "user_data" was added in resource "aws_instance" "web"`,
+				`block variable "region" was lost`,
+				`block variable "zone" was added`,
+			},
+		},
+		{
+			name: "changed expressions",
+			sorted: `resource "aws_instance" "web" {
+  ami  = "ami-2"
+  tags = { Name = "web", Env = "dev" }
+
+  ebs_block_device {
+    device_name = "/dev/sdb"
+  }
+  ebs_block_device {
+    device_name = "/dev/sda"
+  }
+}
+
+variable "region" {
+  type = string
+}
+`,
+			want: []string{
+				"attribute \"ami\" changed from `\"ami-1\"` to `\"ami-2\"` in resource \"aws_instance\" \"web\"",
+				"attribute \"tags\" changed from `{ Name = \"web\", Env = \"prod\" }` to `{ Name = \"web\", Env = \"dev\" }` in resource \"aws_instance\" \"web\"",
+				"attribute \"device_name\" changed from `\"/dev/sda\"` to `\"/dev/sdb\"` in resource \"aws_instance\" \"web\" > ebs_block_device #1",
+				"attribute \"device_name\" changed from `\"/dev/sdb\"` to `\"/dev/sda\"` in resource \"aws_instance\" \"web\" > ebs_block_device #2",
+			},
+		},
+		{
+			name: "lost nested blocks",
+			sorted: `resource "aws_instance" "web" {
+  ami  = "ami-1"
+  tags = { Name = "web", Env = "prod" }
+}
+
+variable "region" {
+  type = string
+}
+`,
+			want: []string{`2 blocks ebs_block_device was lost in resource "aws_instance" "web"`},
+		},
+		{
+			name:   "invalid sorted content",
+			sorted: "variable \"region\" {\n",
+			want:   []string{"the sorted content is not valid HCL"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckEquivalent([]byte(original), []byte(tt.sorted))
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("CheckEquivalent() error = %v", err)
+				}
+				return
+			}
+
+			var eqErr *EquivalenceError
+			if !errors.As(err, &eqErr) {
+				t.Fatalf("CheckEquivalent() error = %v, want *EquivalenceError", err)
+			}
+			if len(eqErr.Differences) != len(tt.want) {
+				t.Fatalf("Differences = %q, want %q", eqErr.Differences, tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(eqErr.Differences[i], want) {
+					t.Errorf("Differences[%d] = %q, want %q", i, eqErr.Differences[i], want)
+				}
+			}
+		})
+	}
+}
+
+// TestCheckEquivalent_InvalidOriginal tests that an original that does not parse is an error
+func TestCheckEquivalent_InvalidOriginal(t *testing.T) {
+	err := CheckEquivalent([]byte("variable {"), []byte(""))
+	if err == nil {
+		t.Fatal("CheckEquivalent() error = nil, want parse error")
+	}
+	var eqErr *EquivalenceError
+	if errors.As(err, &eqErr) {
+		t.Errorf("CheckEquivalent() error = %v, want a parse error", err)
+	}
+}
+
+// TestCheckSorted tests that the guard accepts the expression rewrites enabled
+// in the options and rejects content that lost something
+func TestCheckSorted(t *testing.T) {
+	input := `variable "name" {
+  type = "string"
+}
+
+resource "aws_instance" "web" {
+  ami        = "${var.ami}"
+  depends_on = [aws_vpc.b, aws_vpc.a]
+}
+`
+	file, diags := hclwrite.ParseConfig([]byte(input), "main.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("parse failed: %v", diags)
+	}
+
+	opts := SortOptions{
+		Normalize:          true,
+		SortListAttributes: []string{"depends_on"},
+		FormatOptions:      FormatOptions{MaxLineWidth: 30},
+	}
+	formatted, _, err := SortAndFormatHCLFileWithOptions(file, opts)
+	if err != nil {
+		t.Fatalf("SortAndFormatHCLFileWithOptions() error = %v", err)
+	}
+
+	if err := checkSorted(file, formatted, SortOptions{}); err == nil {
+		t.Error("checkSorted() without the rewrites = nil, want a difference")
+	}
+
+	lost := strings.Replace(formatted, "ami = var.ami", "", 1)
+	err = checkSorted(file, lost, opts)
+	if err == nil || !strings.Contains(err.Error(), `attribute "ami" was lost`) {
+		t.Errorf("checkSorted() = %v, want the lost attribute", err)
+	}
+}
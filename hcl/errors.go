@@ -24,6 +24,8 @@ const (
 	KindFormatting
 	// KindSorting represents an error during sorting operation.
 	KindSorting
+	// KindSchema represents a structural error in a block body (e.g., an output without value).
+	KindSchema
 )
 
 // HCLError is the unified error type for HCL operations.
@@ -64,8 +66,9 @@ func (e *HCLError) Unwrap() error {
 //
 //nolint:revive // exported: HCLParseError is intentionally named to indicate HCL-specific parse errors
 type HCLParseError struct {
-	Path  string          // File path that failed to parse
-	Diags hcl.Diagnostics // Parser diagnostics with error details
+	Path   string          // File path that failed to parse
+	Diags  hcl.Diagnostics // Parser diagnostics with error details
+	Source []byte          // File content, used to render source snippets (may be nil)
 }
 
 // Error implements the error interface, returning a formatted error message
@@ -74,6 +77,19 @@ func (e *HCLParseError) Error() string {
 	return fmt.Sprintf("HCL parsing failed for %s: %s", e.Path, e.Diags.Error())
 }
 
+// DiagnosticsError holds the diagnostics found while validating one or more
+// files, together with the sources they refer to. It is the underlying error of
+// validation errors that report problems in the file content.
+type DiagnosticsError struct {
+	Diags   hcl.Diagnostics   // Problems found, each with its source range
+	Sources map[string][]byte // File contents by file name, used to render source snippets (may be nil)
+}
+
+// Error implements the error interface, summarizing the diagnostics on one line.
+func (e *DiagnosticsError) Error() string {
+	return e.Diags.Error()
+}
+
 // Error checking functions
 
 // IsParsingError checks if an error is a parsing error.
@@ -100,6 +116,50 @@ func IsValidationError(err error) bool {
 	return false
 }
 
+// IsSchemaError checks if an error is a schema validation error.
+func IsSchemaError(err error) bool {
+	var hclErr *HCLError
+	if errors.As(err, &hclErr) {
+		return hclErr.Kind == KindSchema
+	}
+	return false
+}
+
+// ValidationDiagnostics returns the diagnostics of a validation error, each
+// with its source range. It returns nil if err is not a validation error or
+// carries no diagnostics.
+func ValidationDiagnostics(err error) hcl.Diagnostics {
+	var hclErr *HCLError
+	if !errors.As(err, &hclErr) || hclErr.Kind != KindValidation {
+		return nil
+	}
+	var diagsErr *DiagnosticsError
+	if errors.As(hclErr.Err, &diagsErr) {
+		return diagsErr.Diags
+	}
+	return nil
+}
+
+// ErrorDiagnostics returns the diagnostics carried by a parse, validation or
+// schema error, together with the contents of the files they refer to, keyed by
+// file name. It returns nil diagnostics if err carries none.
+func ErrorDiagnostics(err error) (hcl.Diagnostics, map[string][]byte) {
+	var parseErr *HCLParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.Diags, map[string][]byte{parseErr.Path: parseErr.Source}
+	}
+
+	var hclErr *HCLError
+	if !errors.As(err, &hclErr) || (hclErr.Kind != KindValidation && hclErr.Kind != KindSchema) {
+		return nil, nil
+	}
+	var diagsErr *DiagnosticsError
+	if errors.As(hclErr.Err, &diagsErr) {
+		return diagsErr.Diags, diagsErr.Sources
+	}
+	return nil, nil
+}
+
 // IsFormattingError checks if an error is a formatting error.
 func IsFormattingError(err error) bool {
 	var hclErr *HCLError
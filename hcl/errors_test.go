@@ -537,6 +537,7 @@ func TestErrorKind(t *testing.T) {
 		KindValidation,
 		KindFormatting,
 		KindSorting,
+		KindSchema,
 	}
 
 	seen := make(map[ErrorKind]bool)
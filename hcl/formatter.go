@@ -7,6 +7,20 @@ import (
 	"github.com/hashicorp/hcl/v2/hclwrite"
 )
 
+// FormatOptions configures optional formatting steps applied after canonical formatting.
+// The zero value applies canonical formatting only, matching FormatHCLFile.
+type FormatOptions struct {
+	// MaxLineWidth is the maximum line width in columns. When an attribute's
+	// list, tuple or object constructor makes its line longer than this, the
+	// constructor is broken onto one element per line. Zero disables wrapping.
+	MaxLineWidth int
+
+	// CollapseCollections joins multi-line collection constructors back onto
+	// one line when the result fits within MaxLineWidth. It has no effect
+	// when MaxLineWidth is zero.
+	CollapseCollections bool
+}
+
 // FormatHCLFile takes an hclwrite.File and returns the formatted string.
 //
 // It uses the canonical hclwrite formatting, which produces correctly formatted HCL
@@ -15,6 +29,18 @@ import (
 //
 // Returns the formatted content as a string, or an HCLError with KindFormatting if the file is nil.
 func FormatHCLFile(file *hclwrite.File) (string, error) {
+	return FormatHCLFileWithOptions(file, FormatOptions{})
+}
+
+// FormatHCLFileWithOptions formats an hclwrite.File like FormatHCLFile, then applies
+// the line-width-aware wrapping configured in opts.
+//
+// Wrapping works on the canonically formatted output, so line widths are measured
+// exactly as they will be written. The input file is never modified.
+//
+// Returns the formatted content as a string, or an HCLError with KindFormatting if
+// the file is nil or the formatted output cannot be re-parsed for wrapping.
+func FormatHCLFileWithOptions(file *hclwrite.File, opts FormatOptions) (string, error) {
 	if file == nil {
 		return "", &HCLError{
 			Op:   "FormatHCLFile",
@@ -24,7 +50,22 @@ func FormatHCLFile(file *hclwrite.File) (string, error) {
 	}
 
 	// hclwrite.Bytes() returns canonically formatted HCL
-	return string(file.Bytes()), nil
+	formatted := file.Bytes()
+	if opts.MaxLineWidth <= 0 {
+		return string(formatted), nil
+	}
+
+	wrapFile, diags := hclwrite.ParseConfig(formatted, "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return string(formatted), &HCLError{
+			Op:   "FormatHCLFile",
+			Kind: KindFormatting,
+			Err:  diags,
+		}
+	}
+
+	wrapBody(wrapFile.Body(), 0, opts)
+	return string(wrapFile.Bytes()), nil
 }
 
 // FormatHCLString formats a raw HCL string.
@@ -0,0 +1,118 @@
+package hcl
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// BlockMove describes a top-level block moving from one file of a module to another.
+type BlockMove struct {
+	Address string // Block address (e.g., "variable.region")
+	From    string // File name the block is currently in
+	To      string // File name the block is moved to
+}
+
+// DefaultLayout returns the canonical file for each block type, following the
+// HashiCorp module structure convention: resources, data sources and module
+// calls go to main.tf.
+//
+// Modules that group resources into several files such as network.tf and
+// iam.tf can keep that grouping by mapping resource, data and module to "",
+// which leaves those blocks in the file they are declared in.
+func DefaultLayout() map[string]string {
+	return map[string]string{
+		"terraform": "versions.tf",
+		"provider":  "providers.tf",
+		"variable":  "variables.tf",
+		"locals":    "locals.tf",
+		"resource":  "main.tf",
+		"data":      "main.tf",
+		"module":    "main.tf",
+		"output":    "outputs.tf",
+	}
+}
+
+// isOverrideFile reports whether name is a Terraform override file,
+// override.tf or a file ending in _override.tf. Terraform merges the blocks of
+// override files into the blocks they redeclare instead of adding them.
+func isOverrideFile(name string) bool {
+	base := filepath.Base(name)
+	if filepath.Ext(base) != ".tf" {
+		return false
+	}
+	base = strings.TrimSuffix(base, ".tf")
+	return base == "override" || strings.HasSuffix(base, "_override")
+}
+
+// PlanLayout assigns every top-level block in a module's files to its canonical file.
+//
+// The files map is keyed by file name (e.g., "main.tf") and the mapping by block
+// type; blocks whose type has no mapping stay where they are. Blocks in
+// override files (see isOverrideFile) also stay: they redeclare blocks of
+// other files, so moving them next to those would declare them twice.
+//
+// Returns the planned moves in file and block order, and the new content of
+// every file that gains or loses a block. Affected files are unsorted; pass them
+// through SortAndFormatHCLFile before writing. A returned file with no blocks
+// has been emptied by the moves and can be deleted. The input files are not modified.
+func PlanLayout(files map[string]*hclwrite.File, mapping map[string]string) ([]BlockMove, map[string]*hclwrite.File) {
+	return planMoves(files, func(name string, block *hclwrite.Block) string {
+		if isOverrideFile(name) {
+			return name
+		}
+		if dest := mapping[block.Type()]; dest != "" {
+			return dest
+		}
+		return name
+	})
+}
+
+// planMoves assigns every top-level block to the file returned by dest, which
+// receives the block's current file name. It returns the moves and the new
+// content of every affected file, as described for PlanLayout.
+func planMoves(files map[string]*hclwrite.File, dest func(name string, block *hclwrite.Block) string) ([]BlockMove, map[string]*hclwrite.File) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type placedBlock struct {
+		block *hclwrite.Block
+		file  string
+	}
+
+	var moves []BlockMove
+	var placed []placedBlock
+	affected := make(map[string]bool)
+
+	for _, name := range names {
+		if files[name] == nil {
+			continue
+		}
+		for _, block := range files[name].Body().Blocks() {
+			to := dest(name, block)
+			if to != name {
+				moves = append(moves, BlockMove{Address: blockAddress(block), From: name, To: to})
+				affected[name] = true
+				affected[to] = true
+			}
+			placed = append(placed, placedBlock{block: block, file: to})
+		}
+	}
+
+	result := make(map[string]*hclwrite.File, len(affected))
+	for name := range affected {
+		result[name] = hclwrite.NewEmptyFile()
+	}
+	for _, p := range placed {
+		if file, ok := result[p.file]; ok {
+			file.Body().AppendBlock(copyBlockClean(p.block))
+		}
+	}
+
+	return moves, result
+}
@@ -0,0 +1,219 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// parseModule parses a map of file name to content into hclwrite files
+func parseModule(t *testing.T, sources map[string]string) map[string]*hclwrite.File {
+	t.Helper()
+
+	files := make(map[string]*hclwrite.File, len(sources))
+	for name, src := range sources {
+		file, diags := hclwrite.ParseConfig([]byte(src), name, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			t.Fatalf("parse %s failed: %v", name, diags)
+		}
+		files[name] = file
+	}
+	return files
+}
+
+// TestPlanLayout tests moving blocks to their canonical files
+func TestPlanLayout(t *testing.T) {
+	files := parseModule(t, map[string]string{
+		"main.tf": `variable "region" {
+  type = string
+}
+
+resource "aws_instance" "web" {
+  ami = "ami-12345"
+}
+
+output "id" {
+  value = aws_instance.web.id
+}
+`,
+		"network.tf": `resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+variable "cidr" {
+  type = string
+}
+`,
+		"variables.tf": `variable "zone" {
+  type = string
+}
+`,
+		"providers.tf": `provider "aws" {
+  region = var.region
+}
+`,
+	})
+
+	moves, affected := PlanLayout(files, DefaultLayout())
+
+	expectedMoves := []BlockMove{
+		{Address: "variable.region", From: "main.tf", To: "variables.tf"},
+		{Address: "output.id", From: "main.tf", To: "outputs.tf"},
+		{Address: "resource.aws_vpc.main", From: "network.tf", To: "main.tf"},
+		{Address: "variable.cidr", From: "network.tf", To: "variables.tf"},
+	}
+	if len(moves) != len(expectedMoves) {
+		t.Fatalf("expected %d moves, got %d: %v", len(expectedMoves), len(moves), moves)
+	}
+	for i, move := range moves {
+		if move != expectedMoves[i] {
+			t.Errorf("move %d = %+v, want %+v", i, move, expectedMoves[i])
+		}
+	}
+
+	// providers.tf is already canonical and untouched
+	if _, ok := affected["providers.tf"]; ok {
+		t.Error("providers.tf should not be affected")
+	}
+
+	variables := string(affected["variables.tf"].Bytes())
+	for _, name := range []string{`"cidr"`, `"region"`, `"zone"`} {
+		if !strings.Contains(variables, "variable "+name) {
+			t.Errorf("variables.tf missing variable %s:\n%s", name, variables)
+		}
+	}
+
+	if blocks := affected["outputs.tf"].Body().Blocks(); len(blocks) != 1 {
+		t.Errorf("expected 1 block in outputs.tf, got %d", len(blocks))
+	}
+	if blocks := affected["main.tf"].Body().Blocks(); len(blocks) != 2 || blocks[0].Type() != "resource" || blocks[1].Type() != "resource" {
+		t.Errorf("expected only the resources in main.tf, got %d blocks", len(blocks))
+	}
+	if blocks := affected["network.tf"].Body().Blocks(); len(blocks) != 0 {
+		t.Errorf("expected network.tf to be emptied, got %d blocks", len(blocks))
+	}
+}
+
+// TestPlanLayout_KeepGrouping tests that mapping resources to "" keeps them
+// in the files they are declared in
+func TestPlanLayout_KeepGrouping(t *testing.T) {
+	files := parseModule(t, map[string]string{
+		"network.tf": `resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+data "aws_region" "current" {}
+`,
+	})
+
+	mapping := DefaultLayout()
+	mapping["resource"] = ""
+	mapping["data"] = ""
+	moves, affected := PlanLayout(files, mapping)
+	if len(moves) != 0 || len(affected) != 0 {
+		t.Errorf("expected no moves, got %v (affected %d files)", moves, len(affected))
+	}
+}
+
+// TestPlanLayout_OverrideFiles tests that blocks in override files stay there
+func TestPlanLayout_OverrideFiles(t *testing.T) {
+	files := parseModule(t, map[string]string{
+		"variables.tf": `variable "region" {
+  type = string
+}
+`,
+		"override.tf": `variable "region" {
+  default = "eu-west-1"
+}
+`,
+		"dev_override.tf": `output "id" {
+  value = 1
+}
+`,
+	})
+
+	moves, affected := PlanLayout(files, DefaultLayout())
+	if len(moves) != 0 || len(affected) != 0 {
+		t.Errorf("expected no moves, got %v (affected %d files)", moves, len(affected))
+	}
+}
+
+// TestIsOverrideFile tests recognizing Terraform override files
+func TestIsOverrideFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"override.tf", true},
+		{"dev_override.tf", true},
+		{"modules/vpc/override.tf", true},
+		{"main.tf", false},
+		{"overrides.tf", false},
+		{"override.tf.bak", false},
+		{"override.hcl", false},
+		{"myoverride.tf", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isOverrideFile(tt.name); got != tt.want {
+				t.Errorf("isOverrideFile(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPlanLayout_EmptiedFile tests that files losing all their blocks are returned empty
+func TestPlanLayout_EmptiedFile(t *testing.T) {
+	files := parseModule(t, map[string]string{
+		"vars.tf": `variable "region" {
+  type = string
+}
+`,
+	})
+
+	moves, affected := PlanLayout(files, DefaultLayout())
+	if len(moves) != 1 {
+		t.Fatalf("expected 1 move, got %d", len(moves))
+	}
+	if blocks := affected["vars.tf"].Body().Blocks(); len(blocks) != 0 {
+		t.Errorf("expected vars.tf to be empty, got %d blocks", len(blocks))
+	}
+}
+
+// TestPlanLayout_CustomMapping tests overriding and disabling mappings
+func TestPlanLayout_CustomMapping(t *testing.T) {
+	files := parseModule(t, map[string]string{
+		"network.tf": `resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+output "vpc_id" {
+  value = aws_vpc.main.id
+}
+`,
+	})
+
+	moves, affected := PlanLayout(files, map[string]string{"resource": "main.tf", "output": ""})
+	if len(moves) != 1 || moves[0].To != "main.tf" || moves[0].Address != "resource.aws_vpc.main" {
+		t.Fatalf("unexpected moves: %v", moves)
+	}
+	if blocks := affected["network.tf"].Body().Blocks(); len(blocks) != 1 || blocks[0].Type() != "output" {
+		t.Error("expected output to stay in network.tf")
+	}
+}
+
+// TestPlanLayout_AlreadyCanonical tests that a canonical module needs no moves
+func TestPlanLayout_AlreadyCanonical(t *testing.T) {
+	files := parseModule(t, map[string]string{
+		"variables.tf": "variable \"a\" {}\n",
+		"outputs.tf":   "output \"b\" {\n  value = 1\n}\n",
+	})
+
+	moves, affected := PlanLayout(files, DefaultLayout())
+	if len(moves) != 0 || len(affected) != 0 {
+		t.Errorf("expected no moves, got %v (affected %d files)", moves, len(affected))
+	}
+}
@@ -0,0 +1,141 @@
+package hcl
+
+import (
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// SortListValues sorts the elements of list values for the named attributes in place.
+//
+// It is intended for attributes that are semantically sets, such as depends_on
+// or security_group_ids, where element order carries no meaning. Attributes are
+// matched by name in every block, including nested blocks.
+//
+// Only lists whose elements are all literal strings or simple traversals
+// (e.g., aws_instance.web or var.subnet_id) are sorted; a list containing any
+// other expression is left untouched. Single-line lists stay on one line and
+// multi-line lists keep one element per line.
+//
+// Returns the number of lists whose element order changed.
+func SortListValues(file *hclwrite.File, names []string) int {
+	if file == nil || len(names) == 0 {
+		return 0
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	return sortListValuesInBody(file.Body(), wanted)
+}
+
+// sortListValuesInBody sorts matching list attributes in body and its nested blocks.
+func sortListValuesInBody(body *hclwrite.Body, wanted map[string]bool) int {
+	changed := 0
+
+	for name, attr := range body.Attributes() {
+		if !wanted[name] {
+			continue
+		}
+		if sorted := sortListTokens(attr.Expr().BuildTokens(nil)); sorted != nil {
+			body.SetAttributeRaw(name, sorted)
+			changed++
+		}
+	}
+
+	for _, block := range body.Blocks() {
+		changed += sortListValuesInBody(block.Body(), wanted)
+	}
+
+	return changed
+}
+
+// sortListTokens returns the tokens of a list constructor with its elements sorted.
+// Returns nil if tokens are not a sortable list or the elements are already in order.
+func sortListTokens(tokens hclwrite.Tokens) hclwrite.Tokens {
+	elements, isObject, ok := splitCollection(tokens)
+	if !ok || isObject || len(elements) < 2 {
+		return nil
+	}
+
+	keys := make([]string, len(elements))
+	for i, elem := range elements {
+		if !isSortableElement(elem) {
+			return nil
+		}
+		keys[i] = tokensString(elem)
+	}
+
+	order := make([]int, len(elements))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return keys[order[i]] < keys[order[j]]
+	})
+
+	if sort.SliceIsSorted(order, func(i, j int) bool { return order[i] < order[j] }) {
+		return nil
+	}
+
+	multiLine := isMultiLine(tokens)
+	sorted := hclwrite.Tokens{copyToken(tokens[0], 1)}
+	if multiLine {
+		sorted = append(sorted, newlineToken())
+	}
+
+	for i, idx := range order {
+		elem := elements[idx]
+		spaces := 0
+		if i > 0 && !multiLine {
+			spaces = 1
+		}
+		sorted = append(sorted, copyToken(elem[0], spaces))
+		sorted = append(sorted, elem[1:]...)
+
+		switch {
+		case multiLine:
+			sorted = append(sorted, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")}, newlineToken())
+		case i < len(order)-1:
+			sorted = append(sorted, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")})
+		}
+	}
+
+	return append(sorted, copyToken(tokens[len(tokens)-1], 0))
+}
+
+// isSortableElement reports whether a list element is a literal string without
+// interpolation or a simple traversal of dot-separated identifiers.
+func isSortableElement(elem hclwrite.Tokens) bool {
+	if len(elem) == 0 {
+		return false
+	}
+
+	if elem[0].Type == hclsyntax.TokenOQuote {
+		switch len(elem) {
+		case 2:
+			return elem[1].Type == hclsyntax.TokenCQuote
+		case 3:
+			// Interpolations are lexed as separate tokens, so a single
+			// literal token means the string has none.
+			return elem[1].Type == hclsyntax.TokenQuotedLit && elem[2].Type == hclsyntax.TokenCQuote
+		default:
+			return false
+		}
+	}
+
+	// ident ( "." ident )*
+	for i, tok := range elem {
+		want := hclsyntax.TokenIdent
+		if i%2 == 1 {
+			want = hclsyntax.TokenDot
+		}
+		if tok.Type != want {
+			return false
+		}
+	}
+	return elem[len(elem)-1].Type == hclsyntax.TokenIdent
+}
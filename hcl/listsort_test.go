@@ -0,0 +1,177 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// TestSortListValues tests sorting of set-like list attributes
+func TestSortListValues(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		wantChanged int
+	}{
+		{
+			name: "traversals",
+			input: `resource "aws_instance" "web" {
+  depends_on = [aws_subnet.b, aws_iam_role.a, module.vpc]
+}
+`,
+			expected: `resource "aws_instance" "web" {
+  depends_on = [aws_iam_role.a, aws_subnet.b, module.vpc]
+}
+`,
+			wantChanged: 1,
+		},
+		{
+			name: "literal strings multi-line",
+			input: `resource "aws_instance" "web" {
+  security_group_ids = [
+    "sg-2",
+    "sg-1",
+    "sg-3",
+  ]
+}
+`,
+			expected: `resource "aws_instance" "web" {
+  security_group_ids = [
+    "sg-1",
+    "sg-2",
+    "sg-3",
+  ]
+}
+`,
+			wantChanged: 1,
+		},
+		{
+			name: "nested block",
+			input: `resource "aws_lb" "web" {
+  subnet_mapping {
+    availability_zones = ["us-east-1b", "us-east-1a"]
+  }
+}
+`,
+			expected: `resource "aws_lb" "web" {
+  subnet_mapping {
+    availability_zones = ["us-east-1a", "us-east-1b"]
+  }
+}
+`,
+			wantChanged: 1,
+		},
+		{
+			name: "unsortable element leaves list untouched",
+			input: `resource "aws_instance" "web" {
+  security_group_ids = ["sg-2", aws_security_group.web.id, var.extra[0]]
+}
+`,
+			expected: `resource "aws_instance" "web" {
+  security_group_ids = ["sg-2", aws_security_group.web.id, var.extra[0]]
+}
+`,
+		},
+		{
+			name: "interpolated string leaves list untouched",
+			input: `resource "aws_instance" "web" {
+  cidr_blocks = ["10.1.0.0/16", "${var.cidr}"]
+}
+`,
+			expected: `resource "aws_instance" "web" {
+  cidr_blocks = ["10.1.0.0/16", "${var.cidr}"]
+}
+`,
+		},
+		{
+			name: "attribute not in list",
+			input: `resource "aws_instance" "web" {
+  ordered = ["b", "a"]
+}
+`,
+			expected: `resource "aws_instance" "web" {
+  ordered = ["b", "a"]
+}
+`,
+		},
+		{
+			name: "already sorted",
+			input: `resource "aws_instance" "web" {
+  depends_on = [aws_iam_role.a, aws_subnet.b]
+}
+`,
+			expected: `resource "aws_instance" "web" {
+  depends_on = [aws_iam_role.a, aws_subnet.b]
+}
+`,
+		},
+	}
+
+	names := []string{"depends_on", "security_group_ids", "availability_zones", "cidr_blocks"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, diags := hclwrite.ParseConfig([]byte(tt.input), "test.tf", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("parse failed: %v", diags)
+			}
+
+			changed := SortListValues(file, names)
+			if changed != tt.wantChanged {
+				t.Errorf("SortListValues() = %d, want %d", changed, tt.wantChanged)
+			}
+
+			if got := string(file.Bytes()); got != tt.expected {
+				t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSortListValues_NoNames tests that nothing is sorted without attribute names
+func TestSortListValues_NoNames(t *testing.T) {
+	input := `resource "aws_instance" "web" {
+  depends_on = [b.b, a.a]
+}
+`
+	file, diags := hclwrite.ParseConfig([]byte(input), "test.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("parse failed: %v", diags)
+	}
+
+	if changed := SortListValues(file, nil); changed != 0 {
+		t.Errorf("expected no changes, got %d", changed)
+	}
+	if changed := SortListValues(nil, []string{"depends_on"}); changed != 0 {
+		t.Errorf("expected no changes for nil file, got %d", changed)
+	}
+}
+
+// TestSortAndFormatHCLFileWithOptions_SortListAttributes tests list sorting combined with sorting
+func TestSortAndFormatHCLFileWithOptions_SortListAttributes(t *testing.T) {
+	input := `resource "aws_instance" "web" {
+  depends_on = [aws_subnet.b, aws_iam_role.a]
+  ami = "ami-12345"
+}
+`
+	expected := `resource "aws_instance" "web" {
+  ami        = "ami-12345"
+  depends_on = [aws_iam_role.a, aws_subnet.b]
+}
+`
+
+	file, diags := hclwrite.ParseConfig([]byte(input), "test.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("parse failed: %v", diags)
+	}
+
+	formatted, _, err := SortAndFormatHCLFileWithOptions(file, SortOptions{SortListAttributes: []string{"depends_on"}})
+	if err != nil {
+		t.Fatalf("SortAndFormatHCLFileWithOptions failed: %v", err)
+	}
+	if formatted != expected {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", formatted, expected)
+	}
+}
@@ -0,0 +1,155 @@
+package hcl
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// declaration is a named object declared in a module, such as a resource or variable.
+type declaration struct {
+	kind    string    // Human-readable kind (e.g., "resource", "local value")
+	address string    // Address unique within the module (e.g., "aws_s3_bucket.logs", "var.region")
+	name    string    // The declaration as written (e.g., `variable "region"`)
+	rng     hcl.Range // Where it is declared
+}
+
+// ValidateModule checks the files of one module together for objects declared
+// more than once: resources, data sources, module calls, variables, outputs,
+// provider configurations (by name and alias), and local values. Duplicates
+// within a single file are found as well. Override files (override.tf and
+// *_override.tf) are skipped: Terraform merges their blocks into the blocks
+// they redeclare, so redeclaring is what they are for.
+//
+// Each duplicate is reported at its second declaration, with the location of
+// the first in the detail. Blocks with the wrong number of labels are skipped;
+// ValidateRequiredBlockLabels reports those.
+//
+// Returns an HCLError with KindValidation whose Err is a *DiagnosticsError
+// holding the diagnostics and the sources of all files, or nil if every
+// address is unique.
+func ValidateModule(files []*ParsedFile) error {
+	var diags hcl.Diagnostics
+	sources := make(map[string][]byte, len(files))
+	seen := make(map[string]hcl.Range)
+
+	for _, pf := range files {
+		if pf == nil || pf.File == nil {
+			continue
+		}
+		body, ok := pf.File.Body.(*hclsyntax.Body)
+		if !ok || isOverrideFile(pf.File.Body.MissingItemRange().Filename) {
+			continue
+		}
+		for name, src := range fileSources(pf) {
+			sources[name] = src
+		}
+
+		for _, block := range body.Blocks {
+			for _, decl := range blockDeclarations(block) {
+				first, dup := seen[decl.address]
+				if !dup {
+					seen[decl.address] = decl.rng
+					continue
+				}
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate " + decl.kind,
+					Detail: fmt.Sprintf("%s was already declared at %s:%d:%d.",
+						decl.name, first.Filename, first.Start.Line, first.Start.Column),
+					Subject: decl.rng.Ptr(),
+				})
+			}
+		}
+	}
+
+	if len(diags) == 0 {
+		return nil
+	}
+
+	return &HCLError{
+		Op:   "ValidateModule",
+		Kind: KindValidation,
+		Err:  &DiagnosticsError{Diags: diags, Sources: sources},
+	}
+}
+
+// blockDeclarations returns the objects a top-level block declares.
+func blockDeclarations(block *hclsyntax.Block) []declaration {
+	labels := block.Labels
+	decl := declaration{name: blockName(block.Type, labels...), rng: block.DefRange()}
+
+	switch {
+	case block.Type == "resource" && len(labels) == 2:
+		decl.kind, decl.address = "resource", labels[0]+"."+labels[1]
+	case block.Type == "data" && len(labels) == 2:
+		decl.kind, decl.address = "data source", "data."+labels[0]+"."+labels[1]
+	case block.Type == "module" && len(labels) == 1:
+		decl.kind, decl.address = "module call", "module."+labels[0]
+	case block.Type == "variable" && len(labels) == 1:
+		decl.kind, decl.address = "variable", "var."+labels[0]
+	case block.Type == "output" && len(labels) == 1:
+		decl.kind, decl.address = "output", "output."+labels[0]
+	case block.Type == "provider" && len(labels) == 1:
+		decl.kind, decl.address = "provider configuration", "provider."+labels[0]
+		if alias := providerAlias(block); alias != "" {
+			decl.address += "." + alias
+			decl.name += fmt.Sprintf(" with alias %q", alias)
+		}
+	case block.Type == "locals":
+		decls := make([]declaration, 0, len(block.Body.Attributes))
+		for _, attr := range sortedAttributes(block.Body) {
+			decls = append(decls, declaration{
+				kind:    "local value",
+				address: "local." + attr.Name,
+				name:    fmt.Sprintf("local value %q", attr.Name),
+				rng:     attr.NameRange,
+			})
+		}
+		return decls
+	default:
+		return nil
+	}
+	return []declaration{decl}
+}
+
+// blockName returns a block header as written, such as `resource "aws_s3_bucket" "logs"`.
+func blockName(blockType string, labels ...string) string {
+	name := blockType
+	for _, label := range labels {
+		name += fmt.Sprintf(" %q", label)
+	}
+	return name
+}
+
+// providerAlias returns the alias of a provider block, or "" if it has none
+// or the alias is not a literal string.
+func providerAlias(block *hclsyntax.Block) string {
+	attr, ok := block.Body.Attributes["alias"]
+	if !ok {
+		return ""
+	}
+	tmpl, ok := attr.Expr.(*hclsyntax.TemplateExpr)
+	if !ok || !tmpl.IsStringLiteral() {
+		return ""
+	}
+	val, diags := tmpl.Value(nil)
+	if diags.HasErrors() {
+		return ""
+	}
+	return val.AsString()
+}
+
+// sortedAttributes returns the attributes of body in source order.
+func sortedAttributes(body *hclsyntax.Body) []*hclsyntax.Attribute {
+	attrs := make([]*hclsyntax.Attribute, 0, len(body.Attributes))
+	for _, attr := range body.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].SrcRange.Start.Byte < attrs[j].SrcRange.Start.Byte
+	})
+	return attrs
+}
@@ -0,0 +1,184 @@
+package hcl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// parseFiles writes and parses a module's files in name order
+func parseFiles(t *testing.T, sources map[string]string, names ...string) []*ParsedFile {
+	t.Helper()
+
+	dir := t.TempDir()
+	parsed := make([]*ParsedFile, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		//nolint:gosec // G306: Test files can use 0644 permissions
+		if err := os.WriteFile(path, []byte(sources[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		pf, err := ParseHCLFile(path)
+		if err != nil {
+			t.Fatalf("parse %s failed: %v", name, err)
+		}
+		parsed = append(parsed, pf)
+	}
+	return parsed
+}
+
+// TestValidateModule tests detection of duplicate addresses across files
+func TestValidateModule(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources map[string]string
+		wantErr []string
+	}{
+		{
+			name: "unique addresses",
+			sources: map[string]string{
+				"a.tf": `resource "aws_s3_bucket" "logs" {}
+data "aws_s3_bucket" "logs" {}
+provider "aws" {}
+provider "aws" {
+  alias = "east"
+}
+locals {
+  name = "a"
+}
+`,
+				"b.tf": `resource "aws_s3_bucket" "data" {}
+variable "logs" {}
+output "logs" {
+  value = 1
+}
+module "logs" {
+  source = "./logs"
+}
+locals {
+  other = "b"
+}
+`,
+			},
+		},
+		{
+			name: "duplicates across files",
+			sources: map[string]string{
+				"a.tf": `resource "aws_s3_bucket" "logs" {}
+variable "region" {}
+locals {
+  name = "a"
+}
+provider "aws" {
+  alias = "east"
+}
+`,
+				"b.tf": `variable "region" {}
+resource "aws_s3_bucket" "logs" {}
+locals {
+  name = "b"
+}
+provider "aws" {
+  alias = "east"
+}
+`,
+			},
+			wantErr: []string{
+				`Duplicate variable: variable "region" was already declared at %a.tf:2:1.`,
+				`Duplicate resource: resource "aws_s3_bucket" "logs" was already declared at %a.tf:1:1.`,
+				`Duplicate local value: local value "name" was already declared at %a.tf:4:3.`,
+				`Duplicate provider configuration: provider "aws" with alias "east" was already declared at %a.tf:6:1.`,
+			},
+		},
+		{
+			name: "duplicates within a file",
+			sources: map[string]string{
+				"a.tf": `output "id" {
+  value = 1
+}
+data "aws_ami" "ubuntu" {}
+module "vpc" {
+  source = "./vpc"
+}
+provider "aws" {}
+output "id" {
+  value = 2
+}
+data "aws_ami" "ubuntu" {}
+module "vpc" {
+  source = "./vpc"
+}
+provider "aws" {}
+`,
+				"b.tf": ``,
+			},
+			wantErr: []string{
+				`Duplicate output: output "id" was already declared at %a.tf:1:1.`,
+				`Duplicate data source: data "aws_ami" "ubuntu" was already declared at %a.tf:4:1.`,
+				`Duplicate module call: module "vpc" was already declared at %a.tf:5:1.`,
+				`Duplicate provider configuration: provider "aws" was already declared at %a.tf:8:1.`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := parseFiles(t, tt.sources, "a.tf", "b.tf")
+			dir := filepath.Dir(files[0].File.Body.MissingItemRange().Filename)
+
+			err := ValidateModule(files)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if !IsValidationError(err) {
+				t.Fatalf("expected validation error, got %v", err)
+			}
+			diags, sources := ErrorDiagnostics(err)
+			if len(sources) != 2 {
+				t.Errorf("expected sources of both files, got %d", len(sources))
+			}
+			if len(diags) != len(tt.wantErr) {
+				t.Fatalf("expected %d diagnostics, got %d: %v", len(tt.wantErr), len(diags), diags)
+			}
+			for i, want := range tt.wantErr {
+				want = strings.ReplaceAll(want, "%", dir+string(filepath.Separator))
+				got := diags[i].Summary + ": " + diags[i].Detail
+				if got != want {
+					t.Errorf("diagnostic %d:\ngot:  %s\nwant: %s", i, got, want)
+				}
+				if diags[i].Subject == nil || filepath.Base(diags[i].Subject.Filename) == "" {
+					t.Errorf("diagnostic %d has no range", i)
+				}
+			}
+		})
+	}
+}
+
+// TestValidateModule_OverrideFiles tests that override files may redeclare
+// the blocks of other files
+func TestValidateModule_OverrideFiles(t *testing.T) {
+	sources := map[string]string{
+		"main.tf":         "variable \"region\" {}\n",
+		"override.tf":     "variable \"region\" {\n  default = \"eu-west-1\"\n}\n",
+		"dev_override.tf": "variable \"region\" {\n  default = \"us-east-1\"\n}\n",
+	}
+	files := parseFiles(t, sources, "main.tf", "override.tf", "dev_override.tf")
+	if err := ValidateModule(files); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestValidateModule_Empty tests that no files means no duplicates
+func TestValidateModule_Empty(t *testing.T) {
+	if err := ValidateModule(nil); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	if err := ValidateModule([]*ParsedFile{nil, {}}); err != nil {
+		t.Errorf("expected nil for nil files, got %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package hcl
+
+import (
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// RewriteKind identifies which normalization rule produced a Rewrite.
+type RewriteKind string
+
+// Rewrite kind constants for the normalization pass.
+const (
+	// RewriteUnwrapInterpolation replaces "${expr}" with expr.
+	RewriteUnwrapInterpolation RewriteKind = "unwrap-interpolation"
+	// RewriteQuotedType replaces a quoted legacy type constraint such as "string" with a bare type.
+	RewriteQuotedType RewriteKind = "quoted-type"
+	// RewriteCollectionType replaces a bare list or map type with list(any) or map(any).
+	RewriteCollectionType RewriteKind = "collection-type"
+)
+
+// Rewrite describes a single expression change made by the normalization pass.
+// Rewrites are reported so reviewers can see exactly what was changed.
+type Rewrite struct {
+	Kind      RewriteKind // Rule that produced the rewrite
+	Address   string      // Block address (e.g., "variable.region" or "resource.aws_instance.web.ebs_block_device")
+	Attribute string      // Attribute name within the block
+	Before    string      // Expression source before the rewrite
+	After     string      // Expression source after the rewrite
+}
+
+// String returns a one-line description of the rewrite suitable for display.
+func (r Rewrite) String() string {
+	return r.Address + "." + r.Attribute + ": " + r.Before + " → " + r.After
+}
+
+// legacyTypeKeywords maps legacy type constraints to their modern equivalents.
+// Quoted forms ("string") were required before Terraform 0.12; bare list and map
+// are deprecated shorthands for collections of any element type.
+var legacyTypeKeywords = map[string]string{
+	"string": "string",
+	"number": "number",
+	"bool":   "bool",
+	"any":    "any",
+	"list":   "list(any)",
+	"map":    "map(any)",
+}
+
+// NormalizeHCLFile rewrites legacy expression syntax in place.
+//
+// It applies the following rules to every attribute in the file:
+//   - "${expr}" with a single interpolation and nothing else is unwrapped to expr
+//   - quoted type constraints in variable blocks (type = "string") become bare types
+//   - bare list and map type constraints become list(any) and map(any)
+//
+// Interpolations with surrounding text (e.g., "${path.module}/x") are left untouched.
+// Returns the rewrites that were applied, in block and attribute order.
+func NormalizeHCLFile(file *hclwrite.File) []Rewrite {
+	if file == nil {
+		return nil
+	}
+
+	var rewrites []Rewrite
+	for _, block := range file.Body().Blocks() {
+		normalizeBlock(block, "", &rewrites)
+	}
+	return rewrites
+}
+
+// normalizeBlock applies normalization rules to a block and its nested blocks.
+// The parent address is prefixed to the block's own address for reporting.
+func normalizeBlock(block *hclwrite.Block, parent string, rewrites *[]Rewrite) {
+	address := blockAddress(block)
+	if parent != "" {
+		address = parent + "." + address
+	}
+
+	body := block.Body()
+	attributes := body.Attributes()
+	names := SortAttributes(attributes)
+
+	for _, name := range names {
+		tokens := attributes[name].Expr().BuildTokens(nil)
+
+		var kind RewriteKind
+		var replacement hclwrite.Tokens
+
+		if parent == "" && name == "type" && getBlockType(block.Type()) == BlockTypeVariable {
+			kind, replacement = normalizeTypeConstraint(tokens)
+		}
+		if replacement == nil {
+			if inner := unwrapInterpolation(tokens); inner != nil {
+				kind, replacement = RewriteUnwrapInterpolation, inner
+			}
+		}
+		if replacement == nil {
+			continue
+		}
+
+		body.SetAttributeRaw(name, replacement)
+		*rewrites = append(*rewrites, Rewrite{
+			Kind:      kind,
+			Address:   address,
+			Attribute: name,
+			Before:    tokensString(tokens),
+			After:     tokensString(replacement),
+		})
+	}
+
+	for _, nested := range body.Blocks() {
+		normalizeBlock(nested, address, rewrites)
+	}
+}
+
+// normalizeTypeConstraint rewrites quoted and bare legacy type constraints.
+// Returns a nil token slice if the expression is not a legacy type constraint.
+func normalizeTypeConstraint(tokens hclwrite.Tokens) (RewriteKind, hclwrite.Tokens) {
+	switch {
+	case len(tokens) == 3 &&
+		tokens[0].Type == hclsyntax.TokenOQuote &&
+		tokens[1].Type == hclsyntax.TokenQuotedLit &&
+		tokens[2].Type == hclsyntax.TokenCQuote:
+		modern, ok := legacyTypeKeywords[string(tokens[1].Bytes)]
+		if !ok {
+			return "", nil
+		}
+		return RewriteQuotedType, typeTokens(modern)
+	case len(tokens) == 1 && tokens[0].Type == hclsyntax.TokenIdent:
+		name := string(tokens[0].Bytes)
+		if name != "list" && name != "map" {
+			return "", nil
+		}
+		return RewriteCollectionType, typeTokens(legacyTypeKeywords[name])
+	}
+	return "", nil
+}
+
+// typeTokens builds the tokens for a simple type expression such as string or list(any).
+func typeTokens(typeExpr string) hclwrite.Tokens {
+	name, arg, isCall := strings.Cut(strings.TrimSuffix(typeExpr, ")"), "(")
+	tokens := hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(name), SpacesBefore: 1}}
+	if isCall {
+		tokens = append(tokens,
+			&hclwrite.Token{Type: hclsyntax.TokenOParen, Bytes: []byte("(")},
+			&hclwrite.Token{Type: hclsyntax.TokenIdent, Bytes: []byte(arg)},
+			&hclwrite.Token{Type: hclsyntax.TokenCParen, Bytes: []byte(")")},
+		)
+	}
+	return tokens
+}
+
+// unwrapInterpolation returns the inner expression tokens of a template that
+// consists of exactly one interpolation sequence, such as "${var.name}".
+// Returns nil if the template has any literal text, more than one sequence,
+// or strip markers, since unwrapping would change its meaning.
+func unwrapInterpolation(tokens hclwrite.Tokens) hclwrite.Tokens {
+	n := len(tokens)
+	if n < 5 ||
+		tokens[0].Type != hclsyntax.TokenOQuote ||
+		tokens[1].Type != hclsyntax.TokenTemplateInterp ||
+		tokens[n-2].Type != hclsyntax.TokenTemplateSeqEnd ||
+		tokens[n-1].Type != hclsyntax.TokenCQuote {
+		return nil
+	}
+
+	// Strip markers (${~ and ~}) trim surrounding literal text, so they only
+	// make sense inside a larger template.
+	if strings.Contains(string(tokens[1].Bytes), "~") || strings.Contains(string(tokens[n-2].Bytes), "~") {
+		return nil
+	}
+
+	inner := tokens[2 : n-2]
+	if len(inner) == 0 {
+		return nil
+	}
+
+	// The interpolation opened at tokens[1] must be the one closed at tokens[n-2].
+	depth := 0
+	for _, tok := range inner {
+		switch tok.Type {
+		case hclsyntax.TokenTemplateInterp, hclsyntax.TokenTemplateControl:
+			depth++
+		case hclsyntax.TokenTemplateSeqEnd:
+			depth--
+			if depth < 0 {
+				return nil
+			}
+		}
+	}
+	if depth != 0 {
+		return nil
+	}
+
+	unwrapped := make(hclwrite.Tokens, len(inner))
+	for i, tok := range inner {
+		copied := *tok
+		unwrapped[i] = &copied
+	}
+	unwrapped[0].SpacesBefore = 1
+	return unwrapped
+}
+
+// blockAddress returns a dotted address for a block built from its type and labels.
+func blockAddress(block *hclwrite.Block) string {
+	parts := append([]string{block.Type()}, block.Labels()...)
+	return strings.Join(parts, ".")
+}
+
+// tokensString renders tokens as source text without leading or trailing whitespace.
+func tokensString(tokens hclwrite.Tokens) string {
+	return strings.TrimSpace(string(tokens.Bytes()))
+}
@@ -0,0 +1,185 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// TestNormalizeHCLFile tests each normalization rule on a single attribute
+func TestNormalizeHCLFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		kind     RewriteKind
+	}{
+		{
+			name:     "unwrap single interpolation",
+			input:    "resource \"aws_instance\" \"web\" {\n  ami = \"${var.ami}\"\n}\n",
+			expected: "ami = var.ami",
+			kind:     RewriteUnwrapInterpolation,
+		},
+		{
+			name:     "unwrap function call interpolation",
+			input:    "resource \"aws_instance\" \"web\" {\n  tags = \"${merge(var.tags, local.tags)}\"\n}\n",
+			expected: "tags = merge(var.tags, local.tags)",
+			kind:     RewriteUnwrapInterpolation,
+		},
+		{
+			name:     "quoted string type",
+			input:    "variable \"region\" {\n  type = \"string\"\n}\n",
+			expected: "type = string",
+			kind:     RewriteQuotedType,
+		},
+		{
+			name:     "quoted list type",
+			input:    "variable \"zones\" {\n  type = \"list\"\n}\n",
+			expected: "type = list(any)",
+			kind:     RewriteQuotedType,
+		},
+		{
+			name:     "bare map type",
+			input:    "variable \"tags\" {\n  type = map\n}\n",
+			expected: "type = map(any)",
+			kind:     RewriteCollectionType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, diags := hclwrite.ParseConfig([]byte(tt.input), "test.tf", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("parse failed: %v", diags)
+			}
+
+			rewrites := NormalizeHCLFile(file)
+			if len(rewrites) != 1 {
+				t.Fatalf("expected 1 rewrite, got %d: %v", len(rewrites), rewrites)
+			}
+			if rewrites[0].Kind != tt.kind {
+				t.Errorf("expected kind %q, got %q", tt.kind, rewrites[0].Kind)
+			}
+
+			output := string(hclwrite.Format(file.Bytes()))
+			if !strings.Contains(output, tt.expected) {
+				t.Errorf("expected output to contain %q, got:\n%s", tt.expected, output)
+			}
+		})
+	}
+}
+
+// TestNormalizeHCLFile_Untouched tests expressions that must not be rewritten
+func TestNormalizeHCLFile_Untouched(t *testing.T) {
+	input := `variable "name" {
+  type    = string
+  default = "${path.module}/x"
+}
+
+resource "aws_instance" "web" {
+  ami  = "${var.a}${var.b}"
+  name = "${var.prefix}-web"
+  trim = "${~var.x~}"
+  type = "string"
+  zone = "us-east-1a"
+}
+
+locals {
+  zones = list
+}
+`
+
+	file, diags := hclwrite.ParseConfig([]byte(input), "test.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("parse failed: %v", diags)
+	}
+
+	rewrites := NormalizeHCLFile(file)
+	if len(rewrites) != 0 {
+		t.Errorf("expected no rewrites, got %v", rewrites)
+	}
+	if string(file.Bytes()) != input {
+		t.Errorf("file was modified:\n%s", file.Bytes())
+	}
+}
+
+// TestNormalizeHCLFile_NestedBlocks tests rewrites in nested blocks and their addresses
+func TestNormalizeHCLFile_NestedBlocks(t *testing.T) {
+	input := `resource "aws_instance" "web" {
+  root_block_device {
+    volume_type = "${var.volume_type}"
+  }
+}
+`
+
+	file, diags := hclwrite.ParseConfig([]byte(input), "test.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("parse failed: %v", diags)
+	}
+
+	rewrites := NormalizeHCLFile(file)
+	if len(rewrites) != 1 {
+		t.Fatalf("expected 1 rewrite, got %d", len(rewrites))
+	}
+
+	want := `resource.aws_instance.web.root_block_device.volume_type: "${var.volume_type}" → var.volume_type`
+	if got := rewrites[0].String(); got != want {
+		t.Errorf("Rewrite.String() = %q, want %q", got, want)
+	}
+}
+
+// TestNormalizeHCLFile_Nil tests that a nil file is handled gracefully
+func TestNormalizeHCLFile_Nil(t *testing.T) {
+	if rewrites := NormalizeHCLFile(nil); rewrites != nil {
+		t.Errorf("expected nil rewrites for nil file, got %v", rewrites)
+	}
+}
+
+// TestSortAndFormatHCLFileWithOptions_Normalize tests normalization combined with sorting
+func TestSortAndFormatHCLFileWithOptions_Normalize(t *testing.T) {
+	input := `resource "aws_instance" "web" {
+  instance_type = "${var.instance_type}"
+  ami = "ami-12345"
+}
+
+variable "instance_type" {
+  type = "string"
+}
+`
+
+	file, diags := hclwrite.ParseConfig([]byte(input), "test.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("parse failed: %v", diags)
+	}
+
+	formatted, rewrites, err := SortAndFormatHCLFileWithOptions(file, SortOptions{Normalize: true})
+	if err != nil {
+		t.Fatalf("SortAndFormatHCLFileWithOptions failed: %v", err)
+	}
+
+	if len(rewrites) != 2 {
+		t.Fatalf("expected 2 rewrites, got %d: %v", len(rewrites), rewrites)
+	}
+	if rewrites[0].Address != "variable.instance_type" {
+		t.Errorf("expected first rewrite in variable block (sorted order), got %q", rewrites[0].Address)
+	}
+	if strings.Contains(formatted, "${") || strings.Contains(formatted, `"string"`) {
+		t.Errorf("legacy syntax remains in output:\n%s", formatted)
+	}
+
+	// The input file must not be modified
+	if !strings.Contains(string(file.Bytes()), "${var.instance_type}") {
+		t.Error("input file was modified by normalization")
+	}
+
+	// Without the option, nothing is rewritten
+	plain, rewrites, err := SortAndFormatHCLFileWithOptions(file, SortOptions{})
+	if err != nil {
+		t.Fatalf("SortAndFormatHCLFileWithOptions failed: %v", err)
+	}
+	if len(rewrites) != 0 || !strings.Contains(plain, "${var.instance_type}") {
+		t.Errorf("expected no normalization without option, got rewrites %v", rewrites)
+	}
+}
@@ -1,8 +1,11 @@
 package hcl
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
+
+	"github.com/obergerkatz/sortTF/vfs"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
@@ -24,6 +27,11 @@ type ParsedFile struct {
 // If parsing fails, the error will be of type *HCLParseError.
 // The ParsedFile is always returned, even on error, to allow inspection of partial results.
 func ParseHCLFile(path string) (*ParsedFile, error) {
+	return ParseHCLFileFS(vfs.OSFS{}, path)
+}
+
+// ParseHCLFileFS is like ParseHCLFile but reads the file from fsys.
+func ParseHCLFileFS(fsys fs.FS, path string) (*ParsedFile, error) {
 	if path == "" {
 		return nil, &HCLError{
 			Op:   "ParseHCLFile",
@@ -33,7 +41,7 @@ func ParseHCLFile(path string) (*ParsedFile, error) {
 	}
 
 	// Validate file exists and is accessible
-	if err := validateFilePath(path); err != nil {
+	if err := validateFilePath(fsys, path); err != nil {
 		return nil, &HCLError{
 			Op:   "ParseHCLFile",
 			Path: path,
@@ -42,8 +50,7 @@ func ParseHCLFile(path string) (*ParsedFile, error) {
 		}
 	}
 
-	parser := hclparse.NewParser()
-	src, err := os.ReadFile(path) // #nosec G304 -- File path comes from user input, which is expected for a file processing tool
+	src, err := fs.ReadFile(fsys, path)
 	if err != nil {
 		return nil, &HCLError{
 			Op:   "ParseHCLFile",
@@ -53,7 +60,17 @@ func ParseHCLFile(path string) (*ParsedFile, error) {
 		}
 	}
 
-	file, diags := parser.ParseHCL(src, path)
+	return ParseHCL(src, path)
+}
+
+// ParseHCL parses HCL source that has already been read into memory.
+//
+// The filename is only used in diagnostics and error messages. Like
+// ParseHCLFile, it returns a *HCLParseError if parsing fails, and the
+// ParsedFile is always returned.
+func ParseHCL(src []byte, filename string) (*ParsedFile, error) {
+	parser := hclparse.NewParser()
+	file, diags := parser.ParseHCL(src, filename)
 
 	// Always return a ParsedFile, but include diagnostics
 	parsedFile := &ParsedFile{File: file, Body: file.Body, Diags: diags}
@@ -61,8 +78,9 @@ func ParseHCLFile(path string) (*ParsedFile, error) {
 	// If there are parsing errors, return them as a specific error type
 	if diags.HasErrors() {
 		return parsedFile, &HCLParseError{
-			Path:  path,
-			Diags: diags,
+			Path:   filename,
+			Diags:  diags,
+			Source: src,
 		}
 	}
 
@@ -77,7 +95,13 @@ func ParseHCLFile(path string) (*ParsedFile, error) {
 //   - locals, terraform: require no labels
 //   - backend: must have 1 label and appear inside a terraform block
 //
-// Returns an HCLError with KindValidation if any blocks have incorrect label counts.
+// Nested meta-blocks are checked too: dynamic and provisioner blocks require
+// 1 label, while lifecycle, connection, validation, cloud and
+// required_providers blocks take none.
+//
+// Every problem in the file is reported, not just the first. Returns an
+// HCLError with KindValidation whose Err is a *DiagnosticsError describing
+// each problem with its source range; use ValidationDiagnostics to extract them.
 func ValidateRequiredBlockLabels(pf *ParsedFile) error {
 	if pf == nil || pf.File == nil {
 		return &HCLError{
@@ -96,79 +120,281 @@ func ValidateRequiredBlockLabels(pf *ParsedFile) error {
 		}
 	}
 
+	var diags hcl.Diagnostics
 	for _, block := range syntaxBody.Blocks {
-		switch block.Type {
-		case "resource", "data":
-			if len(block.Labels) != 2 {
-				return &HCLError{
-					Op:   "ValidateRequiredBlockLabels",
-					Kind: KindValidation,
-					Err:  fmt.Errorf("%s block must have exactly 2 labels, got %d", block.Type, len(block.Labels)),
-				}
-			}
-		case "module", "provider", "variable", "output":
-			if len(block.Labels) != 1 {
-				return &HCLError{
-					Op:   "ValidateRequiredBlockLabels",
-					Kind: KindValidation,
-					Err:  fmt.Errorf("%s block must have exactly 1 label, got %d", block.Type, len(block.Labels)),
-				}
-			}
-		case "locals", "terraform":
-			if len(block.Labels) != 0 {
-				return &HCLError{
-					Op:   "ValidateRequiredBlockLabels",
-					Kind: KindValidation,
-					Err:  fmt.Errorf("%s block should not have labels: got %d", block.Type, len(block.Labels)),
-				}
-			}
-		case "backend":
-			// Backend blocks should only appear inside terraform blocks
-			if len(block.Labels) != 1 {
-				return &HCLError{
-					Op:   "ValidateRequiredBlockLabels",
-					Kind: KindValidation,
-					Err:  fmt.Errorf("%s block must have exactly 1 label, got %d", block.Type, len(block.Labels)),
-				}
-			}
-			return &HCLError{
-				Op:   "ValidateRequiredBlockLabels",
-				Kind: KindValidation,
-				Err:  fmt.Errorf("backend block must be inside a terraform block"),
-			}
+		diags = append(diags, checkTopLevelBlock(block)...)
+	}
+	if len(diags) == 0 {
+		return nil
+	}
+
+	return &HCLError{
+		Op:   "ValidateRequiredBlockLabels",
+		Path: diags[0].Subject.Filename,
+		Kind: KindValidation,
+		Err:  &DiagnosticsError{Diags: diags, Sources: fileSources(pf)},
+	}
+}
+
+// topLevelLabels is the number of labels required by each top-level Terraform block type.
+var topLevelLabels = map[string]int{
+	"resource":  2,
+	"data":      2,
+	"module":    1,
+	"provider":  1,
+	"variable":  1,
+	"output":    1,
+	"locals":    0,
+	"terraform": 0,
+}
+
+// nestedLabels is the number of labels required by meta-blocks nested
+// directly inside a block of the given type. Other nested blocks are
+// provider-defined and not checked.
+var nestedLabels = map[string]map[string]int{
+	"resource":    {"lifecycle": 0, "connection": 0, "provisioner": 1},
+	"data":        {"lifecycle": 0},
+	"ephemeral":   {"lifecycle": 0},
+	"removed":     {"lifecycle": 0, "connection": 0, "provisioner": 1},
+	"provisioner": {"connection": 0},
+	"variable":    {"validation": 0},
+	"terraform":   {"backend": 1, "cloud": 0, "required_providers": 0},
+}
+
+// checkTopLevelBlock validates the labels of a top-level block and its nested blocks.
+func checkTopLevelBlock(block *hclsyntax.Block) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	if block.Type == "backend" {
+		// Backend blocks should only appear inside terraform blocks
+		if len(block.Labels) != 1 {
+			diags = append(diags, labelCountDiagnostic(block, 1, ""))
+		}
+		return append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Misplaced backend block",
+			Detail:   "backend block must be inside a terraform block",
+			Subject:  block.DefRange().Ptr(),
+		})
+	}
+
+	if want, ok := topLevelLabels[block.Type]; ok && len(block.Labels) != want {
+		diags = append(diags, labelCountDiagnostic(block, want, ""))
+	}
+
+	return append(diags, checkNestedBlocks(block)...)
+}
+
+// checkNestedBlocks validates the labels of the blocks nested anywhere inside parent.
+func checkNestedBlocks(parent *hclsyntax.Block) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	for _, inner := range parent.Body.Blocks {
+		want, ok := nestedLabels[parent.Type][inner.Type]
+		if inner.Type == "dynamic" {
+			want, ok = 1, true
+		}
+		if ok && len(inner.Labels) != want {
+			diags = append(diags, labelCountDiagnostic(inner, want, parent.Type))
+		}
+		diags = append(diags, checkNestedBlocks(inner)...)
+	}
+
+	return diags
+}
+
+// labelCountDiagnostic describes a block with the wrong number of labels.
+// If parent is not empty, the block is described as nested inside it.
+func labelCountDiagnostic(block *hclsyntax.Block, want int, parent string) *hcl.Diagnostic {
+	name := block.Type + " block"
+	if parent != "" {
+		name += " inside " + parent
+	}
+
+	var detail string
+	switch want {
+	case 0:
+		detail = fmt.Sprintf("%s should not have labels: got %d", name, len(block.Labels))
+	case 1:
+		detail = fmt.Sprintf("%s must have exactly 1 label, got %d", name, len(block.Labels))
+	default:
+		detail = fmt.Sprintf("%s must have exactly %d labels, got %d", name, want, len(block.Labels))
+	}
+
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Invalid block labels",
+		Detail:   detail,
+		Subject:  block.DefRange().Ptr(),
+	}
+}
+
+// ValidateSchema checks the bodies of core Terraform blocks for structural mistakes:
+//   - output blocks must set value
+//   - module blocks must set source
+//   - variable blocks may only contain type, default, description, sensitive,
+//     nullable, ephemeral and validation blocks
+//   - resource, data and module blocks must not set both count and for_each
+//   - lifecycle blocks may only appear inside resource and data blocks
+//
+// Like ValidateRequiredBlockLabels, every problem is reported. Returns an
+// HCLError with KindSchema whose Err is a *DiagnosticsError.
+func ValidateSchema(pf *ParsedFile) error {
+	if pf == nil || pf.File == nil {
+		return &HCLError{
+			Op:   "ValidateSchema",
+			Kind: KindSchema,
+			Err:  fmt.Errorf("parsed file is nil"),
+		}
+	}
+
+	syntaxBody, ok := pf.File.Body.(*hclsyntax.Body)
+	if !ok {
+		return &HCLError{
+			Op:   "ValidateSchema",
+			Kind: KindSchema,
+			Err:  fmt.Errorf("file body is not hclsyntax.Body"),
+		}
+	}
+
+	var diags hcl.Diagnostics
+	for _, block := range syntaxBody.Blocks {
+		diags = append(diags, checkBlockSchema(block)...)
+	}
+	if len(diags) == 0 {
+		return nil
+	}
+
+	return &HCLError{
+		Op:   "ValidateSchema",
+		Path: pf.File.Body.MissingItemRange().Filename,
+		Kind: KindSchema,
+		Err:  &DiagnosticsError{Diags: diags, Sources: fileSources(pf)},
+	}
+}
+
+// blockSchemas describe the bodies of core block types. Output and module
+// schemas are partial: only the listed attributes are checked.
+var blockSchemas = map[string]struct {
+	schema  *hcl.BodySchema
+	partial bool
+}{
+	"output": {
+		schema:  &hcl.BodySchema{Attributes: []hcl.AttributeSchema{{Name: "value", Required: true}}},
+		partial: true,
+	},
+	"module": {
+		schema:  &hcl.BodySchema{Attributes: []hcl.AttributeSchema{{Name: "source", Required: true}}},
+		partial: true,
+	},
+	"variable": {
+		schema: &hcl.BodySchema{
+			Attributes: []hcl.AttributeSchema{
+				{Name: "type"},
+				{Name: "default"},
+				{Name: "description"},
+				{Name: "sensitive"},
+				{Name: "nullable"},
+				{Name: "ephemeral"},
+			},
+			Blocks: []hcl.BlockHeaderSchema{{Type: "validation"}},
+		},
+	},
+}
+
+// lifecycleParents are the block types that may contain a lifecycle block.
+var lifecycleParents = map[string]bool{
+	"resource":  true,
+	"data":      true,
+	"ephemeral": true,
+	"removed":   true,
+}
+
+// lifecycleParentNames lists lifecycleParents for diagnostics.
+const lifecycleParentNames = "resource, data, ephemeral or removed block"
+
+// checkBlockSchema validates the body of a top-level block.
+func checkBlockSchema(block *hclsyntax.Block) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	if block.Type == "lifecycle" {
+		diags = append(diags, misplacedLifecycleDiagnostic(block, ""))
+	}
+
+	if bs, ok := blockSchemas[block.Type]; ok {
+		if bs.partial {
+			_, _, contentDiags := block.Body.PartialContent(bs.schema)
+			diags = append(diags, contentDiags...)
+		} else {
+			_, contentDiags := block.Body.Content(bs.schema)
+			diags = append(diags, contentDiags...)
+		}
+	}
+
+	switch block.Type {
+	case "resource", "data", "module":
+		count, hasCount := block.Body.Attributes["count"]
+		forEach, hasForEach := block.Body.Attributes["for_each"]
+		if hasCount && hasForEach {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  `Invalid combination of "count" and "for_each"`,
+				Detail:   fmt.Sprintf(`%s block must not set both "count" and "for_each"`, block.Type),
+				Subject:  forEach.NameRange.Ptr(),
+				Context:  hcl.RangeOver(count.SrcRange, forEach.SrcRange).Ptr(),
+			})
 		}
-		// Special case: backend block must be inside terraform block
-		if block.Type == "terraform" {
-			for _, inner := range block.Body.Blocks {
-				if inner.Type == "backend" && len(inner.Labels) != 1 {
-					return &HCLError{
-						Op:   "ValidateRequiredBlockLabels",
-						Kind: KindValidation,
-						Err:  fmt.Errorf("backend block inside terraform must have exactly 1 label, got %d", len(inner.Labels)),
-					}
-				}
+	}
+
+	if !lifecycleParents[block.Type] {
+		for _, inner := range block.Body.Blocks {
+			if inner.Type == "lifecycle" {
+				diags = append(diags, misplacedLifecycleDiagnostic(inner, block.Type))
 			}
 		}
 	}
-	return nil
+
+	return diags
+}
+
+// misplacedLifecycleDiagnostic describes a lifecycle block outside the
+// blocks in lifecycleParents. parent is the enclosing block type, or empty
+// at top level.
+func misplacedLifecycleDiagnostic(block *hclsyntax.Block, parent string) *hcl.Diagnostic {
+	detail := "lifecycle block must be inside a " + lifecycleParentNames
+	if parent != "" {
+		detail = fmt.Sprintf("lifecycle block is not allowed inside a %s block; it must be inside a %s", parent, lifecycleParentNames)
+	}
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Misplaced lifecycle block",
+		Detail:   detail,
+		Subject:  block.DefRange().Ptr(),
+	}
 }
 
 // Helper functions
 
+// fileSources returns the content of a parsed file keyed by its file name,
+// for rendering diagnostics.
+func fileSources(pf *ParsedFile) map[string][]byte {
+	return map[string][]byte{pf.File.Body.MissingItemRange().Filename: pf.File.Bytes}
+}
+
 // validateFilePath checks if a file path is valid and accessible.
 // It returns a user-friendly error message if the path is invalid,
 // doesn't exist, has permission issues, or is a directory.
-func validateFilePath(path string) error {
+func validateFilePath(fsys fs.FS, path string) error {
 	if path == "" {
 		return fmt.Errorf("empty path provided")
 	}
 
-	info, err := os.Stat(path)
+	info, err := fs.Stat(fsys, path)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return fmt.Errorf("file does not exist")
 		}
-		if os.IsPermission(err) {
+		if errors.Is(err, fs.ErrPermission) {
 			return fmt.Errorf("permission denied")
 		}
 		return fmt.Errorf("failed to access file: %w", err)
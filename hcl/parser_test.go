@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/obergerkatz/sortTF/vfs"
 )
 
 // TestParseHCLFile tests parsing valid HCL files
@@ -154,6 +156,66 @@ func TestParseHCLFile_Directory(t *testing.T) {
 	}
 }
 
+// TestParseHCL tests parsing in-memory source
+func TestParseHCL(t *testing.T) {
+	parsed, err := ParseHCL([]byte("variable \"region\" {}\n"), "mem.tf")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.File == nil || string(parsed.File.Bytes) != "variable \"region\" {}\n" {
+		t.Errorf("expected parsed file with source, got %+v", parsed)
+	}
+
+	parsed, err = ParseHCL([]byte("resource \"broken\" {\n"), "mem.tf")
+	var parseErr *HCLParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected *HCLParseError, got %T", err)
+	}
+	if parseErr.Path != "mem.tf" || parseErr.Source == nil {
+		t.Errorf("expected path and source in error, got %+v", parseErr)
+	}
+	if parsed == nil {
+		t.Error("expected ParsedFile even on error")
+	}
+}
+
+// TestParseHCLFileFS tests parsing files from an in-memory file system
+func TestParseHCLFileFS(t *testing.T) {
+	fsys := vfs.NewMemFS(map[string][]byte{
+		"main.tf":          []byte("variable \"region\" {}\n"),
+		"broken.tf":        []byte("resource \"broken\" {\n"),
+		"modules/vpc/a.tf": []byte("locals {}\n"),
+	})
+
+	tests := []struct {
+		name      string
+		path      string
+		wantErr   bool
+		wantParse bool
+	}{
+		{"valid file", "main.tf", false, false},
+		{"nested file", "modules/vpc/a.tf", false, false},
+		{"parse error", "broken.tf", true, true},
+		{"missing file", "missing.tf", true, false},
+		{"directory", "modules", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseHCLFileFS(fsys, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHCLFileFS(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			var parseErr *HCLParseError
+			if errors.As(err, &parseErr) != tt.wantParse {
+				t.Errorf("ParseHCLFileFS(%q) error = %T, want *HCLParseError: %v", tt.path, err, tt.wantParse)
+			}
+			if !tt.wantErr && parsed.File == nil {
+				t.Errorf("ParseHCLFileFS(%q) returned no file", tt.path)
+			}
+		})
+	}
+}
+
 // TestValidateRequiredBlockLabels tests block label validation
 func TestValidateRequiredBlockLabels(t *testing.T) {
 	tests := []struct {
@@ -318,6 +380,276 @@ func TestValidateRequiredBlockLabels(t *testing.T) {
 	}
 }
 
+// TestValidateRequiredBlockLabels_AllProblems tests that every problem is reported with its range
+func TestValidateRequiredBlockLabels_AllProblems(t *testing.T) {
+	content := `resource "aws_instance" {
+  ami = "ami-12345"
+
+  lifecycle "extra" {
+    create_before_destroy = true
+  }
+
+  dynamic {
+    content {}
+  }
+}
+
+variable "ok" {}
+
+locals "bad" {
+  name = "x"
+}
+
+output {
+  value = 1
+}
+`
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "test.tf")
+	//nolint:gosec // G306: Test files can use 0644 permissions
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseHCLFile(filePath)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	err = ValidateRequiredBlockLabels(parsed)
+	if !IsValidationError(err) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	diags := ValidationDiagnostics(err)
+	expected := []struct {
+		line   int
+		column int
+		detail string
+	}{
+		{1, 1, "resource block must have exactly 2 labels, got 1"},
+		{4, 3, "lifecycle block inside resource should not have labels: got 1"},
+		{8, 3, "dynamic block inside resource must have exactly 1 label, got 0"},
+		{15, 1, "locals block should not have labels: got 1"},
+		{19, 1, "output block must have exactly 1 label, got 0"},
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d diagnostics, got %d: %v", len(expected), len(diags), diags)
+	}
+	for i, want := range expected {
+		diag := diags[i]
+		if diag.Subject == nil {
+			t.Fatalf("diagnostic %d has no range", i)
+		}
+		if diag.Subject.Filename != filePath || diag.Subject.Start.Line != want.line || diag.Subject.Start.Column != want.column {
+			t.Errorf("diagnostic %d: got %s:%d:%d, want line %d column %d", i,
+				diag.Subject.Filename, diag.Subject.Start.Line, diag.Subject.Start.Column, want.line, want.column)
+		}
+		if diag.Detail != want.detail {
+			t.Errorf("diagnostic %d: got detail %q, want %q", i, diag.Detail, want.detail)
+		}
+	}
+}
+
+// TestValidationDiagnostics_OtherErrors tests that non-validation errors carry no diagnostics
+func TestValidationDiagnostics_OtherErrors(t *testing.T) {
+	if diags := ValidationDiagnostics(nil); diags != nil {
+		t.Errorf("expected nil for nil error, got %v", diags)
+	}
+	if diags := ValidationDiagnostics(&HCLError{Op: "Parse", Kind: KindParsing, Err: errors.New("boom")}); diags != nil {
+		t.Errorf("expected nil for parsing error, got %v", diags)
+	}
+	if diags := ValidationDiagnostics(ValidateRequiredBlockLabels(nil)); diags != nil {
+		t.Errorf("expected nil for validation error without diagnostics, got %v", diags)
+	}
+}
+
+// TestValidateSchema tests structural checks of core block bodies
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		details []string
+	}{
+		{
+			name: "valid module",
+			content: `variable "name" {
+  type        = string
+  default     = "app"
+  description = "Name"
+  sensitive   = false
+  nullable    = false
+  ephemeral   = false
+
+  validation {
+    condition     = length(var.name) > 0
+    error_message = "Name must not be empty."
+  }
+}
+
+resource "aws_instance" "web" {
+  count = 2
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+
+data "aws_ami" "ubuntu" {
+  lifecycle {
+    postcondition {
+      condition     = self.id != ""
+      error_message = "No AMI found."
+    }
+  }
+}
+
+module "vpc" {
+  source   = "./vpc"
+  for_each = toset(["a"])
+}
+
+output "id" {
+  value       = aws_instance.web[0].id
+  description = "ID"
+}
+`,
+		},
+		{
+			name: "lifecycle in removed and ephemeral blocks",
+			content: `removed {
+  from = aws_instance.a
+
+  lifecycle {
+    destroy = false
+  }
+}
+
+ephemeral "aws_secretsmanager_secret_version" "db" {
+  secret_id = "db"
+
+  lifecycle {
+    postcondition {
+      condition     = self.secret_string != ""
+      error_message = "The secret is empty."
+    }
+  }
+}
+`,
+		},
+		{
+			name: "output without value",
+			content: `output "id" {
+  description = "ID"
+}
+`,
+			details: []string{`The argument "value" is required`},
+		},
+		{
+			name: "module without source",
+			content: `module "vpc" {
+  cidr = "10.0.0.0/16"
+}
+`,
+			details: []string{`The argument "source" is required`},
+		},
+		{
+			name: "count and for_each",
+			content: `resource "aws_instance" "web" {
+  count    = 2
+  for_each = toset(["a"])
+}
+`,
+			details: []string{`resource block must not set both "count" and "for_each"`},
+		},
+		{
+			name: "unknown variable attribute",
+			content: `variable "name" {
+  type    = string
+  require = true
+}
+`,
+			details: []string{`An argument named "require" is not expected here.`},
+		},
+		{
+			name: "lifecycle outside resource",
+			content: `lifecycle {
+  prevent_destroy = true
+}
+
+module "vpc" {
+  source = "./vpc"
+
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+`,
+			details: []string{
+				"lifecycle block must be inside a resource, data, ephemeral or removed block",
+				"lifecycle block is not allowed inside a module block",
+			},
+		},
+		{
+			name: "every problem reported",
+			content: `output "a" {}
+
+output "b" {}
+`,
+			details: []string{`The argument "value" is required`, `The argument "value" is required`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "test.tf")
+			//nolint:gosec // G306: Test files can use 0644 permissions
+			if err := os.WriteFile(filePath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := ParseHCLFile(filePath)
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+
+			err = ValidateSchema(parsed)
+			if len(tt.details) == 0 {
+				if err != nil {
+					t.Errorf("unexpected schema error: %v", err)
+				}
+				return
+			}
+
+			if !IsSchemaError(err) {
+				t.Fatalf("expected schema error, got %v", err)
+			}
+			diags, sources := ErrorDiagnostics(err)
+			if len(sources[filePath]) == 0 {
+				t.Error("expected schema error to carry the source")
+			}
+			if len(diags) != len(tt.details) {
+				t.Fatalf("expected %d diagnostics, got %d: %v", len(tt.details), len(diags), diags)
+			}
+			for i, want := range tt.details {
+				if !strings.Contains(diags[i].Detail, want) {
+					t.Errorf("diagnostic %d: got %q, want it to contain %q", i, diags[i].Detail, want)
+				}
+				if diags[i].Subject == nil {
+					t.Errorf("diagnostic %d has no range", i)
+				}
+			}
+		})
+	}
+}
+
+// TestValidateSchema_NilInput tests nil input handling
+func TestValidateSchema_NilInput(t *testing.T) {
+	if err := ValidateSchema(nil); !IsSchemaError(err) {
+		t.Errorf("expected schema error for nil input, got %v", err)
+	}
+}
+
 // TestValidateRequiredBlockLabels_NilInput tests nil input handling
 func TestValidateRequiredBlockLabels_NilInput(t *testing.T) {
 	err := ValidateRequiredBlockLabels(nil)
@@ -404,7 +736,7 @@ func TestValidateFilePath(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			path := tt.setup(t)
-			err := validateFilePath(path)
+			err := validateFilePath(vfs.OSFS{}, path)
 
 			if tt.wantErr && err == nil {
 				t.Error("expected error, got nil")
@@ -1,10 +1,14 @@
 package hcl
 
 import (
+	"bytes"
 	"sort"
 	"strings"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
 )
 
 // BlockType represents the type of a Terraform block.
@@ -83,33 +87,13 @@ func getBlockType(name string) BlockType {
 // The function recursively copies attributes and nested blocks.
 func copyBlockClean(src *hclwrite.Block) *hclwrite.Block {
 	// Create new block with same type and labels
-	newBlock := hclwrite.NewBlock(src.Type(), src.Labels())
+	newBlock := hclwrite.NewBlock(src.Type(), blockLabels(src))
 
 	// Get source body
 	srcBody := src.Body()
 	newBody := newBlock.Body()
 
-	// Copy attributes only (no comment tokens)
-	attributes := srcBody.Attributes()
-
-	// If for_each exists, write it first
-	if attr, ok := attributes["for_each"]; ok {
-		newBody.SetAttributeRaw("for_each", attr.Expr().BuildTokens(nil))
-	}
-
-	// Get sorted attribute names (excluding for_each)
-	var attrNames []string
-	for name := range attributes {
-		if name != "for_each" {
-			attrNames = append(attrNames, name)
-		}
-	}
-	sort.Strings(attrNames)
-
-	// Copy attributes in sorted order
-	for _, name := range attrNames {
-		newBody.SetAttributeRaw(name, attributes[name].Expr().BuildTokens(nil))
-	}
+	copyAttributesSorted(srcBody, newBody)
 
 	// Recursively copy nested blocks
 	nestedBlocks := srcBody.Blocks()
@@ -125,7 +109,7 @@ func copyBlockClean(src *hclwrite.Block) *hclwrite.Block {
 				return typeOrderI < typeOrderJ
 			}
 
-			return compareLabels(nestedBlocks[i].Labels(), nestedBlocks[j].Labels())
+			return compareLabels(blockLabels(nestedBlocks[i]), blockLabels(nestedBlocks[j]))
 		})
 
 		// Copy nested blocks cleanly
@@ -137,12 +121,87 @@ func copyBlockClean(src *hclwrite.Block) *hclwrite.Block {
 	return newBlock
 }
 
+// blockLabels returns the labels of a block, decoded from its tokens.
+//
+// Block.Labels only understands quoted labels that lex as a single literal
+// token and returns their escape sequences as written, so labels such as
+// "a$" or "a\"b" would be lost or escaped twice when the block is rebuilt.
+func blockLabels(block *hclwrite.Block) []string {
+	var labels []string
+	var quoted []byte
+	seenType := false
+	for _, token := range block.BuildTokens(nil) {
+		switch {
+		case token.Type == hclsyntax.TokenOBrace && quoted == nil:
+			return labels
+		case token.Type == hclsyntax.TokenIdent && quoted == nil:
+			if seenType {
+				labels = append(labels, string(token.Bytes))
+			}
+			seenType = true
+		case token.Type == hclsyntax.TokenOQuote:
+			quoted = append([]byte(nil), token.Bytes...)
+		case token.Type == hclsyntax.TokenCQuote && quoted != nil:
+			labels = append(labels, decodeQuoted(append(quoted, token.Bytes...)))
+			quoted = nil
+		case quoted != nil:
+			quoted = append(quoted, token.Bytes...)
+		}
+	}
+	return labels
+}
+
+// decodeQuoted returns the value of a quoted string literal, with its
+// escape sequences decoded. A literal that does not evaluate to a string
+// is returned as written, without its quotes.
+func decodeQuoted(src []byte) string {
+	// Most labels have nothing to decode, and parsing them is costly
+	if !bytes.ContainsAny(src, `\$%`) {
+		return string(src[1 : len(src)-1])
+	}
+
+	expr, diags := hclsyntax.ParseExpression(src, "", hcl.Pos{Line: 1, Column: 1})
+	if !diags.HasErrors() {
+		if value, diags := expr.Value(nil); !diags.HasErrors() && value.Type() == cty.String && value.IsKnown() {
+			return value.AsString()
+		}
+	}
+	return string(src[1 : len(src)-1])
+}
+
+// copyAttributesSorted copies the attributes of src to dst without comment
+// tokens, alphabetically with for_each first.
+func copyAttributesSorted(src, dst *hclwrite.Body) {
+	attributes := src.Attributes()
+
+	// If for_each exists, write it first
+	if attr, ok := attributes["for_each"]; ok {
+		dst.SetAttributeRaw("for_each", attr.Expr().BuildTokens(nil))
+	}
+
+	// Get sorted attribute names (excluding for_each)
+	var attrNames []string
+	for name := range attributes {
+		if name != "for_each" {
+			attrNames = append(attrNames, name)
+		}
+	}
+	sort.Strings(attrNames)
+
+	// Copy attributes in sorted order
+	for _, name := range attrNames {
+		dst.SetAttributeRaw(name, attributes[name].Expr().BuildTokens(nil))
+	}
+}
+
 // SortHCLFile sorts all blocks and attributes in an HCL file.
 //
 // It sorts blocks by type according to Terraform conventions
 // (terraform, provider, variable, locals, data, resource, module, output),
 // then alphabetically by labels within each type.
 // Attributes within blocks are sorted alphabetically, with for_each always first.
+// Top-level attributes, as used by Terragrunt (e.g., inputs), come before the
+// blocks, sorted alphabetically.
 //
 // Returns a new hclwrite.File with sorted content.
 func SortHCLFile(file *hclwrite.File) *hclwrite.File {
@@ -160,6 +219,11 @@ func SortHCLFile(file *hclwrite.File) *hclwrite.File {
 	newFile := hclwrite.NewEmptyFile()
 	body := newFile.Body()
 
+	copyAttributesSorted(file.Body(), body)
+	if len(body.Attributes()) > 0 && len(blocks) > 0 {
+		body.AppendNewline()
+	}
+
 	// Add sorted blocks to the new file
 	for i, block := range blocks {
 		// Create a clean copy of the block to avoid token baggage
@@ -178,23 +242,17 @@ func SortHCLFile(file *hclwrite.File) *hclwrite.File {
 }
 
 // parseBlocks extracts all top-level blocks from an HCL body.
-// Backend blocks at the top level are skipped as they should only
-// appear nested within terraform blocks.
+// Backend blocks belong inside a terraform block, but misplaced ones are
+// kept, after the outputs, so that sorting never loses content.
 func parseBlocks(body *hclwrite.Body) []Block {
 	var blocks []Block
 
 	for _, block := range body.Blocks() {
 		blockType := getBlockType(block.Type())
 
-		// Handle backend blocks specially - they should be treated as nested blocks within terraform
-		if blockType == BlockTypeBackend {
-			// Skip backend blocks at the top level - they should be nested within terraform blocks
-			continue
-		}
-
 		blocks = append(blocks, Block{
 			Type:   blockType,
-			Labels: block.Labels(),
+			Labels: blockLabels(block),
 			Block:  block,
 		})
 	}
@@ -294,7 +352,7 @@ func sortBlockAttributes(block *hclwrite.Block) {
 			}
 
 			// If same type, sort by labels
-			return compareLabels(nestedBlocks[i].Labels(), nestedBlocks[j].Labels())
+			return compareLabels(blockLabels(nestedBlocks[i]), blockLabels(nestedBlocks[j]))
 		})
 
 		// Remove all nested blocks
@@ -340,19 +398,64 @@ func SortAttributes(attributes map[string]*hclwrite.Attribute) []string {
 	return names
 }
 
+// SortOptions enables optional passes that run alongside sorting and formatting.
+// The zero value sorts and formats only, matching SortAndFormatHCLFile.
+type SortOptions struct {
+	// Normalize rewrites legacy expression syntax (see NormalizeHCLFile).
+	Normalize bool
+
+	// SortListAttributes names attributes whose list values are sorted
+	// because they are semantically sets (see SortListValues).
+	SortListAttributes []string
+
+	// SkipEquivalenceCheck skips the CheckEquivalent check of the result,
+	// for callers that only preview it and do not write it anywhere. The
+	// check parses and compares both sides again, which costs about as
+	// much as sorting.
+	SkipEquivalenceCheck bool
+
+	// FormatOptions configures the formatting steps applied after sorting.
+	FormatOptions
+}
+
 // SortAndFormatHCLFile sorts all blocks and attributes in an HCL file and returns the formatted string.
 // This is the main entry point that combines sorting and formatting in one operation.
 // It first sorts the file using SortHCLFile, then formats it using FormatHCLFile.
-// Returns the formatted content as a string, or an HCLError with KindSorting if sorting or formatting fails.
+// Returns the formatted content as a string, or an HCLError with KindSorting if sorting or formatting
+// fails, or if the sorted content does not mean the same as the input (see CheckEquivalent).
 func SortAndFormatHCLFile(file *hclwrite.File) (string, error) {
+	formatted, _, err := SortAndFormatHCLFileWithOptions(file, SortOptions{})
+	return formatted, err
+}
+
+// SortAndFormatHCLFileWithOptions sorts and formats an HCL file like SortAndFormatHCLFile,
+// then applies the optional passes enabled in opts to the sorted result.
+// The input file is never modified.
+//
+// Unless opts.SkipEquivalenceCheck is set, the result is checked with
+// CheckEquivalent against the input, after the expression rewrites enabled
+// in opts. If sorting lost or changed anything, the error is an HCLError
+// with KindSorting wrapping an *EquivalenceError.
+// Returns the formatted content and any normalization rewrites that were applied.
+func SortAndFormatHCLFileWithOptions(file *hclwrite.File, opts SortOptions) (string, []Rewrite, error) {
 	sorted := SortHCLFile(file)
-	formatted, err := FormatHCLFile(sorted)
+
+	var rewrites []Rewrite
+	if opts.Normalize {
+		rewrites = NormalizeHCLFile(sorted)
+	}
+	SortListValues(sorted, opts.SortListAttributes)
+
+	formatted, err := FormatHCLFileWithOptions(sorted, opts.FormatOptions)
+	if err == nil && file != nil && !opts.SkipEquivalenceCheck {
+		err = checkSorted(file, formatted, opts)
+	}
 	if err != nil {
-		return formatted, &HCLError{
+		return formatted, rewrites, &HCLError{
 			Op:   "SortAndFormatHCLFile",
 			Kind: KindSorting,
 			Err:  err,
 		}
 	}
-	return formatted, nil
+	return formatted, rewrites, nil
 }
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 )
 
@@ -757,8 +758,8 @@ func TestSortHCLFile_Fixtures(t *testing.T) {
 	}
 }
 
-// TestParseBlocks_BackendSkipped tests that top-level backend blocks are skipped
-func TestParseBlocks_BackendSkipped(t *testing.T) {
+// TestParseBlocks_BackendKept tests that misplaced top-level backend blocks are kept
+func TestParseBlocks_BackendKept(t *testing.T) {
 	input := `backend "s3" {
   bucket = "test"
 }
@@ -775,13 +776,58 @@ variable "test" {
 
 	blocks := parseBlocks(file.Body())
 
-	// Backend should be skipped, only variable should be present
-	if len(blocks) != 1 {
-		t.Errorf("expected 1 block (backend skipped), got %d", len(blocks))
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(blocks))
 	}
 
-	if len(blocks) > 0 && blocks[0].Type != BlockTypeVariable {
-		t.Errorf("expected variable block, got %v", blocks[0].Type)
+	sortBlocks(blocks)
+	if blocks[0].Type != BlockTypeVariable || blocks[1].Type != BlockTypeBackend {
+		t.Errorf("expected variable before backend, got %v and %v", blocks[0].Type, blocks[1].Type)
+	}
+}
+
+// TestSortHCLFile_TopLevelAttributes tests that top-level attributes, as in
+// Terragrunt files, are kept and sorted before the blocks
+func TestSortHCLFile_TopLevelAttributes(t *testing.T) {
+	input := `terraform {
+  source = "../modules/vpc"
+}
+
+inputs = {
+  name = "main"
+}
+
+include "root" {
+  path = find_in_parent_folders()
+}
+
+download_dir = ".terragrunt"
+`
+	want := `download_dir = ".terragrunt"
+inputs = {
+  name = "main"
+}
+
+terraform {
+  source = "../modules/vpc"
+}
+
+include "root" {
+  path = find_in_parent_folders()
+}
+`
+
+	file, diags := hclwrite.ParseConfig([]byte(input), "terragrunt.hcl", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("parse failed: %v", diags)
+	}
+
+	got, err := SortAndFormatHCLFile(file)
+	if err != nil {
+		t.Fatalf("SortAndFormatHCLFile() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("SortAndFormatHCLFile() =\n%s\nwant:\n%s", got, want)
 	}
 }
 
@@ -908,3 +954,88 @@ func TestSortHCLFile_NestedBlocksOfDifferentTypes(t *testing.T) {
 
 	t.Logf("Sorted output:\n%s", output)
 }
+
+// TestBlockLabels tests that labels are decoded from their tokens
+func TestBlockLabels(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"quoted", `resource "aws_instance" "web" {}`, []string{"aws_instance", "web"}},
+		{"identifiers", `locals {}`, nil},
+		{"bare identifier", `block name {}`, []string{"name"}},
+		{"dollar", `output "cost$" {}`, []string{"cost$"}},
+		{"escaped quote", `output "a\"b" {}`, []string{`a"b`}},
+		{"escaped interpolation", `output "a$${b}" {}`, []string{"a${b}"}},
+		{"unicode escape", `output "caf\u00e9" {}`, []string{"café"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, diags := hclwrite.ParseConfig([]byte(tt.input+"\n"), "test.tf", hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				t.Fatalf("parse failed: %v", diags)
+			}
+			got := blockLabels(file.Body().Blocks()[0])
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("blockLabels() = %q, want %q", got, tt.want)
+			}
+
+			// The equivalence check fails if a label changes while sorting
+			if _, err := SortAndFormatHCLFile(file); err != nil {
+				t.Errorf("SortAndFormatHCLFile() error = %v", err)
+			}
+		})
+	}
+}
+
+// FuzzSortAndFormatHCLFile tests that sorting never changes the meaning of a
+// file and that sorting the result again changes nothing
+func FuzzSortAndFormatHCLFile(f *testing.F) {
+	fixtures, err := filepath.Glob("../testdata/fixtures/*/*.tf")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range fixtures {
+		//nolint:gosec // G304: Test file path is controlled
+		content, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(content)
+	}
+	f.Add([]byte("inputs = {\n  a = 1\n}\n\ninclude \"root\" {\n  path = \"..\"\n}\n"))
+	f.Add([]byte("locals {\n  script = <<-EOT\n    echo hello\n  EOT\n}\n\n\n\nvariable \"b\" {}\n"))
+	f.Add([]byte("backend \"s3\" {\n  bucket = \"state\"\n}\n# trailing comment\n"))
+	f.Add([]byte("A\"0$\"{}"))
+
+	f.Fuzz(func(t *testing.T, src []byte) {
+		file, diags := hclwrite.ParseConfig(src, "fuzz.tf", hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			t.Skip()
+		}
+		if _, diags := hclsyntax.ParseConfig(src, "fuzz.tf", hcl.Pos{Line: 1, Column: 1}); diags.HasErrors() {
+			t.Skip()
+		}
+
+		first, err := SortAndFormatHCLFile(file)
+		if err != nil {
+			t.Fatalf("first pass failed: %v\ninput:\n%s", err, src)
+		}
+		if err := CheckEquivalent(src, []byte(first)); err != nil {
+			t.Fatalf("first pass changed the meaning: %v\ninput:\n%s", err, src)
+		}
+
+		sortedFile, diags := hclwrite.ParseConfig([]byte(first), "fuzz.tf", hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			t.Fatalf("sorted output does not parse: %v\noutput:\n%s", diags, first)
+		}
+		second, err := SortAndFormatHCLFile(sortedFile)
+		if err != nil {
+			t.Fatalf("second pass failed: %v\noutput:\n%s", err, first)
+		}
+		if second != first {
+			t.Fatalf("second pass changed the output\nfirst:\n%s\nsecond:\n%s", first, second)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package hcl
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// SplitRule assigns a top-level block to the file it should be split into.
+// It returns a file name such as "iam.tf", or "" to keep the block in its current file.
+type SplitRule func(block *hclwrite.Block) string
+
+// splitTypeFiles names the files blocks are grouped into by SplitByType.
+// Block types not listed here go to "<type>.tf".
+var splitTypeFiles = map[string]string{
+	"terraform": "versions.tf",
+	"provider":  "providers.tf",
+	"variable":  "variables.tf",
+	"locals":    "locals.tf",
+	"data":      "data.tf",
+	"resource":  "resources.tf",
+	"module":    "modules.tf",
+	"output":    "outputs.tf",
+}
+
+// SplitByType groups blocks by block type, using the canonical file names of
+// DefaultLayout except for data sources, resources and modules, which go to
+// data.tf, resources.tf and modules.tf instead of main.tf.
+func SplitByType() SplitRule {
+	return func(block *hclwrite.Block) string {
+		if name, ok := splitTypeFiles[block.Type()]; ok {
+			return name
+		}
+		return block.Type() + ".tf"
+	}
+}
+
+// SplitByResourcePrefix groups resource and data blocks by the service segment
+// of their type, the part after the provider name: aws_iam_role and
+// aws_iam_policy_document both go to iam.tf, aws_s3_bucket to s3.tf.
+// Other blocks stay in their current file.
+func SplitByResourcePrefix() SplitRule {
+	return func(block *hclwrite.Block) string {
+		blockType := getBlockType(block.Type())
+		if blockType != BlockTypeResource && blockType != BlockTypeData {
+			return ""
+		}
+		labels := block.Labels()
+		if len(labels) == 0 {
+			return ""
+		}
+		parts := strings.SplitN(labels[0], "_", 3)
+		if len(parts) < 2 || parts[1] == "" {
+			return ""
+		}
+		return parts[1] + ".tf"
+	}
+}
+
+// SplitByLabelPattern groups blocks whose labels, joined with ".", match pattern.
+// The file is named after the first capture group, or the whole match if the
+// pattern has no groups; for example `^aws_(\w+?)_` sends aws_iam_role.app to iam.tf.
+// Blocks that do not match, or match an empty string, stay in their current file.
+func SplitByLabelPattern(pattern *regexp.Regexp) SplitRule {
+	return func(block *hclwrite.Block) string {
+		match := pattern.FindStringSubmatch(strings.Join(block.Labels(), "."))
+		if match == nil {
+			return ""
+		}
+		group := match[0]
+		if len(match) > 1 {
+			group = match[1]
+		}
+		if group == "" {
+			return ""
+		}
+		return group + ".tf"
+	}
+}
+
+// PlanSplit assigns the top-level blocks of the source file to the files chosen
+// by rule. Other entries in files are existing destination files to merge into;
+// their blocks stay where they are.
+//
+// Returns the planned moves and the new content of every affected file, as
+// described for PlanLayout. The input files are not modified.
+func PlanSplit(files map[string]*hclwrite.File, source string, rule SplitRule) ([]BlockMove, map[string]*hclwrite.File) {
+	return planMoves(files, func(name string, block *hclwrite.Block) string {
+		if name != source {
+			return name
+		}
+		if dest := rule(block); dest != "" {
+			return dest
+		}
+		return name
+	})
+}
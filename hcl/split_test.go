@@ -0,0 +1,162 @@
+package hcl
+
+import (
+	"regexp"
+	"testing"
+)
+
+// TestSplitRules tests the file each rule assigns to a block
+func TestSplitRules(t *testing.T) {
+	files := parseModule(t, map[string]string{
+		"main.tf": `terraform {
+  required_version = ">= 1.0"
+}
+
+resource "aws_iam_role" "app" {
+  name = "app"
+}
+
+data "aws_iam_policy_document" "app" {}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+
+resource "random_id" "suffix" {
+  byte_length = 4
+}
+
+module "vpc" {
+  source = "./vpc"
+}
+
+moved {
+  from = aws_s3_bucket.old
+  to   = aws_s3_bucket.logs
+}
+`,
+	})
+	blocks := files["main.tf"].Body().Blocks()
+
+	tests := []struct {
+		name     string
+		rule     SplitRule
+		expected []string
+	}{
+		{
+			name:     "by type",
+			rule:     SplitByType(),
+			expected: []string{"versions.tf", "resources.tf", "data.tf", "resources.tf", "resources.tf", "modules.tf", "moved.tf"},
+		},
+		{
+			name:     "by resource prefix",
+			rule:     SplitByResourcePrefix(),
+			expected: []string{"", "iam.tf", "iam.tf", "s3.tf", "id.tf", "", ""},
+		},
+		{
+			name:     "by label pattern with group",
+			rule:     SplitByLabelPattern(regexp.MustCompile(`^aws_(\w+?)_`)),
+			expected: []string{"", "iam.tf", "iam.tf", "s3.tf", "", "", ""},
+		},
+		{
+			name:     "by label pattern without group",
+			rule:     SplitByLabelPattern(regexp.MustCompile(`^vpc$`)),
+			expected: []string{"", "", "", "", "", "vpc.tf", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, block := range blocks {
+				if got := tt.rule(block); got != tt.expected[i] {
+					t.Errorf("block %s: got %q, want %q", blockAddress(block), got, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+// TestPlanSplit tests splitting one file while merging into an existing file
+func TestPlanSplit(t *testing.T) {
+	files := parseModule(t, map[string]string{
+		"main.tf": `resource "aws_iam_role" "app" {
+  name = "app"
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+
+locals {
+  name = "app"
+}
+`,
+		"s3.tf": `resource "aws_s3_bucket" "data" {
+  bucket = "data"
+}
+
+resource "aws_iam_role" "replication" {
+  name = "replication"
+}
+`,
+	})
+
+	moves, affected := PlanSplit(files, "main.tf", SplitByResourcePrefix())
+
+	expectedMoves := []BlockMove{
+		{Address: "resource.aws_iam_role.app", From: "main.tf", To: "iam.tf"},
+		{Address: "resource.aws_s3_bucket.logs", From: "main.tf", To: "s3.tf"},
+	}
+	if len(moves) != len(expectedMoves) {
+		t.Fatalf("got %d moves, want %d: %v", len(moves), len(expectedMoves), moves)
+	}
+	for i, move := range moves {
+		if move != expectedMoves[i] {
+			t.Errorf("move %d: got %+v, want %+v", i, move, expectedMoves[i])
+		}
+	}
+
+	// Blocks already in the destination stay there even if the rule would move them
+	expectedBlocks := map[string][]string{
+		"main.tf": {"locals"},
+		"iam.tf":  {"resource.aws_iam_role.app"},
+		"s3.tf":   {"resource.aws_s3_bucket.logs", "resource.aws_s3_bucket.data", "resource.aws_iam_role.replication"},
+	}
+	if len(affected) != len(expectedBlocks) {
+		t.Fatalf("got %d affected files, want %d", len(affected), len(expectedBlocks))
+	}
+	for name, want := range expectedBlocks {
+		file, ok := affected[name]
+		if !ok {
+			t.Fatalf("expected %s to be affected", name)
+		}
+		var got []string
+		for _, block := range file.Body().Blocks() {
+			got = append(got, blockAddress(block))
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s: got blocks %v, want %v", name, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: got blocks %v, want %v", name, got, want)
+				break
+			}
+		}
+	}
+}
+
+// TestPlanSplit_NoMoves tests that a rule keeping every block plans nothing
+func TestPlanSplit_NoMoves(t *testing.T) {
+	files := parseModule(t, map[string]string{
+		"main.tf": `locals {
+  name = "app"
+}
+`,
+	})
+
+	moves, affected := PlanSplit(files, "main.tf", SplitByResourcePrefix())
+	if len(moves) != 0 || len(affected) != 0 {
+		t.Errorf("expected no moves, got %v and %d affected files", moves, len(affected))
+	}
+}
@@ -0,0 +1,157 @@
+package hcl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// UnusedDeclaration describes a variable or local value that is declared in
+// a module but never referenced by any expression in the module's files.
+type UnusedDeclaration struct {
+	Address string    // "var.<name>" or "local.<name>"
+	Range   hcl.Range // Where it is declared
+}
+
+// Diagnostic returns a warning describing the unused declaration.
+func (u UnusedDeclaration) Diagnostic() *hcl.Diagnostic {
+	kind, name := "local value", strings.TrimPrefix(u.Address, "local.")
+	if strings.HasPrefix(u.Address, "var.") {
+		kind, name = "variable", strings.TrimPrefix(u.Address, "var.")
+	}
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  "Unused " + kind,
+		Detail:   fmt.Sprintf("%s %q is declared but never referenced in this module.", kind, name),
+		Subject:  u.Range.Ptr(),
+	}
+}
+
+// FindUnused returns the variables and local values declared in the files of
+// one module that no expression in those files references, in file and
+// declaration order.
+//
+// References are found by walking the traversals of every expression. A
+// variable referenced only from its own validation blocks counts as unused.
+// A local value referenced only by other unused local values counts as used.
+func FindUnused(files []*ParsedFile) []UnusedDeclaration {
+	var declared []UnusedDeclaration
+	referenced := make(map[string]bool)
+
+	for _, pf := range files {
+		if pf == nil || pf.File == nil {
+			continue
+		}
+		body, ok := pf.File.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		collectReferences(body, "", referenced)
+
+		for _, block := range body.Blocks {
+			switch {
+			case block.Type == "variable" && len(block.Labels) == 1:
+				declared = append(declared, UnusedDeclaration{Address: "var." + block.Labels[0], Range: block.DefRange()})
+			case block.Type == "locals":
+				for _, attr := range sortedAttributes(block.Body) {
+					declared = append(declared, UnusedDeclaration{Address: "local." + attr.Name, Range: attr.NameRange})
+				}
+			}
+		}
+	}
+
+	var unused []UnusedDeclaration
+	for _, decl := range declared {
+		if !referenced[decl.Address] {
+			unused = append(unused, decl)
+		}
+	}
+	return unused
+}
+
+// collectReferences records every var.<name> and local.<name> referenced by
+// the expressions in body and its nested blocks. References to self, the
+// address of the enclosing variable block, are ignored.
+func collectReferences(body *hclsyntax.Body, self string, referenced map[string]bool) {
+	for _, attr := range body.Attributes {
+		for _, traversal := range attr.Expr.Variables() {
+			if address := referenceAddress(traversal); address != "" && address != self {
+				referenced[address] = true
+			}
+		}
+	}
+
+	for _, block := range body.Blocks {
+		blockSelf := self
+		if block.Type == "variable" && len(block.Labels) == 1 && self == "" {
+			blockSelf = "var." + block.Labels[0]
+		}
+		collectReferences(block.Body, blockSelf, referenced)
+	}
+}
+
+// referenceAddress returns "var.<name>" or "local.<name>" for a traversal
+// rooted at var or local, or "" for any other traversal.
+func referenceAddress(traversal hcl.Traversal) string {
+	root := traversal.RootName()
+	if (root != "var" && root != "local") || len(traversal) < 2 {
+		return ""
+	}
+	attr, ok := traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return ""
+	}
+	return root + "." + attr.Name
+}
+
+// RemoveDeclarations deletes the variable blocks and local values with the
+// given addresses from file. Locals blocks left empty are removed as well.
+//
+// Returns the number of declarations removed.
+func RemoveDeclarations(file *hclwrite.File, addresses []string) int {
+	if file == nil || len(addresses) == 0 {
+		return 0
+	}
+
+	remove := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		remove[address] = true
+	}
+
+	removed := 0
+	body := file.Body()
+	for _, block := range body.Blocks() {
+		switch block.Type() {
+		case "variable":
+			if labels := block.Labels(); len(labels) == 1 && remove["var."+labels[0]] {
+				body.RemoveBlock(block)
+				removed++
+			}
+		case "locals":
+			names := make([]string, 0, len(block.Body().Attributes()))
+			for name := range block.Body().Attributes() {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			removedHere := 0
+			for _, name := range names {
+				if remove["local."+name] {
+					block.Body().RemoveAttribute(name)
+					removedHere++
+				}
+			}
+			removed += removedHere
+			if removedHere > 0 && len(block.Body().Attributes()) == 0 && len(block.Body().Blocks()) == 0 {
+				body.RemoveBlock(block)
+			}
+		}
+	}
+
+	return removed
+}
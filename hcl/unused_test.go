@@ -0,0 +1,118 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// TestFindUnused tests detection of unreferenced variables and locals across files
+func TestFindUnused(t *testing.T) {
+	sources := map[string]string{
+		"main.tf": `resource "aws_instance" "web" {
+  ami  = var.ami
+  tags = { Name = "${local.prefix}-web" }
+
+  dynamic "ebs_block_device" {
+    for_each = var.volumes
+    content {
+      volume_size = ebs_block_device.value
+    }
+  }
+}
+
+locals {
+  prefix = lower(var.name)
+  unused = "x"
+}
+`,
+		"variables.tf": `variable "ami" {}
+
+variable "name" {}
+
+variable "volumes" {}
+
+variable "region" {
+  validation {
+    condition     = length(var.region) > 0
+    error_message = "Region must not be empty."
+  }
+}
+
+variable "zone" {}
+`,
+	}
+
+	files := parseFiles(t, sources, "main.tf", "variables.tf")
+	unused := FindUnused(files)
+
+	expected := []struct {
+		address string
+		file    string
+		line    int
+		detail  string
+	}{
+		{"local.unused", "main.tf", 15, `local value "unused" is declared but never referenced in this module.`},
+		{"var.region", "variables.tf", 7, `variable "region" is declared but never referenced in this module.`},
+		{"var.zone", "variables.tf", 14, `variable "zone" is declared but never referenced in this module.`},
+	}
+	if len(unused) != len(expected) {
+		t.Fatalf("expected %d unused declarations, got %v", len(expected), unused)
+	}
+	for i, want := range expected {
+		got := unused[i]
+		if got.Address != want.address || got.Range.Start.Line != want.line {
+			t.Errorf("unused %d: got %s at line %d, want %s at line %d", i, got.Address, got.Range.Start.Line, want.address, want.line)
+		}
+		if diag := got.Diagnostic(); diag.Severity != hcl.DiagWarning || diag.Subject == nil || diag.Detail != want.detail {
+			t.Errorf("unused %d: expected warning with range and detail %q, got %+v", i, want.detail, diag)
+		}
+	}
+}
+
+// TestRemoveDeclarations tests deleting variables and locals from a file
+func TestRemoveDeclarations(t *testing.T) {
+	input := `variable "zone" {}
+
+variable "ami" {}
+
+locals {
+  unused = "x"
+}
+
+locals {
+  prefix = "app"
+  other  = "y"
+}
+
+locals {}
+`
+	expected := `
+variable "ami" {}
+
+
+locals {
+  prefix = "app"
+}
+
+locals {}
+`
+
+	file, diags := hclwrite.ParseConfig([]byte(input), "test.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("parse failed: %v", diags)
+	}
+
+	removed := RemoveDeclarations(file, []string{"var.zone", "local.unused", "local.other", "var.missing"})
+	if removed != 3 {
+		t.Errorf("RemoveDeclarations() = %d, want 3", removed)
+	}
+	if got := string(file.Bytes()); got != expected {
+		t.Errorf("unexpected output:\ngot:\n%q\nwant:\n%q", got, expected)
+	}
+
+	if removed := RemoveDeclarations(nil, []string{"var.zone"}); removed != 0 {
+		t.Errorf("expected 0 for nil file, got %d", removed)
+	}
+}
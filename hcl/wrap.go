@@ -0,0 +1,393 @@
+package hcl
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// indentWidth is the number of spaces hclwrite uses per nesting level.
+const indentWidth = 2
+
+// wrapBody wraps or collapses collection expressions of every attribute in body
+// and its nested blocks. Depth is the nesting level of body, used to compute
+// the indentation of each attribute line.
+func wrapBody(body *hclwrite.Body, depth int, opts FormatOptions) {
+	indent := depth * indentWidth
+
+	// Wrapping or collapsing an attribute takes it out of or into a run of
+	// aligned attributes, which moves the others in the run, so the runs
+	// are measured again after each change. An attribute is wrapped at most
+	// once and collapsed at most once.
+	for range 2 * len(body.Attributes()) {
+		if !wrapAttribute(body, indent, opts) {
+			break
+		}
+	}
+
+	for _, block := range body.Blocks() {
+		wrapBody(block.Body(), depth+1, opts)
+	}
+}
+
+// wrapAttribute wraps the first attribute of body, alphabetically, whose line
+// is wider than opts.MaxLineWidth, or collapses the first one that fits on a
+// single line if opts.CollapseCollections is set. Indent is the indentation
+// of the attribute lines. Returns false if no attribute was changed.
+func wrapAttribute(body *hclwrite.Body, indent int, opts FormatOptions) bool {
+	attributes := body.Attributes()
+	aligned := alignedAttributes(body)
+	lineWidth := func(name string) int {
+		return indent + aligned[name] + len(" = ") + tokensWidth(attributes[name].Expr().BuildTokens(nil))
+	}
+
+	for _, name := range SortAttributes(attributes) {
+		expr := attributes[name].Expr().BuildTokens(nil)
+
+		if isMultiLine(expr) {
+			if !opts.CollapseCollections {
+				continue
+			}
+			collapsed := collapseCollection(expr)
+			if collapsed == nil {
+				continue
+			}
+
+			// The collapsed line joins the attributes around it, which may
+			// push them past the width too
+			fitted := make(map[string]bool, len(aligned))
+			for other := range aligned {
+				fitted[other] = lineWidth(other) <= opts.MaxLineWidth
+			}
+			fitted[name] = true
+			body.SetAttributeRaw(name, collapsed)
+			aligned = alignedAttributes(body)
+			fits := true
+			for other := range aligned {
+				if fitted[other] && lineWidth(other) > opts.MaxLineWidth {
+					fits = false
+					break
+				}
+			}
+			if fits {
+				return true
+			}
+			body.SetAttributeRaw(name, expr)
+			aligned = alignedAttributes(body)
+			continue
+		}
+
+		if lineWidth(name) > opts.MaxLineWidth {
+			prefix := indent + aligned[name] + len(" = ")
+			if wrapped := wrapCollection(expr, indent, prefix, opts.MaxLineWidth); wrapped != nil {
+				body.SetAttributeRaw(name, wrapped)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// alignedAttributes returns the width hclwrite pads the name of each
+// single-line attribute of body to when it aligns the equals signs of a run
+// of consecutive single-line attributes: the width of the longest name in
+// the run. Blank lines, blocks and multi-line expressions end a run.
+func alignedAttributes(body *hclwrite.Body) map[string]int {
+	var names []string
+	var widths []int
+	depth := 0
+	var line hclwrite.Tokens
+	for _, tok := range body.BuildTokens(nil) {
+		line = append(line, tok)
+		if !endsLine(tok) {
+			continue
+		}
+
+		name, width := "", -1
+		if depth == 0 && len(line) > 2 && line[0].Type == hclsyntax.TokenIdent && line[1].Type == hclsyntax.TokenEqual {
+			lineDepth := 0
+			for _, t := range line {
+				lineDepth += nestingDelta(t.Type)
+			}
+			if lineDepth == 0 {
+				name = string(line[0].Bytes)
+				width = utf8.RuneCountInString(name)
+			}
+		}
+		for _, t := range line {
+			depth += nestingDelta(t.Type)
+		}
+		names = append(names, name)
+		widths = append(widths, width)
+		line = nil
+	}
+
+	aligned := make(map[string]int, len(names))
+	for i, width := range alignRuns(widths) {
+		if names[i] != "" {
+			aligned[names[i]] = width
+		}
+	}
+	return aligned
+}
+
+// alignRuns takes the name widths of consecutive lines, -1 for lines that
+// are not single-line assignments, and returns the width each name is padded
+// to: the longest width in its run of consecutive assignments.
+func alignRuns(widths []int) []int {
+	aligned := make([]int, len(widths))
+	start := 0
+	for i := 0; i <= len(widths); i++ {
+		if i < len(widths) && widths[i] >= 0 {
+			continue
+		}
+		longest := 0
+		for _, width := range widths[start:i] {
+			longest = max(longest, width)
+		}
+		for j := start; j < i; j++ {
+			aligned[j] = longest
+		}
+		if i < len(widths) {
+			aligned[i] = -1
+		}
+		start = i + 1
+	}
+	return aligned
+}
+
+// endsLine reports whether tok ends a line, as hclwrite splits lines when it
+// formats: a newline, or a line comment, which includes its newline.
+func endsLine(tok *hclwrite.Token) bool {
+	return tok.Type == hclsyntax.TokenNewline ||
+		(tok.Type == hclsyntax.TokenComment && len(tok.Bytes) > 0 && tok.Bytes[len(tok.Bytes)-1] == '\n')
+}
+
+// wrapCollection breaks a single-line list, tuple or object constructor onto one
+// element per line. List elements get trailing commas; object elements are
+// separated by newlines only, as terraform fmt writes them. Elements that are
+// themselves collections are wrapped recursively if they still exceed width.
+//
+// Indent is the indentation of the line the collection starts on and prefix is
+// the number of columns before the collection on that line.
+// Returns nil if tokens are not a wrappable collection constructor.
+func wrapCollection(tokens hclwrite.Tokens, indent, prefix, width int) hclwrite.Tokens {
+	if prefix+tokensWidth(tokens) <= width {
+		return nil
+	}
+
+	elements, isObject, ok := splitCollection(tokens)
+	if !ok || len(elements) == 0 {
+		return nil
+	}
+
+	elemIndent := indent + indentWidth
+	if isObject {
+		wrapObjectElements(elements, elemIndent, width)
+	} else {
+		for i, elem := range elements {
+			if wrapped := wrapCollection(elem, elemIndent, elemIndent, width); wrapped != nil {
+				elements[i] = wrapped
+			}
+		}
+	}
+
+	wrapped := hclwrite.Tokens{copyToken(tokens[0], 1), newlineToken()}
+	for _, elem := range elements {
+		wrapped = append(wrapped, elem...)
+		if !isObject {
+			wrapped = append(wrapped, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")})
+		}
+		wrapped = append(wrapped, newlineToken())
+	}
+
+	return append(wrapped, copyToken(tokens[len(tokens)-1], 0))
+}
+
+// wrapObjectElements wraps, in place, the values of object elements whose
+// lines are still wider than width. Elements are "key = value" (or
+// "key: value"); only the value can wrap. Like attributes, the keys of
+// consecutive single-line "key = value" elements are aligned, so each line
+// is measured with the longest key in its run, again after each wrap.
+func wrapObjectElements(elements []hclwrite.Tokens, indent, width int) {
+	for changed := true; changed; {
+		changed = false
+
+		keys := make([]int, len(elements))
+		for i, elem := range elements {
+			keys[i] = -1
+			if !isMultiLine(elem) {
+				if eq := slices.IndexFunc(elem, func(tok *hclwrite.Token) bool { return tok.Type == hclsyntax.TokenEqual }); eq > 0 {
+					keys[i] = tokensWidth(elem[:eq])
+				}
+			}
+		}
+		aligned := alignRuns(keys)
+
+		for i, elem := range elements {
+			if isMultiLine(elem) {
+				continue
+			}
+			sep := slices.IndexFunc(elem, func(tok *hclwrite.Token) bool {
+				return tok.Type == hclsyntax.TokenEqual || tok.Type == hclsyntax.TokenColon
+			})
+			if sep < 0 {
+				continue
+			}
+			key, value := elem[:sep+1], elem[sep+1:]
+			prefix := indent + tokensWidth(key) + 1
+			if aligned[i] >= 0 {
+				prefix = indent + aligned[i] + len(" = ")
+			}
+			if wrapped := wrapCollection(value, indent, prefix, width); wrapped != nil {
+				elements[i] = append(append(hclwrite.Tokens{}, key...), wrapped...)
+				changed = true
+				break
+			}
+		}
+	}
+}
+
+// collapseCollection joins a multi-line list, tuple or object constructor onto
+// a single line. Returns nil if the collection contains nested multi-line
+// elements, comments or heredocs, which cannot be collapsed safely.
+func collapseCollection(tokens hclwrite.Tokens) hclwrite.Tokens {
+	elements, isObject, ok := splitCollection(tokens)
+	if !ok {
+		return nil
+	}
+
+	collapsed := hclwrite.Tokens{copyToken(tokens[0], 1)}
+	for i, elem := range elements {
+		if isMultiLine(elem) {
+			return nil
+		}
+		if i > 0 {
+			collapsed = append(collapsed, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")})
+		}
+		spaces := 1
+		if i == 0 && !isObject {
+			spaces = 0
+		}
+		collapsed = append(collapsed, copyToken(elem[0], spaces))
+		collapsed = append(collapsed, elem[1:]...)
+	}
+
+	closeSpaces := 0
+	if isObject && len(elements) > 0 {
+		closeSpaces = 1
+	}
+	return append(collapsed, copyToken(tokens[len(tokens)-1], closeSpaces))
+}
+
+// splitCollection splits a collection constructor into its elements.
+// Elements are separated by top-level commas, and in object constructors also
+// by top-level newlines. Separator tokens are not included in the elements.
+//
+// Returns ok=false if tokens are not a single list, tuple or object constructor
+// (for example a for expression, a function call, or an indexed collection),
+// or if they contain comments or heredocs.
+func splitCollection(tokens hclwrite.Tokens) (elements []hclwrite.Tokens, isObject, ok bool) {
+	n := len(tokens)
+	if n < 2 {
+		return nil, false, false
+	}
+
+	switch {
+	case tokens[0].Type == hclsyntax.TokenOBrack && tokens[n-1].Type == hclsyntax.TokenCBrack:
+	case tokens[0].Type == hclsyntax.TokenOBrace && tokens[n-1].Type == hclsyntax.TokenCBrace:
+		isObject = true
+	default:
+		return nil, false, false
+	}
+
+	var current hclwrite.Tokens
+	depth := 0
+	for i := 1; i < n-1; i++ {
+		tok := tokens[i]
+
+		switch tok.Type {
+		case hclsyntax.TokenComment, hclsyntax.TokenOHeredoc:
+			return nil, false, false
+		case hclsyntax.TokenIdent:
+			// [for x in xs : x] and {for k, v in m : k => v} are not constructors.
+			if depth == 0 && len(current) == 0 && len(elements) == 0 && string(tok.Bytes) == "for" {
+				return nil, false, false
+			}
+		}
+
+		if depth == 0 && (tok.Type == hclsyntax.TokenComma || tok.Type == hclsyntax.TokenNewline) {
+			if tok.Type == hclsyntax.TokenNewline && !isObject && len(current) > 0 {
+				// A newline inside a list element only appears in multi-line lists
+				// between elements; treat it like any other whitespace.
+				continue
+			}
+			if len(current) > 0 {
+				elements = append(elements, current)
+				current = nil
+			}
+			continue
+		}
+
+		depth += nestingDelta(tok.Type)
+		if depth < 0 {
+			// The opening token is closed before the end, e.g. [a][0] or {a = 1}.b.
+			return nil, false, false
+		}
+		current = append(current, tok)
+	}
+
+	if depth != 0 {
+		return nil, false, false
+	}
+	if len(current) > 0 {
+		elements = append(elements, current)
+	}
+	return elements, isObject, true
+}
+
+// nestingDelta returns +1 for tokens that open a nested construct,
+// -1 for tokens that close one, and 0 otherwise.
+func nestingDelta(t hclsyntax.TokenType) int {
+	switch t {
+	case hclsyntax.TokenOBrack, hclsyntax.TokenOBrace, hclsyntax.TokenOParen,
+		hclsyntax.TokenOQuote, hclsyntax.TokenTemplateInterp, hclsyntax.TokenTemplateControl:
+		return 1
+	case hclsyntax.TokenCBrack, hclsyntax.TokenCBrace, hclsyntax.TokenCParen,
+		hclsyntax.TokenCQuote, hclsyntax.TokenTemplateSeqEnd:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// isMultiLine reports whether tokens span more than one line.
+func isMultiLine(tokens hclwrite.Tokens) bool {
+	for _, tok := range tokens {
+		if tok.Type == hclsyntax.TokenNewline || tok.Type == hclsyntax.TokenOHeredoc {
+			return true
+		}
+	}
+	return false
+}
+
+// tokensWidth returns the rendered width in columns of single-line tokens,
+// excluding leading whitespace.
+func tokensWidth(tokens hclwrite.Tokens) int {
+	return utf8.RuneCountInString(strings.TrimSpace(string(tokens.Bytes())))
+}
+
+// copyToken returns a copy of tok with the given number of spaces before it.
+func copyToken(tok *hclwrite.Token, spacesBefore int) *hclwrite.Token {
+	copied := *tok
+	copied.SpacesBefore = spacesBefore
+	return &copied
+}
+
+// newlineToken returns a new newline token.
+func newlineToken() *hclwrite.Token {
+	return &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")}
+}
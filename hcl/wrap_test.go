@@ -0,0 +1,270 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// formatWithWidth parses input and formats it with the given wrapping options
+func formatWithWidth(t *testing.T, input string, opts FormatOptions) string {
+	t.Helper()
+
+	file, diags := hclwrite.ParseConfig([]byte(input), "test.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("parse failed: %v", diags)
+	}
+
+	formatted, err := FormatHCLFileWithOptions(file, opts)
+	if err != nil {
+		t.Fatalf("FormatHCLFileWithOptions failed: %v", err)
+	}
+
+	// Output must always be valid HCL
+	if _, diags := hclwrite.ParseConfig([]byte(formatted), "test.tf", hcl.Pos{Line: 1, Column: 1}); diags.HasErrors() {
+		t.Fatalf("output is invalid HCL: %v\n%s", diags, formatted)
+	}
+	return formatted
+}
+
+// TestFormatHCLFileWithOptions_WrapList tests that long lists are broken one element per line
+func TestFormatHCLFileWithOptions_WrapList(t *testing.T) {
+	input := `resource "aws_instance" "web" {
+  vpc_security_group_ids = ["sg-0123456789abcdef0", "sg-0123456789abcdef1", "sg-0123456789abcdef2"]
+}
+`
+	expected := `resource "aws_instance" "web" {
+  vpc_security_group_ids = [
+    "sg-0123456789abcdef0",
+    "sg-0123456789abcdef1",
+    "sg-0123456789abcdef2",
+  ]
+}
+`
+
+	got := formatWithWidth(t, input, FormatOptions{MaxLineWidth: 80})
+	if got != expected {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+// TestFormatHCLFileWithOptions_WrapObject tests that long objects are broken one element per line
+func TestFormatHCLFileWithOptions_WrapObject(t *testing.T) {
+	input := `locals {
+  tags = { Environment = "production", Team = "platform-engineering", CostCenter = "cc-12345" }
+}
+`
+	expected := `locals {
+  tags = {
+    Environment = "production"
+    Team        = "platform-engineering"
+    CostCenter  = "cc-12345"
+  }
+}
+`
+
+	got := formatWithWidth(t, input, FormatOptions{MaxLineWidth: 60})
+	if got != expected {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+// TestFormatHCLFileWithOptions_WrapNested tests that nested collections are wrapped only when still too long
+func TestFormatHCLFileWithOptions_WrapNested(t *testing.T) {
+	input := `locals {
+  matrix = [["a", "b"], ["cccccccccccccccccccc", "dddddddddddddddddddd", "eeeeeeeeeeeeeeeeeeee"]]
+}
+`
+	expected := `locals {
+  matrix = [
+    ["a", "b"],
+    [
+      "cccccccccccccccccccc",
+      "dddddddddddddddddddd",
+      "eeeeeeeeeeeeeeeeeeee",
+    ],
+  ]
+}
+`
+
+	got := formatWithWidth(t, input, FormatOptions{MaxLineWidth: 40})
+	if got != expected {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+// TestFormatHCLFileWithOptions_Unwrappable tests expressions that are not collection constructors
+func TestFormatHCLFileWithOptions_Unwrappable(t *testing.T) {
+	input := `locals {
+  doubled = [for value in var.some_really_long_variable_name : value * 2 if value > 0]
+  first   = ["alpha-value", "beta-value", "gamma-value", "delta-value"][0]
+  joined  = join(",", ["alpha-value", "beta-value", "gamma-value", "delta-value"])
+  message = "a very long string literal that is definitely wider than the limit"
+}
+`
+
+	got := formatWithWidth(t, input, FormatOptions{MaxLineWidth: 40})
+	if got != input {
+		t.Errorf("expected unwrappable expressions to be untouched, got:\n%s", got)
+	}
+}
+
+// TestFormatHCLFileWithOptions_Collapse tests collapsing short multi-line collections
+func TestFormatHCLFileWithOptions_Collapse(t *testing.T) {
+	input := `locals {
+  zones = [
+    "a",
+    "b",
+  ]
+  tags = {
+    Name = "web"
+  }
+  long = [
+    "this-element-is-long",
+    "so-is-this-one-here",
+  ]
+}
+`
+	expected := `locals {
+  zones = ["a", "b"]
+  tags  = { Name = "web" }
+  long = [
+    "this-element-is-long",
+    "so-is-this-one-here",
+  ]
+}
+`
+
+	got := formatWithWidth(t, input, FormatOptions{MaxLineWidth: 40, CollapseCollections: true})
+	if got != expected {
+		t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", got, expected)
+	}
+
+	// Without the collapse option, multi-line collections are kept
+	if got := formatWithWidth(t, input, FormatOptions{MaxLineWidth: 40}); got != input {
+		t.Errorf("expected multi-line collections to be kept, got:\n%s", got)
+	}
+}
+
+// TestFormatHCLFileWithOptions_AlignedNames tests that line widths include
+// the padding that aligns the equals signs of consecutive attributes and
+// object elements to the longest name among them
+func TestFormatHCLFileWithOptions_AlignedNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     FormatOptions
+		expected string
+	}{
+		{
+			name: "short name aligned to a long one",
+			input: `resource "aws_instance" "web" {
+  a_thirty_two_character_attribute = "x"
+  c = ["aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"]
+}
+`,
+			opts: FormatOptions{MaxLineWidth: 80},
+			expected: `resource "aws_instance" "web" {
+  a_thirty_two_character_attribute = "x"
+  c = [
+    "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+    "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+  ]
+}
+`,
+		},
+		{
+			name: "collapse that would push a neighbor past the width",
+			input: `locals {
+  a = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+  a_thirty_two_character_attribute = [
+    "x",
+  ]
+  b_also_a_rather_long_attribute_name = "y"
+  c = [
+    "z",
+  ]
+}
+`,
+			opts: FormatOptions{MaxLineWidth: 80, CollapseCollections: true},
+			expected: `locals {
+  a = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+  a_thirty_two_character_attribute = [
+    "x",
+  ]
+  b_also_a_rather_long_attribute_name = "y"
+  c                                   = ["z"]
+}
+`,
+		},
+		{
+			name: "object key aligned to a long one",
+			input: `locals {
+  tags = { a = ["aaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbbbbbbbbbbb"], a_thirty_two_character_key_name = "x" }
+}
+`,
+			opts: FormatOptions{MaxLineWidth: 80},
+			expected: `locals {
+  tags = {
+    a = [
+      "aaaaaaaaaaaaaaaaaaaa",
+      "bbbbbbbbbbbbbbbbbbbbbbbbb",
+    ]
+    a_thirty_two_character_key_name = "x"
+  }
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatWithWidth(t, tt.input, tt.opts)
+			if got != tt.expected {
+				t.Errorf("unexpected output:\ngot:\n%s\nwant:\n%s", got, tt.expected)
+			}
+			if again := formatWithWidth(t, got, tt.opts); again != got {
+				t.Errorf("wrapping is not idempotent, second pass:\n%s", again)
+			}
+			for _, line := range strings.Split(got, "\n") {
+				if len(line) > tt.opts.MaxLineWidth {
+					t.Errorf("line wider than %d columns: %q", tt.opts.MaxLineWidth, line)
+				}
+			}
+		})
+	}
+}
+
+// TestFormatHCLFileWithOptions_Idempotent tests that wrapping output is stable
+func TestFormatHCLFileWithOptions_Idempotent(t *testing.T) {
+	input := `resource "aws_instance" "web" {
+  tags                   = { Name = "web", Environment = "production", Owner = "platform" }
+  vpc_security_group_ids = ["sg-0123456789abcdef0", "sg-0123456789abcdef1"]
+}
+`
+	opts := FormatOptions{MaxLineWidth: 60, CollapseCollections: true}
+
+	first := formatWithWidth(t, input, opts)
+	second := formatWithWidth(t, first, opts)
+	if first != second {
+		t.Errorf("wrapping is not idempotent\nfirst:\n%s\nsecond:\n%s", first, second)
+	}
+	if !strings.Contains(first, "vpc_security_group_ids = [\n") {
+		t.Errorf("expected long list to be wrapped, got:\n%s", first)
+	}
+}
+
+// TestFormatHCLFileWithOptions_Disabled tests that zero width leaves formatting unchanged
+func TestFormatHCLFileWithOptions_Disabled(t *testing.T) {
+	input := `locals {
+  zones = ["us-east-1a", "us-east-1b", "us-east-1c", "us-east-1d", "us-east-1e", "us-east-1f"]
+}
+`
+
+	got := formatWithWidth(t, input, FormatOptions{CollapseCollections: true})
+	if got != input {
+		t.Errorf("expected no wrapping with zero width, got:\n%s", got)
+	}
+}
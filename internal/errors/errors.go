@@ -69,6 +69,10 @@ type Error struct {
 	Path string    // file path (optional, may be empty)
 	Kind ErrorKind // error category
 	Err  error     // underlying error
+
+	// Details is pre-rendered text, such as source snippets of diagnostics,
+	// printed by PrintError below the error message. It is not part of Error().
+	Details string
 }
 
 // Error implements the error interface.
@@ -157,6 +161,9 @@ func PrintError(err error, stderr io.Writer) {
 	var e *Error
 	if errors.As(err, &e) {
 		printErrorWithKind(e, stderr)
+		if e.Details != "" {
+			_, _ = io.WriteString(stderr, e.Details)
+		}
 		return
 	}
 
@@ -440,6 +440,25 @@ func TestPrintError_AllErrorKinds(t *testing.T) {
 	}
 }
 
+// TestPrintError_Details tests that details are printed below the message but not part of Error()
+func TestPrintError_Details(t *testing.T) {
+	err := errors.NewWithKind("Validate", errors.KindValidation, fmt.Errorf("invalid blocks in main.tf"))
+	err.Details = "  1 | resource \"a\" {\n    | ^^^^^^^^^^^^\n"
+
+	var buf bytes.Buffer
+	errors.PrintError(err, &buf)
+	got := buf.String()
+
+	message := strings.Index(got, "invalid blocks in main.tf")
+	details := strings.Index(got, err.Details)
+	if message < 0 || details < message {
+		t.Errorf("PrintError() output = %q, want message followed by details", got)
+	}
+	if strings.Contains(err.Error(), "^") {
+		t.Errorf("Error() = %q, should not include details", err.Error())
+	}
+}
+
 // TestPrintError_SentinelErrors tests PrintError with all sentinel errors
 func TestPrintError_SentinelErrors(t *testing.T) {
 	tests := []struct {
@@ -2,17 +2,21 @@
 //
 // This package handles discovery of Terraform (.tf) and Terragrunt (.hcl) files,
 // with logic to skip common directories like .terraform and .terragrunt-cache.
-// It provides both recursive and non-recursive file discovery.
+// It provides both recursive and non-recursive file discovery, on the
+// operating system's file system or on any io/fs.FS.
 package files
 
 import (
 	stderrors "errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/obergerkatz/sortTF/internal/errors"
+	"github.com/obergerkatz/sortTF/vfs"
 )
 
 // IsValidFile checks if a file should be processed based on its name and type.
@@ -52,19 +56,36 @@ func ShouldSkipDir(_ string, info os.FileInfo) bool {
 // When recursive is false, it only examines the immediate directory.
 // Returns a slice of file paths, or an error if the root path is inaccessible.
 func FindFiles(root string, recursive bool) ([]string, error) {
+	foundFiles, err := FindFilesFS(vfs.OSFS{}, root, recursive)
+	if err != nil {
+		return nil, err
+	}
+	for i, path := range foundFiles {
+		foundFiles[i] = filepath.FromSlash(path)
+	}
+	return foundFiles, nil
+}
+
+// FindFilesFS is like FindFiles but discovers files in fsys. The returned
+// paths are slash-separated and start with root.
+func FindFilesFS(fsys fs.FS, root string, recursive bool) ([]string, error) {
 	// Check if root path exists
-	if _, err := os.Stat(root); err != nil {
+	if _, err := fs.Stat(fsys, root); err != nil {
 		return nil, errors.NewWithPath("FindFiles", root, errors.Wrap(err))
 	}
 
 	var foundFiles []string
 	if recursive {
-		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return errors.NewWithPath("Walk", path, errors.Wrap(err))
+			}
+			info, err := d.Info()
 			if err != nil {
 				return errors.NewWithPath("Walk", path, errors.Wrap(err))
 			}
 			if ShouldSkipDir(path, info) {
-				return filepath.SkipDir
+				return fs.SkipDir
 			}
 			if IsValidFile(path, info) {
 				foundFiles = append(foundFiles, path)
@@ -77,7 +98,7 @@ func FindFiles(root string, recursive bool) ([]string, error) {
 		return foundFiles, nil
 	}
 
-	entries, err := os.ReadDir(root)
+	entries, err := fs.ReadDir(fsys, root)
 	if err != nil {
 		return nil, errors.NewWithPath("ReadDir", root, errors.Wrap(err))
 	}
@@ -88,7 +109,7 @@ func FindFiles(root string, recursive bool) ([]string, error) {
 		if entry.Type().IsRegular() {
 			name := strings.ToLower(entry.Name())
 			if (strings.HasSuffix(name, ".tf") || strings.HasSuffix(name, ".hcl")) && entry.Name() != ".terraform.lock.hcl" {
-				foundFiles = append(foundFiles, filepath.Join(root, entry.Name()))
+				foundFiles = append(foundFiles, path.Join(root, entry.Name()))
 			}
 		}
 	}
@@ -141,6 +162,42 @@ func ValidateDirectoryPath(path string) error {
 	return nil
 }
 
+// FindRepoRoot returns the root of the git repository that contains dir:
+// the closest directory, starting at dir and going up, that holds a .git
+// directory or file (as in worktrees and submodules). Returns "" if dir is
+// not inside a repository.
+func FindRepoRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// RepoPath returns path relative to the root of the git repository it is in,
+// with forward slashes, as the a/ and b/ paths of a patch. Outside of a
+// repository it is relative to the working directory, and outside of that
+// relative to the root of the file system, so it never starts with / or ../.
+func RepoPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "/")
+	}
+	root := FindRepoRoot(filepath.Dir(abs))
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+	if rel, err := filepath.Rel(root, abs); err == nil && filepath.IsLocal(rel) {
+		return filepath.ToSlash(rel)
+	}
+	return strings.TrimPrefix(filepath.ToSlash(abs[len(filepath.VolumeName(abs)):]), "/")
+}
+
 // IsNotExistError checks if the error indicates a file or directory doesn't exist.
 // Uses errors.Is to unwrap the error chain and check for ErrFileNotFound.
 func IsNotExistError(err error) bool {
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/obergerkatz/sortTF/internal/errors"
+	"github.com/obergerkatz/sortTF/vfs"
 )
 
 // Mock FileInfo for testing
@@ -217,6 +218,49 @@ func TestFindFiles(t *testing.T) {
 	}
 }
 
+// TestFindFilesFS tests discovering files in an in-memory file system.
+func TestFindFilesFS(t *testing.T) {
+	fsys := vfs.NewMemFS(map[string][]byte{
+		"main.tf":                            nil,
+		"terragrunt.hcl":                     nil,
+		"notes.txt":                          nil,
+		".terraform.lock.hcl":                nil,
+		".terraform/modules/vpc/main.tf":     nil,
+		"modules/vpc/main.tf":                nil,
+		"modules/vpc/.terragrunt-cache/x.tf": nil,
+	})
+
+	tests := []struct {
+		name      string
+		root      string
+		recursive bool
+		want      []string
+		wantErr   bool
+	}{
+		{"non-recursive", ".", false, []string{"main.tf", "terragrunt.hcl"}, false},
+		{"recursive", ".", true, []string{"main.tf", "modules/vpc/main.tf", "terragrunt.hcl"}, false},
+		{"subdirectory", "modules", true, []string{"modules/vpc/main.tf"}, false},
+		{"missing root", "missing", false, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindFilesFS(fsys, tt.root, tt.recursive)
+			if tt.wantErr {
+				if !IsNotExistError(err) {
+					t.Errorf("FindFilesFS() error = %v, want not-exist error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindFilesFS() error = %v", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("FindFilesFS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFindFilesErrorHandling(t *testing.T) {
 	// Test with non-existent directory
 	_, err := FindFiles("/non/existent/path", false)
@@ -567,6 +611,85 @@ func TestValidateDirectoryPath(t *testing.T) {
 	}
 }
 
+// TestFindRepoRoot tests finding the root of the enclosing git repository
+func TestFindRepoRoot(t *testing.T) {
+	base := t.TempDir()
+	repo := filepath.Join(base, "repo")
+	worktree := filepath.Join(base, "worktree")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(repo, ".git"), filepath.Join(repo, "modules", "vpc"), worktree, outside} {
+		//nolint:gosec // G301: Test directories can use 0755 permissions
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//nolint:gosec // G306: Test files can use 0644 permissions
+	if err := os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: ../repo/.git/worktrees/w\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{"repository root", repo, repo},
+		{"nested directory", filepath.Join(repo, "modules", "vpc"), repo},
+		{"worktree with .git file", worktree, worktree},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindRepoRoot(tt.dir); got != tt.want {
+				t.Errorf("FindRepoRoot(%q) = %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+
+	// The temporary directory itself may be inside a repository
+	if got := FindRepoRoot(outside); strings.HasPrefix(got, base) {
+		t.Errorf("FindRepoRoot(%q) = %q, want no repository inside %q", outside, got, base)
+	}
+}
+
+// TestRepoPath tests the paths of files relative to their repository root
+func TestRepoPath(t *testing.T) {
+	base := t.TempDir()
+	repo := filepath.Join(base, "repo")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(repo, ".git"), filepath.Join(outside, "work")} {
+		//nolint:gosec // G301: Test directories can use 0755 permissions
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if FindRepoRoot(outside) != "" {
+		t.Skip("the temporary directory is inside a git repository")
+	}
+	t.Chdir(filepath.Join(outside, "work"))
+
+	other := filepath.Join(outside, "other", "main.tf")
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"inside a repository", filepath.Join(repo, "infra", "main.tf"), "infra/main.tf"},
+		{"relative to the working directory", filepath.Join("infra", "main.tf"), "infra/main.tf"},
+		{"outside the working directory", other, strings.TrimPrefix(filepath.ToSlash(other[len(filepath.VolumeName(other)):]), "/")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RepoPath(tt.path)
+			if got != tt.want {
+				t.Errorf("RepoPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+			if strings.HasPrefix(got, "/") || strings.HasPrefix(got, "../") {
+				t.Errorf("RepoPath(%q) = %q, want a path that stays under its root", tt.path, got)
+			}
+		})
+	}
+}
+
 func TestErrorHelperFunctions(t *testing.T) {
 	// Test errors.Error type checking
 	fileErr := errors.NewWithPath("Test", "/test", fmt.Errorf("test"))
@@ -0,0 +1,149 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sync"
+	"testing/fstest"
+	"time"
+)
+
+// MemFS is an in-memory file system. It is safe for concurrent use.
+//
+// Names must satisfy fs.ValidPath. Directories are implied by the files
+// they contain, as in fstest.MapFS.
+type MemFS struct {
+	mu    sync.RWMutex
+	files fstest.MapFS
+}
+
+var _ interface {
+	ConditionalWriteFS
+	fs.ReadFileFS
+	fs.ReadDirFS
+	fs.StatFS
+} = (*MemFS)(nil)
+
+// NewMemFS returns an in-memory file system holding a copy of files, keyed by name.
+func NewMemFS(files map[string][]byte) *MemFS {
+	m := &MemFS{files: make(fstest.MapFS, len(files))}
+	for name, data := range files {
+		m.files[name] = &fstest.MapFile{Data: append([]byte(nil), data...), Mode: 0644, ModTime: time.Now()}
+	}
+	return m
+}
+
+// Open opens the named file for reading. Later writes do not affect files
+// that are already open.
+func (m *MemFS) Open(name string) (fs.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.files.Open(name)
+}
+
+// ReadFile returns a copy of the contents of the named file.
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.files.ReadFile(name)
+}
+
+// ReadDir reads the named directory and returns its entries sorted by name.
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.files.ReadDir(name)
+}
+
+// Stat returns a FileInfo describing the named file.
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.files.Stat(name)
+}
+
+// WriteFile writes a copy of data to the named file, creating it with perm
+// if it does not exist. An existing file keeps its mode.
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return m.WriteFileIf(name, data, perm, nil)
+}
+
+// WriteFileIf is like WriteFile but calls check, if not nil, right before
+// the file is replaced. The check may read from m.
+func (m *MemFS) WriteFileIf(name string, data []byte, perm fs.FileMode, check func() error) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if check != nil {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if info, err := m.files.Stat(dir); err == nil && !info.IsDir() {
+			return &fs.PathError{Op: "write", Path: name, Err: errNotDir}
+		}
+	}
+
+	mode := perm
+	if info, err := m.files.Stat(name); err == nil {
+		if info.IsDir() {
+			return &fs.PathError{Op: "write", Path: name, Err: errIsDir}
+		}
+		mode = info.Mode()
+	}
+
+	// Replace the entry rather than modifying it, so open files are unaffected
+	m.files[name] = &fstest.MapFile{Data: append([]byte(nil), data...), Mode: mode, ModTime: time.Now()}
+	return nil
+}
+
+// Remove removes the named file.
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	info, err := m.files.Stat(name)
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if info.IsDir() {
+		return &fs.PathError{Op: "remove", Path: name, Err: errIsDir}
+	}
+
+	delete(m.files, name)
+	return nil
+}
+
+// MkdirAll creates the named directory and any missing parents.
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		if info, err := m.files.Stat(dir); err == nil && !info.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: errNotDir}
+		}
+	}
+	if _, err := m.files.Stat(name); err == nil {
+		return nil
+	}
+
+	m.files[name] = &fstest.MapFile{Mode: fs.ModeDir | perm, ModTime: time.Now()}
+	return nil
+}
+
+var (
+	errIsDir  = errors.New("is a directory")
+	errNotDir = errors.New("not a directory")
+)
@@ -0,0 +1,211 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+// TestMemFS_FS tests MemFS against the io/fs conformance checks.
+func TestMemFS_FS(t *testing.T) {
+	fsys := NewMemFS(map[string][]byte{
+		"main.tf":             []byte("a = 1\n"),
+		"modules/vpc/main.tf": []byte("b = 2\n"),
+	})
+	if err := fstest.TestFS(fsys, "main.tf", "modules/vpc/main.tf"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMemFS_NewMemFSCopies tests that NewMemFS does not share the caller's slices.
+func TestMemFS_NewMemFSCopies(t *testing.T) {
+	data := []byte("a = 1\n")
+	fsys := NewMemFS(map[string][]byte{"main.tf": data})
+	data[0] = 'b'
+
+	got, err := fsys.ReadFile("main.tf")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(got) != "a = 1\n" {
+		t.Errorf("ReadFile() = %q, want %q", got, "a = 1\n")
+	}
+}
+
+// TestMemFS_WriteFile tests creating and replacing files.
+func TestMemFS_WriteFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		wantErr  error
+		wantMode fs.FileMode
+	}{
+		{"new file", "variables.tf", nil, 0600},
+		{"existing file keeps mode", "main.tf", nil, 0644},
+		{"new directory", "modules/vpc/main.tf", nil, 0600},
+		{"invalid name", "../main.tf", fs.ErrInvalid, 0},
+		{"root", ".", fs.ErrInvalid, 0},
+		{"directory", "modules", errIsDir, 0},
+		{"parent is a file", "main.tf/child.tf", errNotDir, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFS(map[string][]byte{
+				"main.tf":          []byte("a = 1\n"),
+				"modules/db/db.tf": []byte("b = 2\n"),
+			})
+
+			err := fsys.WriteFile(tt.file, []byte("c = 3\n"), 0600)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("WriteFile(%q) error = %v, want %v", tt.file, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WriteFile(%q) error = %v", tt.file, err)
+			}
+
+			got, err := fs.ReadFile(fsys, tt.file)
+			if err != nil {
+				t.Fatalf("ReadFile(%q) error = %v", tt.file, err)
+			}
+			if string(got) != "c = 3\n" {
+				t.Errorf("ReadFile(%q) = %q, want %q", tt.file, got, "c = 3\n")
+			}
+
+			info, err := fs.Stat(fsys, tt.file)
+			if err != nil {
+				t.Fatalf("Stat(%q) error = %v", tt.file, err)
+			}
+			if info.Mode() != tt.wantMode {
+				t.Errorf("Stat(%q).Mode() = %v, want %v", tt.file, info.Mode(), tt.wantMode)
+			}
+		})
+	}
+}
+
+// TestMemFS_WriteFileOpenFile tests that writes do not affect files that are already open.
+func TestMemFS_WriteFileOpenFile(t *testing.T) {
+	fsys := NewMemFS(map[string][]byte{"main.tf": []byte("a = 1\n")})
+
+	f, err := fsys.Open("main.tf")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+
+	if err := fsys.WriteFile("main.tf", []byte("a = 2\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	buf := make([]byte, 16)
+	n, _ := f.Read(buf)
+	if string(buf[:n]) != "a = 1\n" {
+		t.Errorf("Read() from open file = %q, want %q", buf[:n], "a = 1\n")
+	}
+}
+
+// TestMemFS_Remove tests removing files.
+func TestMemFS_Remove(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr error
+	}{
+		{"file", "main.tf", nil},
+		{"missing file", "outputs.tf", fs.ErrNotExist},
+		{"directory", "modules", errIsDir},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFS(map[string][]byte{
+				"main.tf":          []byte("a = 1\n"),
+				"modules/db/db.tf": []byte("b = 2\n"),
+			})
+
+			err := fsys.Remove(tt.file)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Remove(%q) error = %v, want %v", tt.file, err, tt.wantErr)
+			}
+			if err == nil {
+				if _, err := fs.Stat(fsys, tt.file); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Stat(%q) after Remove() error = %v, want fs.ErrNotExist", tt.file, err)
+				}
+			}
+		})
+	}
+}
+
+// TestMemFS_WriteFileIf tests that the check may read the file system and
+// that a failed check leaves the file untouched.
+func TestMemFS_WriteFileIf(t *testing.T) {
+	fsys := NewMemFS(map[string][]byte{"main.tf": []byte("a = 1\n")})
+	errChanged := errors.New("changed")
+
+	check := func() error {
+		data, err := fsys.ReadFile("main.tf")
+		if err != nil {
+			return err
+		}
+		if string(data) != "a = 1\n" {
+			return errChanged
+		}
+		return nil
+	}
+
+	if err := fsys.WriteFileIf("main.tf", []byte("a = 2\n"), 0644, check); err != nil {
+		t.Fatalf("WriteFileIf() error = %v", err)
+	}
+	if err := fsys.WriteFileIf("main.tf", []byte("a = 3\n"), 0644, check); !errors.Is(err, errChanged) {
+		t.Fatalf("WriteFileIf() error = %v, want %v", err, errChanged)
+	}
+	if got, _ := fsys.ReadFile("main.tf"); string(got) != "a = 2\n" {
+		t.Errorf("ReadFile() = %q, want %q", got, "a = 2\n")
+	}
+}
+
+// TestMemFS_MkdirAll tests creating directories.
+func TestMemFS_MkdirAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     string
+		wantErr error
+	}{
+		{"new directory", "backups/run", nil},
+		{"existing directory", "modules", nil},
+		{"root", ".", nil},
+		{"invalid name", "/backups", fs.ErrInvalid},
+		{"file", "main.tf", errNotDir},
+		{"below a file", "main.tf/backups", errNotDir},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := NewMemFS(map[string][]byte{
+				"main.tf":          []byte("a = 1\n"),
+				"modules/db/db.tf": []byte("b = 2\n"),
+			})
+
+			err := fsys.MkdirAll(tt.dir, 0755)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MkdirAll(%q) error = %v, want %v", tt.dir, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			info, err := fs.Stat(fsys, tt.dir)
+			if err != nil || !info.IsDir() {
+				t.Errorf("Stat(%q) = %v, %v, want a directory", tt.dir, info, err)
+			}
+			entries, err := fs.ReadDir(fsys, tt.dir)
+			if err != nil {
+				t.Errorf("ReadDir(%q) error = %v", tt.dir, err)
+			}
+			if tt.dir == "modules" && len(entries) != 1 {
+				t.Errorf("ReadDir(%q) = %v, want the existing entries", tt.dir, entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// OSFS is the operating system's file system. The zero value is ready to use.
+//
+// Unlike most fs.FS implementations, OSFS accepts any operating system path,
+// including absolute paths and paths containing "..", rather than only the
+// names that satisfy fs.ValidPath. Names are relative to the current
+// working directory.
+type OSFS struct{}
+
+var _ interface {
+	ConditionalWriteFS
+	fs.ReadFileFS
+	fs.ReadDirFS
+	fs.StatFS
+} = OSFS{}
+
+// Open opens the named file for reading.
+func (OSFS) Open(name string) (fs.File, error) {
+	return os.Open(name) // #nosec G304 -- File path comes from user input, which is expected for a file processing tool
+}
+
+// ReadFile reads the named file and returns its contents.
+func (OSFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name) // #nosec G304 -- File path comes from user input, which is expected for a file processing tool
+}
+
+// ReadDir reads the named directory and returns its entries sorted by name.
+func (OSFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// Stat returns a FileInfo describing the named file.
+func (OSFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// WriteFile replaces the named file with data atomically: readers see either
+// the old or the new content, never a partial write, and a failed write
+// leaves the file untouched.
+//
+// The data is written to a uniquely named temporary file in the same
+// directory, synced, and renamed over the file, after which the directory is
+// synced as well. If name is a symbolic link, its target is replaced and the
+// link is kept. An existing file keeps its mode and, where permitted, its
+// owner and group; a new file is created with perm (before umask).
+func (fsys OSFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return fsys.WriteFileIf(name, data, perm, nil)
+}
+
+// WriteFileIf is like WriteFile but calls check, if not nil, after the
+// temporary file is synced and before it is renamed over the file.
+func (OSFS) WriteFileIf(name string, data []byte, perm fs.FileMode, check func() error) error {
+	target, err := resolveSymlinks(name)
+	if err != nil {
+		return err
+	}
+
+	existing, err := os.Stat(target)
+	switch {
+	case err == nil:
+		if !existing.Mode().IsRegular() {
+			return &fs.PathError{Op: "write", Path: name, Err: errors.New("not a regular file")}
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		existing = nil
+	default:
+		return err
+	}
+
+	dir := filepath.Dir(target)
+	createPerm := perm
+	if existing != nil {
+		// Start private; the original mode is applied once the content is in place
+		createPerm = 0600
+	}
+	tmp, err := createTemp(dir, filepath.Base(target), createPerm)
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if existing != nil {
+		// Change the owner first, as that clears the setuid and setgid bits
+		copyOwner(tmp, existing)
+		if err := tmp.Chmod(existing.Mode().Perm() | existing.Mode()&(fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky)); err != nil {
+			return fmt.Errorf("set file mode: %w", err)
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+
+	if check != nil {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("replace file: %w", err)
+	}
+	committed = true
+
+	if err := syncDir(dir); err != nil {
+		return fmt.Errorf("sync directory: %w", err)
+	}
+	return nil
+}
+
+// Remove removes the named file or empty directory.
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// MkdirAll creates the named directory and any missing parents.
+func (OSFS) MkdirAll(name string, perm fs.FileMode) error {
+	return os.MkdirAll(name, perm)
+}
+
+// resolveSymlinks returns the file that writing to name should replace:
+// name itself, or the final target if name is a symbolic link. The target
+// of a dangling link does not need to exist.
+func resolveSymlinks(name string) (string, error) {
+	target := name
+	// Bound the number of links followed, as the kernel does, to stop cycles
+	for range 255 {
+		info, err := os.Lstat(target)
+		if errors.Is(err, fs.ErrNotExist) {
+			return target, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return target, nil
+		}
+
+		link, err := os.Readlink(target)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(target), link)
+		}
+		target = link
+	}
+	return "", &fs.PathError{Op: "write", Path: name, Err: errors.New("too many levels of symbolic links")}
+}
+
+// createTemp creates a new file in dir with a unique name derived from base,
+// opened for writing with perm (before umask).
+func createTemp(dir, base string, perm fs.FileMode) (*os.File, error) {
+	for range 10000 {
+		name := filepath.Join(dir, "."+base+"."+strconv.FormatUint(uint64(rand.Uint32()), 36)+".tmp")
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm) // #nosec G304 -- Temp file next to a file the user asked to write
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return f, err
+	}
+	return nil, &fs.PathError{Op: "createtemp", Path: filepath.Join(dir, "."+base+".*.tmp"), Err: fs.ErrExist}
+}
@@ -0,0 +1,296 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// assertNoTempFiles fails the test if dir contains leftover temporary files.
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("temporary file left behind: %s", entry.Name())
+		}
+	}
+}
+
+// TestOSFS_ReadWrite tests reading, writing and removing files by absolute path.
+func TestOSFS_ReadWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.tf")
+	fsys := OSFS{}
+
+	if err := fsys.WriteFile(path, []byte("a = 1\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := fsys.WriteFile(path, []byte("a = 2\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() overwrite error = %v", err)
+	}
+
+	got, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(got) != "a = 2\n" {
+		t.Errorf("ReadFile() = %q, want %q", got, "a = 2\n")
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "main.tf" {
+		t.Errorf("ReadDir() = %v, want [main.tf]", entries)
+	}
+
+	sub := filepath.Join(dir, "backups", "run")
+	if err := fsys.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if info, err := fs.Stat(fsys, sub); err != nil || !info.IsDir() {
+		t.Errorf("Stat() after MkdirAll() = %v, %v, want a directory", info, err)
+	}
+
+	if err := fsys.Remove(path); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := fs.Stat(fsys, path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() after Remove() error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+// TestOSFS_WriteFileMode tests that existing files keep their mode and new
+// files are created with the requested one.
+func TestOSFS_WriteFileMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing fs.FileMode // 0 for a new file
+		perm     fs.FileMode
+		want     fs.FileMode
+	}{
+		{"private file", 0600, 0644, 0600},
+		{"executable file", 0755, 0644, 0755},
+		{"read-only file", 0444, 0644, 0444},
+		{"new file", 0, 0640, 0640},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "main.tf")
+			if tt.existing != 0 {
+				if err := os.WriteFile(path, []byte("a = 1\n"), tt.existing); err != nil {
+					t.Fatal(err)
+				}
+				// Apply the mode exactly, regardless of umask
+				if err := os.Chmod(path, tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := (OSFS{}).WriteFile(path, []byte("a = 2\n"), tt.perm); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// New files are subject to the umask, which may clear bits but never set them
+			if got := info.Mode().Perm(); got != tt.want && (tt.existing != 0 || got&^tt.want != 0) {
+				t.Errorf("mode = %v, want %v", got, tt.want)
+			}
+			assertNoTempFiles(t, dir)
+		})
+	}
+}
+
+// TestOSFS_WriteFileSymlink tests that writing through a symbolic link
+// replaces its target and keeps the link.
+func TestOSFS_WriteFileSymlink(t *testing.T) {
+	dir := t.TempDir()
+	shared := filepath.Join(dir, "shared")
+	if err := os.Mkdir(shared, 0750); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(shared, "providers.tf")
+	//nolint:gosec // G306: Test files can use 0644 permissions
+	if err := os.WriteFile(target, []byte("a = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(dir, "providers.tf")
+	if err := os.Symlink(filepath.Join("shared", "providers.tf"), link); err != nil {
+		t.Skip("Symlink not supported on this system")
+	}
+	chain := filepath.Join(dir, "chain.tf")
+	if err := os.Symlink("providers.tf", chain); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (OSFS{}).WriteFile(chain, []byte("a = 2\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	for _, path := range []string{link, chain} {
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			t.Errorf("%s was replaced by a regular file", filepath.Base(path))
+		}
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	got, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "a = 2\n" {
+		t.Errorf("target content = %q, want %q", got, "a = 2\n")
+	}
+	assertNoTempFiles(t, dir)
+	assertNoTempFiles(t, shared)
+}
+
+// TestOSFS_WriteFileSymlinkLoop tests that a symbolic link cycle fails.
+func TestOSFS_WriteFileSymlinkLoop(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.tf")
+	if err := os.Symlink("b.tf", a); err != nil {
+		t.Skip("Symlink not supported on this system")
+	}
+	if err := os.Symlink("a.tf", filepath.Join(dir, "b.tf")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (OSFS{}).WriteFile(a, []byte("a = 1\n"), 0644); err == nil {
+		t.Error("WriteFile() through a symbolic link cycle should fail")
+	}
+}
+
+// TestOSFS_WriteFileConcurrent tests that concurrent writes to one file do
+// not collide on their temporary files.
+func TestOSFS_WriteFileConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.tf")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- (OSFS{}).WriteFile(path, []byte(fmt.Sprintf("a = %d\n", i)), 0644)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("WriteFile() error = %v", err)
+		}
+	}
+	//nolint:gosec // G304: Test file path is controlled
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(got), "a = ") || strings.Count(string(got), "\n") != 1 {
+		t.Errorf("content = %q, want a single complete write", got)
+	}
+	assertNoTempFiles(t, dir)
+}
+
+// TestOSFS_WriteFileError tests that failed writes leave the file and
+// directory untouched.
+func TestOSFS_WriteFileError(t *testing.T) {
+	t.Run("missing directory", func(t *testing.T) {
+		dir := t.TempDir()
+		err := (OSFS{}).WriteFile(filepath.Join(dir, "missing", "main.tf"), []byte("a = 1\n"), 0644)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("WriteFile() error = %v, want fs.ErrNotExist", err)
+		}
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := (OSFS{}).WriteFile(dir, []byte("a = 1\n"), 0644); err == nil {
+			t.Error("WriteFile() over a directory should fail")
+		}
+		assertNoTempFiles(t, filepath.Dir(dir))
+	})
+
+	t.Run("read-only directory", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("Permissions are not enforced for root")
+		}
+		dir := t.TempDir()
+		path := filepath.Join(dir, "main.tf")
+		//nolint:gosec // G306: Test files can use 0644 permissions
+		if err := os.WriteFile(path, []byte("a = 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		//nolint:gosec // G302: Test needs to set restrictive permissions
+		if err := os.Chmod(dir, 0555); err != nil {
+			t.Fatal(err)
+		}
+		//nolint:gosec // G302: Test cleanup needs to restore permissions
+		defer func() { _ = os.Chmod(dir, 0755) }()
+
+		if err := (OSFS{}).WriteFile(path, []byte("a = 2\n"), 0644); !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("WriteFile() error = %v, want fs.ErrPermission", err)
+		}
+		//nolint:gosec // G304: Test file path is controlled
+		if got, _ := os.ReadFile(path); string(got) != "a = 1\n" {
+			t.Errorf("content = %q, want it unchanged", got)
+		}
+		assertNoTempFiles(t, dir)
+	})
+}
+
+// TestOSFS_WriteFileIf tests that a failed check leaves the file untouched.
+func TestOSFS_WriteFileIf(t *testing.T) {
+	errChanged := errors.New("changed")
+	tests := []struct {
+		name    string
+		check   func() error
+		wantErr error
+		want    string
+	}{
+		{"no check", nil, nil, "a = 2\n"},
+		{"check passes", func() error { return nil }, nil, "a = 2\n"},
+		{"check fails", func() error { return errChanged }, errChanged, "a = 1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "main.tf")
+			//nolint:gosec // G306: Test files can use 0644 permissions
+			if err := os.WriteFile(path, []byte("a = 1\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := (OSFS{}).WriteFileIf(path, []byte("a = 2\n"), 0644, tt.check)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("WriteFileIf() error = %v, want %v", err, tt.wantErr)
+			}
+			//nolint:gosec // G304: Test file path is controlled
+			if got, _ := os.ReadFile(path); string(got) != tt.want {
+				t.Errorf("content = %q, want %q", got, tt.want)
+			}
+			assertNoTempFiles(t, dir)
+		})
+	}
+}
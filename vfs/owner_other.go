@@ -0,0 +1,14 @@
+//go:build !unix
+
+package vfs
+
+import (
+	"io/fs"
+	"os"
+)
+
+// copyOwner does nothing on systems without Unix file ownership.
+func copyOwner(*os.File, fs.FileInfo) {}
+
+// syncDir does nothing on systems where directories cannot be synced.
+func syncDir(string) error { return nil }
@@ -0,0 +1,32 @@
+//go:build unix
+
+package vfs
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// copyOwner gives f the owner and group of the file described by info, as
+// far as the process is permitted to; failures are ignored.
+func copyOwner(f *os.File, info fs.FileInfo) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	// Only the owner can be kept without privileges; try the group alone too
+	if err := f.Chown(int(stat.Uid), int(stat.Gid)); err != nil {
+		_ = f.Chown(-1, int(stat.Gid))
+	}
+}
+
+// syncDir flushes the directory entry of a renamed file to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir) // #nosec G304 -- Directory of a file the user asked to write
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+	return d.Sync()
+}
@@ -0,0 +1,50 @@
+//go:build unix
+
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// TestOSFS_WriteFileOwner tests that existing files keep their owner and group.
+func TestOSFS_WriteFileOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Changing file ownership requires root")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.tf")
+	//nolint:gosec // G306: Test files can use 0644 permissions
+	if err := os.WriteFile(path, []byte("a = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(path, 1234, 5678); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (OSFS{}).WriteFile(path, []byte("a = 2\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat := info.Sys().(*syscall.Stat_t)
+	if stat.Uid != 1234 || stat.Gid != 5678 {
+		t.Errorf("owner = %d:%d, want 1234:5678", stat.Uid, stat.Gid)
+	}
+}
+
+// TestSyncDir tests syncing an existing and a missing directory.
+func TestSyncDir(t *testing.T) {
+	if err := syncDir(t.TempDir()); err != nil {
+		t.Errorf("syncDir() error = %v", err)
+	}
+	if err := syncDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("syncDir() of a missing directory should fail")
+	}
+}
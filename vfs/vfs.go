@@ -0,0 +1,49 @@
+// Package vfs defines the file systems that sortTF discovers, reads and
+// writes files through.
+//
+// Reading uses the standard io/fs.FS interface, so any fs.FS, such as an
+// embed.FS or a fstest.MapFS, can be sorted in DryRun or Validate mode.
+// Writing sorted files back additionally requires the small WriteFS
+// extension.
+//
+// Two implementations are provided:
+//   - OSFS: the operating system's file system
+//   - MemFS: an in-memory file system, useful in tests
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// WriteFS is a file system that files can also be written to and removed from.
+type WriteFS interface {
+	fs.FS
+
+	// WriteFile writes data to the named file, creating it with perm if
+	// necessary. Implementations should replace the file atomically, so a
+	// failed write never leaves a partially written file behind.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+
+	// Remove removes the named file.
+	Remove(name string) error
+
+	// MkdirAll creates the named directory and any missing parents with
+	// perm (before umask). It does nothing if the directory already exists.
+	MkdirAll(name string, perm fs.FileMode) error
+}
+
+// ConditionalWriteFS is a WriteFS that can check a precondition immediately
+// before a file is replaced, for example that nobody else changed it since
+// it was read.
+type ConditionalWriteFS interface {
+	WriteFS
+
+	// WriteFileIf is like WriteFile but calls check, if not nil, once the new
+	// content is ready, right before the file is replaced. If check returns
+	// an error, the file is left untouched and that error is returned.
+	WriteFileIf(name string, data []byte, perm fs.FileMode, check func() error) error
+}
+
+// ErrReadOnly indicates a write to a file system that does not implement WriteFS.
+var ErrReadOnly = errors.New("file system is read-only")
@@ -0,0 +1,33 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+// TestErrReadOnly tests that ErrReadOnly can be matched through wrapping.
+func TestErrReadOnly(t *testing.T) {
+	err := &fs.PathError{Op: "write", Path: "main.tf", Err: ErrReadOnly}
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("errors.Is(%v, ErrReadOnly) = false, want true", err)
+	}
+}
+
+// TestWriteFS_Implementations tests that the provided file systems implement WriteFS.
+func TestWriteFS_Implementations(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fs.FS
+	}{
+		{"os", OSFS{}},
+		{"memory", NewMemFS(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.fsys.(WriteFS); !ok {
+				t.Errorf("%T does not implement WriteFS", tt.fsys)
+			}
+		})
+	}
+}